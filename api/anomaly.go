@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAnomalyLogs 获取被标记为异常的报价记录（管理员API），platform 为平台标识
+func GetAnomalyLogs(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	platform := ""
+	if key := c.Query("platform"); key != "" {
+		p, ok := models.GetPlatformByKey(key)
+		if !ok {
+			c.JSON(http.StatusOK, gin.H{
+				"code": utils.ErrCodeInvalidParams,
+				"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+			})
+			return
+		}
+		platform = p.Code
+	}
+
+	logs, total, code := models.GetAnomalyLogs(platform, c.Query("search"), pageSize, pageNum)
+	jsonWithCurrency(c, gin.H{
+		"code":  code,
+		"msg":   utils.ErrorMessage(code),
+		"data":  logs,
+		"total": total,
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"uu/services"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyRequest 新增 API key 请求，quota / window 不填时每 60 秒 1 次
+type APIKeyRequest struct {
+	Key    string `json:"key" binding:"required"`
+	Remark string `json:"remark"`
+	Quota  int    `json:"quota" binding:"gte=0"`
+	Window int    `json:"window" binding:"gte=0"` // 额度窗口（秒）
+}
+
+// APIKeyIDRequest 按 ID 操作 API key 的请求
+type APIKeyIDRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// GetAPIKeys 获取所有 SteamDT API key 的额度、健康分和退避状态（管理员API）
+func GetAPIKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+		"data": services.ListAPIKeys(),
+	})
+}
+
+// AddAPIKey 新增 API key，立即加入 key 池（管理员API）
+func AddAPIKey(c *gin.Context) {
+	var req APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := services.AddAPIKey(req.Key, req.Remark, req.Quota, req.Window)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// DeleteAPIKey 删除 API key（管理员API）
+func DeleteAPIKey(c *gin.Context) {
+	var req APIKeyIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := services.RemoveAPIKey(req.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// DisableAPIKey 禁用 API key（管理员API）
+func DisableAPIKey(c *gin.Context) {
+	setAPIKeyEnabled(c, false)
+}
+
+// EnableAPIKey 启用 API key，同时清除退避（管理员API）
+func EnableAPIKey(c *gin.Context) {
+	setAPIKeyEnabled(c, true)
+}
+
+func setAPIKeyEnabled(c *gin.Context, enabled bool) {
+	var req APIKeyIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := services.SetAPIKeyEnabled(req.ID, enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"uu/models"
+	"uu/services"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestRequest 创建回测请求，日期格式 2006-01-02，hold_days 为买入后多少天卖出（默认 7，交易冷却期）
+type BacktestRequest struct {
+	ProfileID uint   `json:"profile_id"`
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date" binding:"required"`
+	HoldDays  int    `json:"hold_days" binding:"min=0"`
+}
+
+// CreateBacktest 创建回测任务，任务在后台执行，通过 detail 接口查看状态和结果
+func CreateBacktest(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	start, ok := parseDate(req.StartDate)
+	end, ok2 := parseDate(req.EndDate)
+	if req.HoldDays == 0 {
+		req.HoldDays = models.DefaultBacktestHold
+	}
+	if !ok || !ok2 || end.Before(start) || end.Sub(start).Hours()/24 >= models.MaxBacktestDays ||
+		req.HoldDays > models.MaxBacktestHoldDays {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	job, code := services.SubmitBacktest(userID, &services.BacktestRequest{
+		ProfileID: req.ProfileID,
+		Source:    req.Source,
+		Target:    req.Target,
+		StartDate: start,
+		EndDate:   end,
+		HoldDays:  req.HoldDays,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": job,
+	})
+}
+
+// GetBacktestJobs 获取回测任务列表
+func GetBacktestJobs(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	jobs, code := models.GetBacktestJobs(userID)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": jobs,
+	})
+}
+
+// GetBacktestJob 获取回测任务状态和结果
+func GetBacktestJob(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	job, result, code := models.GetBacktestJob(userID, uint(id))
+	if code != utils.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  utils.ErrorMessage(code),
+		})
+		return
+	}
+	jsonWithCurrency(c, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+		"data": gin.H{"job": job, "result": result},
+	})
+}
+
+// DeleteBacktestJob 删除回测任务
+func DeleteBacktestJob(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.DeleteBacktestJob(userID, uint(id))
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
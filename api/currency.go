@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"uu/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const displayCurrencyKey = "displayCurrency"
+
+// displayCurrency 当前请求的展示币种，不支持的币种按人民币处理
+// 优先取参数 currency，其次请求头 X-Display-Currency，最后是用户默认设置方案中的展示币种
+func displayCurrency(c *gin.Context) string {
+	if currency := c.GetString(displayCurrencyKey); currency != "" {
+		return currency
+	}
+	currency := c.Query("currency")
+	if currency == "" {
+		currency = c.GetHeader("X-Display-Currency")
+	}
+	if currency == "" {
+		if userId := getUserIdFromContext(c); userId != "" {
+			currency = models.GetUserDisplayCurrency(userId)
+		}
+	}
+	currency = models.NormalizeCurrency(currency)
+	if _, ok := models.GetFxRateMap()[currency]; !ok {
+		currency = models.BaseCurrency
+	}
+	c.Set(displayCurrencyKey, currency)
+	return currency
+}
+
+// convertAmounts 将金额字段换算为当前请求的展示币种
+func convertAmounts(c *gin.Context, v interface{}) interface{} {
+	return models.ConvertAmounts(v, displayCurrency(c), models.GetFxRateMap())
+}
+
+// jsonWithCurrency 按展示币种换算 data 中的金额字段后返回，并附加 currency 字段
+func jsonWithCurrency(c *gin.Context, h gin.H) {
+	if data, ok := h["data"]; ok {
+		h["data"] = convertAmounts(c, data)
+	}
+	h["currency"] = displayCurrency(c)
+	c.JSON(http.StatusOK, h)
+}
+
+// queryAmountPtr 读取以展示币种填写的金额筛选参数并换算为人民币，未传或格式错误时返回 nil
+func queryAmountPtr(c *gin.Context, key string) *float64 {
+	f := queryFloatPtr(c, key)
+	if f == nil {
+		return nil
+	}
+	amount := models.ToBaseCurrency(*f, displayCurrency(c), models.GetFxRateMap())
+	return &amount
+}
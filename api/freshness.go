@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FreshnessRequest 设置平台行情最大有效时长请求
+type FreshnessRequest struct {
+	Platform string `json:"platform" binding:"required"` // 平台标识
+	MaxAge   int64  `json:"max_age" binding:"required,gt=0"`
+}
+
+// GetFreshnessStats 获取各平台行情更新时间分布（管理员API）
+func GetFreshnessStats(c *gin.Context) {
+	stats, code := models.GetFreshnessStats()
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": stats,
+	})
+}
+
+// UpdatePlatformMaxAge 设置平台行情最大有效时长（管理员API）
+func UpdatePlatformMaxAge(c *gin.Context) {
+	var req FreshnessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	p, ok := models.GetPlatformByKey(req.Platform)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	if err := models.SetPlatformMaxAge(p, req.MaxAge); err != nil {
+		config.Log.Errorf("Update %s max age error: %v", p.Code, err)
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeUpdateFreshness,
+			"msg":  utils.ErrorMessage(utils.ErrCodeUpdateFreshness),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFxRates 获取汇率列表（1 单位外币折合人民币）
+func GetFxRates(c *gin.Context) {
+	rates, err := models.GetAllFxRates()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeGetFxRates,
+			"msg":  utils.ErrorMessage(utils.ErrCodeGetFxRates),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"data": rates,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+	})
+}
+
+// UpdateFxRate 修改汇率（管理员API）
+func UpdateFxRate(c *gin.Context) {
+	var req struct {
+		Currency string  `json:"currency" binding:"required,len=3"`
+		Rate     float64 `json:"rate" binding:"gt=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	// 人民币为基准币种，汇率固定为 1
+	if models.NormalizeCurrency(req.Currency) == models.BaseCurrency {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeUnsupportedCurrency,
+			"msg":  utils.ErrorMessage(utils.ErrCodeUnsupportedCurrency),
+		})
+		return
+	}
+
+	if err := models.UpdateFxRate(req.Currency, req.Rate, "admin"); err != nil {
+		config.Log.Errorf("Update fx rate error: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeUpdateFxRate,
+			"msg":  utils.ErrorMessage(utils.ErrCodeUpdateFxRate),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+	})
+}
+
+// ReloadFxRates 从配置的汇率文件重新导入汇率（管理员API）
+func ReloadFxRates(c *gin.Context) {
+	if config.CONFIG.Fx == nil || config.CONFIG.Fx.RatesFile == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeLoadFxRates,
+			"msg":  utils.ErrorMessage(utils.ErrCodeLoadFxRates),
+		})
+		return
+	}
+	loaded, err := models.LoadFxRatesFromFile(config.CONFIG.Fx.RatesFile)
+	if err != nil {
+		config.Log.Errorf("Load fx rates file error: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeLoadFxRates,
+			"msg":  utils.ErrorMessage(utils.ErrCodeLoadFxRates),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"data": gin.H{"loaded": loaded},
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+	})
+}
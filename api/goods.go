@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"uu/models"
 	"uu/utils"
 
@@ -16,28 +17,12 @@ func GetGoods(c *gin.Context) {
 		pageSize = 25
 		pageNum = 1
 	}
-	userId := getUserIdFromContext(c)
-	sort := c.Query("sort")
-	desc, _ := strconv.ParseBool(c.Query("desc"))
-	category := c.Query("category")
-	search := c.Query("search")
-	source := c.Query("source")
-	target := c.Query("target")
+	q := parseGoodsQuery(c)
+	q.PageSize = pageSize
+	q.PageNum = pageNum
 
-	// 新增：购买方案和出售方案
-	// buy_type: sell(在售价购买) / bidding(求购价购买)，默认 sell
-	// sell_type: sell(在售价出售) / bidding(求购价出售)，默认 sell
-	buyType := c.Query("buy_type")
-	sellType := c.Query("sell_type")
-	if buyType != "sell" && buyType != "bidding" {
-		buyType = "sell"
-	}
-	if sellType != "sell" && sellType != "bidding" {
-		sellType = "sell"
-	}
-
-	s, total, code := models.GetGoods(userId, pageSize, pageNum, desc, sort, search, source, target, category, buyType, sellType)
-	c.JSON(http.StatusOK, gin.H{
+	s, total, code := models.GetGoods(q)
+	jsonWithCurrency(c, gin.H{
 		"code":  code,
 		"data":  s,
 		"total": total,
@@ -85,7 +70,7 @@ func GetPriceHistory(c *gin.Context) {
 			})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
+		jsonWithCurrency(c, gin.H{
 			"code": utils.SUCCESS,
 			"data": gin.H{
 				"marketHashName": marketHashName,
@@ -107,13 +92,77 @@ func GetPriceHistory(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonWithCurrency(c, gin.H{
 		"code": utils.SUCCESS,
 		"data": history,
 		"msg":  utils.ErrorMessage(utils.SUCCESS),
 	})
 }
 
+// GetPriceCandles 获取商品在某平台的K线（1h/4h/1d）
+func GetPriceCandles(c *gin.Context) {
+	marketHashName := c.Query("market_hash_name")
+	resolution := c.DefaultQuery("resolution", "1h")
+	if marketHashName == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  "market_hash_name is required",
+		})
+		return
+	}
+	// platform 支持平台标识（uu）或平台代码（YOUPIN），默认悠悠
+	platform := models.Platforms()[0]
+	if p := c.Query("platform"); p != "" {
+		def, ok := models.GetPlatformByKey(p)
+		if !ok {
+			def, ok = models.GetPlatformByCode(p)
+		}
+		if !ok {
+			c.JSON(http.StatusOK, gin.H{
+				"code": utils.ErrCodeInvalidParams,
+				"msg":  "unknown platform",
+			})
+			return
+		}
+		platform = def
+	}
+	if _, ok := models.CandleResolutions[resolution]; !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  "resolution must be 1h, 4h or 1d",
+		})
+		return
+	}
+
+	// 小时级K线只能覆盖日内快照的保留范围
+	days, maxDays := 30, 365
+	if resolution != "1d" {
+		days, maxDays = models.PriceSnapshotRetentionDays, models.PriceSnapshotRetentionDays
+	}
+	if d, err := strconv.Atoi(c.Query("days")); err == nil && d > 0 && d <= maxDays {
+		days = d
+	}
+
+	candles, err := models.GetPriceCandles(marketHashName, platform.Code, resolution, days)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeGetGoods,
+			"msg":  "Failed to get price candles",
+		})
+		return
+	}
+	jsonWithCurrency(c, gin.H{
+		"code": utils.SUCCESS,
+		"data": gin.H{
+			"marketHashName": marketHashName,
+			"platform":       platform.Code,
+			"resolution":     resolution,
+			"candles":        candles,
+		},
+		"msg": utils.ErrorMessage(utils.SUCCESS),
+	})
+}
+
 // GetGoodsDetail 获取商品详情（包含基础信息、所有平台历史数据、各平台在售信息）
 func GetGoodsDetail(c *gin.Context) {
 	marketHashName := c.Query("market_hash_name")
@@ -144,7 +193,7 @@ func GetGoodsDetail(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonWithCurrency(c, gin.H{
 		"code": utils.SUCCESS,
 		"data": detail,
 		"msg":  utils.ErrorMessage(utils.SUCCESS),
@@ -170,7 +219,7 @@ func GetPriceIncreaseByU(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonWithCurrency(c, gin.H{
 		"code": utils.SUCCESS,
 		"data": increase,
 		"msg":  utils.ErrorMessage(utils.SUCCESS),
@@ -188,7 +237,7 @@ func GetPublicHomeData(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonWithCurrency(c, gin.H{
 		"code": utils.SUCCESS,
 		"data": data,
 		"msg":  utils.ErrorMessage(utils.SUCCESS),
@@ -215,7 +264,7 @@ func GetRelatedWears(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonWithCurrency(c, gin.H{
 		"code": utils.SUCCESS,
 		"data": result,
 		"msg":  utils.ErrorMessage(utils.SUCCESS),
@@ -272,17 +321,202 @@ func GetBigItemBidding(c *gin.Context) {
 	search := c.Query("search")
 	platform := c.Query("platform")
 	category := c.Query("category")
+	watchlist, _ := strconv.ParseBool(c.Query("watchlist"))
+	excludeAnomaly, _ := strconv.ParseBool(c.Query("exclude_anomaly"))
 
 	// 默认平台为悠悠
 	if platform == "" {
 		platform = "uu"
 	}
 
-	data, total, code := models.GetBigItemBidding(pageSize, pageNum, desc, sort, search, platform, category)
-	c.JSON(http.StatusOK, gin.H{
+	data, total, code := models.GetBigItemBidding(&models.BigItemBiddingQuery{
+		UserId:           getUserIdFromContext(c),
+		PageSize:         pageSize,
+		PageNum:          pageNum,
+		IsDesc:           desc,
+		SortField:        sort,
+		Search:           search,
+		Platform:         platform,
+		Category:         category,
+		MinNetProfit:     queryAmountPtr(c, "min_net_profit"),
+		MinNetProfitRate: queryFloatPtr(c, "min_net_profit_rate"),
+		MinLiquidity:     queryFloatPtr(c, "min_liquidity"),
+		Watchlist:        watchlist,
+		ExcludeAnomaly:   excludeAnomaly,
+	})
+	jsonWithCurrency(c, gin.H{
 		"code":  code,
 		"data":  data,
 		"total": total,
 		"msg":   utils.ErrorMessage(code),
 	})
 }
+
+// GetArbitrageRoutes 对每个饰品评估所有平台组合和买卖方式，返回扣除手续费后的最优路线
+// platforms / buy_types / sell_types 为逗号分隔的列表，为空表示全部
+func GetArbitrageRoutes(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "25"))
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 25
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	desc, _ := strconv.ParseBool(c.Query("desc"))
+	watchlist, _ := strconv.ParseBool(c.Query("watchlist"))
+	profileId, _ := strconv.ParseUint(c.Query("profile"), 10, 64)
+	excludeAnomaly, _ := strconv.ParseBool(c.Query("exclude_anomaly"))
+	excludeStale, _ := strconv.ParseBool(c.Query("exclude_stale"))
+
+	routes, total, code := models.FindArbitrageRoutes(&models.RouteQuery{
+		UserId:           getUserIdFromContext(c),
+		ProfileId:        uint(profileId),
+		PageSize:         pageSize,
+		PageNum:          pageNum,
+		IsDesc:           desc,
+		SortField:        c.Query("sort"),
+		Search:           c.Query("search"),
+		Category:         c.Query("category"),
+		Platforms:        queryList(c, "platforms"),
+		BuyTypes:         queryPriceTypes(c, "buy_types"),
+		SellTypes:        queryPriceTypes(c, "sell_types"),
+		MinNetProfit:     queryAmountPtr(c, "min_net_profit"),
+		MinNetProfitRate: queryFloatPtr(c, "min_net_profit_rate"),
+		Watchlist:        watchlist,
+		ExcludeAnomaly:   excludeAnomaly,
+		MaxAge:           queryMaxAge(c),
+		ExcludeStale:     excludeStale,
+	})
+	jsonWithCurrency(c, gin.H{
+		"code":  code,
+		"data":  routes,
+		"total": total,
+		"msg":   utils.ErrorMessage(code),
+	})
+}
+
+// GetSteamRatio 挂刀：按 Steam 余额比例（Steam 到手余额 / 买入价）排序
+// sort: instant_ratio(卖给求购，默认) / listing_ratio(上架出售)；min_ratio 未传时使用设置中的挂刀比例阈值
+func GetSteamRatio(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	pageNum, _ := strconv.Atoi(c.Query("page_num"))
+	if pageNum == 0 || pageSize == 0 {
+		pageSize = 25
+		pageNum = 1
+	}
+	desc := true
+	if v, err := strconv.ParseBool(c.Query("desc")); err == nil {
+		desc = v
+	}
+	watchlist, _ := strconv.ParseBool(c.Query("watchlist"))
+
+	items, total, code := models.GetSteamRatio(&models.SteamRatioQuery{
+		UserId:         getUserIdFromContext(c),
+		PageSize:       pageSize,
+		PageNum:        pageNum,
+		IsDesc:         desc,
+		SortField:      c.Query("sort"),
+		Source:         c.Query("source"),
+		Search:         c.Query("search"),
+		Category:       c.Query("category"),
+		Watchlist:      watchlist,
+		MinRatio:       queryFloatPtr(c, "min_ratio"),
+		MinSteamVolume: queryFloatPtr(c, "min_steam_volume"),
+		MinSteamPrice:  queryAmountPtr(c, "min_steam_price"),
+		MaxSteamPrice:  queryAmountPtr(c, "max_steam_price"),
+	})
+	jsonWithCurrency(c, gin.H{
+		"code":  code,
+		"data":  items,
+		"total": total,
+		"msg":   utils.ErrorMessage(code),
+	})
+}
+
+// queryList 读取逗号分隔的列表参数
+func queryList(c *gin.Context, key string) []string {
+	var result []string
+	for _, v := range strings.Split(c.Query(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// queryPriceTypes 读取买卖方式列表，忽略无效值
+func queryPriceTypes(c *gin.Context, key string) []string {
+	var result []string
+	for _, t := range queryList(c, key) {
+		if t == models.PriceTypeSell || t == models.PriceTypeBidding {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// parseGoodsQuery 解析搬砖数据的筛选参数（不含分页），供列表和实时推送共用
+func parseGoodsQuery(c *gin.Context) *models.GoodsQuery {
+	desc, _ := strconv.ParseBool(c.Query("desc"))
+
+	// 新增：购买方案和出售方案
+	// buy_type: sell(在售价购买) / bidding(求购价购买)
+	// sell_type: sell(在售价出售) / bidding(求购价出售)
+	// 未指定时使用设置方案中的值，方案中也没有则为 sell
+	buyType := c.Query("buy_type")
+	sellType := c.Query("sell_type")
+	// 排除冷却期（默认7天）历史最大回撤会吃掉全部利润的饰品
+	excludeDrawdown, _ := strconv.ParseBool(c.Query("exclude_drawdown"))
+	// 只显示自选饰品
+	watchlist, _ := strconv.ParseBool(c.Query("watchlist"))
+	// 设置方案ID，为空使用默认方案
+	profileId, _ := strconv.ParseUint(c.Query("profile"), 10, 64)
+	// 排除报价被标记为异常的饰品
+	excludeAnomaly, _ := strconv.ParseBool(c.Query("exclude_anomaly"))
+	// 排除行情过期的饰品，否则只在 stale 字段标记过期原因
+	excludeStale, _ := strconv.ParseBool(c.Query("exclude_stale"))
+
+	return &models.GoodsQuery{
+		UserId:           getUserIdFromContext(c),
+		ProfileId:        uint(profileId),
+		IsDesc:           desc,
+		SortField:        c.Query("sort"),
+		Search:           c.Query("search"),
+		Source:           c.Query("source"),
+		Target:           c.Query("target"),
+		Category:         c.Query("category"),
+		BuyType:          buyType,
+		SellType:         sellType,
+		MinNetProfit:     queryAmountPtr(c, "min_net_profit"),
+		MinNetProfitRate: queryFloatPtr(c, "min_net_profit_rate"),
+		MinLiquidity:     queryFloatPtr(c, "min_liquidity"),
+		ExcludeDrawdown:  excludeDrawdown,
+		Watchlist:        watchlist,
+		ExcludeAnomaly:   excludeAnomaly,
+		MaxAge:           queryMaxAge(c),
+		ExcludeStale:     excludeStale,
+	}
+}
+
+// queryMaxAge 读取行情最大有效时长（秒），未传或不大于 0 时返回 nil，使用各平台配置
+func queryMaxAge(c *gin.Context) *int64 {
+	seconds, err := strconv.ParseInt(c.Query("max_age"), 10, 64)
+	if err != nil || seconds <= 0 {
+		return nil
+	}
+	return &seconds
+}
+
+// queryFloatPtr 读取可选的浮点型查询参数，未传或格式错误时返回 nil
+func queryFloatPtr(c *gin.Context, key string) *float64 {
+	val, ok := c.GetQuery(key)
+	if !ok || val == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"uu/services"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz 存活检查，进程能处理请求即返回 200
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+		"data": gin.H{"status": services.HealthOK, "uptime": services.Uptime()},
+	})
+}
+
+// Readyz 就绪检查，MySQL / Redis 不可用时返回 503，行情过期和 token 失效只标记为 warn
+func Readyz(c *gin.Context) {
+	report := services.CheckReadiness(c.Request.Context())
+	status, code := http.StatusOK, utils.SUCCESS
+	if !report.Ready {
+		status, code = http.StatusServiceUnavailable, utils.ErrCodeNotReady
+	}
+	c.JSON(status, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": report,
+	})
+}
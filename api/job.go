@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"uu/config"
+	"uu/models"
+	"uu/services"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobRequest 定时任务操作请求
+type JobRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// GetJobs 获取所有定时任务的状态和最近一次运行（管理员API）
+func GetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+		"data": services.ListJobs(),
+	})
+}
+
+// GetJobRuns 分页获取定时任务运行记录（管理员API），job / status 为空不限制
+func GetJobRuns(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+
+	runs, total, code := models.GetJobRuns(c.Query("job"), c.Query("status"), pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"code":  code,
+		"msg":   utils.ErrorMessage(code),
+		"data":  runs,
+		"total": total,
+	})
+}
+
+// GetSweepProgress 获取全量行情扫描的进度、预计剩余时间和上一轮耗时（管理员API）
+func GetSweepProgress(c *gin.Context) {
+	progress, err := services.GetSweepProgress()
+	if err != nil {
+		config.Log.Errorf("Get sweep progress error: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeGetSweepProgress,
+			"msg":  utils.ErrorMessage(utils.ErrCodeGetSweepProgress),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+		"data": progress,
+	})
+}
+
+// GetRefreshPriorities 按分数从高到低分页获取饰品刷新优先级（管理员API），tier 为空不限制
+func GetRefreshPriorities(c *gin.Context) {
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+
+	list, total, code := models.GetRefreshPriorities(c.Query("tier"), pageSize, pageNum)
+	c.JSON(http.StatusOK, gin.H{
+		"code":  code,
+		"msg":   utils.ErrorMessage(code),
+		"data":  list,
+		"total": total,
+	})
+}
+
+// PauseJob 暂停定时任务的计划执行（管理员API）
+func PauseJob(c *gin.Context) {
+	setJobPaused(c, true)
+}
+
+// ResumeJob 恢复定时任务的计划执行（管理员API）
+func ResumeJob(c *gin.Context) {
+	setJobPaused(c, false)
+}
+
+func setJobPaused(c *gin.Context, paused bool) {
+	var req JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := services.SetJobPaused(req.Name, paused)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// TriggerJob 立即执行一次定时任务（管理员API），暂停中的任务也可触发
+func TriggerJob(c *gin.Context) {
+	var req JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := services.TriggerJob(req.Name)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
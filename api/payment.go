@@ -207,6 +207,7 @@ func CreatePayOrder(c *gin.Context) {
 
 	// 调用YunGouOS Native支付
 	payResp, err := services.CreateNativePay(
+		c.Request.Context(),
 		outTradeNo,
 		plan.Price,
 		body,
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPlatformFees 获取各平台手续费配置（管理员API）
+func GetPlatformFees(c *gin.Context) {
+	fees, err := models.GetAllPlatformFees()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeGetPlatformFee,
+			"msg":  utils.ErrorMessage(utils.ErrCodeGetPlatformFee),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"data": fees,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+	})
+}
+
+// UpdatePlatformFee 修改平台手续费配置（管理员API）
+func UpdatePlatformFee(c *gin.Context) {
+	var req struct {
+		Platform    string  `json:"platform" binding:"required"`
+		SellerFee   float64 `json:"seller_fee" binding:"min=0,lt=1"`
+		WithdrawFee float64 `json:"withdraw_fee" binding:"min=0,lt=1"`
+		MinFee      float64 `json:"min_fee" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	if _, ok := models.GetPlatformByCode(req.Platform); !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	if err := models.UpdatePlatformFee(req.Platform, req.SellerFee, req.WithdrawFee, req.MinFee); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeUpdatePlatformFee,
+			"msg":  utils.ErrorMessage(utils.ErrCodeUpdatePlatformFee),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+	})
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"uu/models"
+	"uu/services"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HoldingRequest 添加/修改持仓请求，buy_platform 为平台标识（如 uu），buy_date 格式 2006-01-02，为空为当天
+type HoldingRequest struct {
+	ID             uint    `json:"id"`
+	MarketHashName string  `json:"market_hash_name" binding:"required,max=255"`
+	Quantity       int64   `json:"quantity" binding:"min=1"`
+	BuyPrice       float64 `json:"buy_price" binding:"min=0"`
+	BuyPlatform    string  `json:"buy_platform"`
+	BuyDate        string  `json:"buy_date"`
+	Note           string  `json:"note" binding:"max=255"`
+}
+
+// SellHoldingRequest 卖出持仓请求，quantity 小于持有数量时部分卖出
+type SellHoldingRequest struct {
+	ID           uint    `json:"id" binding:"required"`
+	Quantity     int64   `json:"quantity" binding:"min=1"`
+	SellPrice    float64 `json:"sell_price" binding:"gt=0"`
+	SellPlatform string  `json:"sell_platform" binding:"required"`
+	SellDate     string  `json:"sell_date"`
+}
+
+// parseDate 解析 2006-01-02 格式日期，为空时为当天
+func parseDate(s string) (time.Time, bool) {
+	now := time.Now()
+	if s == "" {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), true
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, now.Location())
+	return t, err == nil
+}
+
+// toHolding 校验请求并转换为持仓记录
+func (r *HoldingRequest) toHolding() (*models.Holding, bool) {
+	date, ok := parseDate(r.BuyDate)
+	if !ok {
+		return nil, false
+	}
+	h := &models.Holding{
+		MarketHashName: r.MarketHashName,
+		Quantity:       r.Quantity,
+		BuyPrice:       r.BuyPrice,
+		BuyDate:        date,
+		Note:           r.Note,
+	}
+	if r.BuyPlatform != "" {
+		p, ok := models.GetPlatformByKey(r.BuyPlatform)
+		if !ok {
+			return nil, false
+		}
+		h.BuyPlatform = p.Code
+	}
+	return h, true
+}
+
+// GetPortfolio 获取持仓概览：估值、未实现/已实现盈亏、最佳卖出平台
+func GetPortfolio(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	summary, code := models.GetPortfolio(userID)
+	jsonWithCurrency(c, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": summary,
+	})
+}
+
+// GetPortfolioChart 获取组合价值曲线，days 默认 90，最大 366
+func GetPortfolioChart(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "90"))
+	if err != nil || days <= 0 || days > 366 {
+		days = 90
+	}
+	points, code := models.GetPortfolioChart(userID, days)
+	jsonWithCurrency(c, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": points,
+	})
+}
+
+// CreateHolding 添加持仓
+func CreateHolding(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req HoldingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	h, ok := req.toHolding()
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	h.UserID = userID
+	code := models.CreateHolding(h)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": h,
+	})
+}
+
+// UpdateHolding 修改持仓
+func UpdateHolding(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req HoldingRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	h, ok := req.toHolding()
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.UpdateHolding(userID, req.ID, h)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// DeleteHolding 删除持仓记录
+func DeleteHolding(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.DeleteHolding(userID, uint(id))
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// SellHolding 记录卖出，按卖出平台手续费计算已实现盈亏
+func SellHolding(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req SellHoldingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	platform, ok := models.GetPlatformByKey(req.SellPlatform)
+	date, dateOk := parseDate(req.SellDate)
+	if !ok || !dateOk {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.SellHolding(userID, req.ID, req.Quantity, req.SellPrice, platform, date)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// ImportInventory 导入系统悠悠/BUFF 账号的库存到当前用户持仓（管理员API），买入价留空由用户补填，见 docs/portfolio.md
+func ImportInventory(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req struct {
+		Source string `json:"source" binding:"required,oneof=uu buff"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	imported, code := services.ImportInventory(c.Request.Context(), userID, req.Source)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": gin.H{"imported": imported},
+	})
+}
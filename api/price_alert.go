@@ -0,0 +1,239 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PriceAlertRequest 创建/更新价格预警请求
+type PriceAlertRequest struct {
+	ID              uint    `json:"id"` // 更新时必填
+	MarketHashName  string  `json:"market_hash_name" binding:"required,max=255"`
+	AlertType       string  `json:"alert_type" binding:"required"` // price_below / price_above / spread_above
+	Platform        string  `json:"platform"`                      // 价格预警：uu, buff, c5, steam
+	SourcePlatform  string  `json:"source_platform"`               // 价差预警：买入平台
+	TargetPlatform  string  `json:"target_platform"`               // 价差预警：卖出平台
+	Threshold       float64 `json:"threshold" binding:"required,gt=0"`
+	CooldownMinutes int     `json:"cooldown_minutes" binding:"min=0,max=10080"`
+	EmailEnabled    bool    `json:"email_enabled"`
+	Enabled         *bool   `json:"enabled"`
+}
+
+// toModel 校验请求并转换为预警模型
+func (r *PriceAlertRequest) toModel(userID uuid.UUID) (*models.PriceAlert, bool) {
+	if !models.IsValidAlertType(r.AlertType) {
+		return nil, false
+	}
+	if r.AlertType == models.AlertTypeSpreadAbove {
+		if !models.IsValidAlertPlatform(r.SourcePlatform) || !models.IsValidAlertPlatform(r.TargetPlatform) ||
+			r.SourcePlatform == r.TargetPlatform {
+			return nil, false
+		}
+		r.Platform = ""
+	} else {
+		if !models.IsValidAlertPlatform(r.Platform) {
+			return nil, false
+		}
+		r.SourcePlatform, r.TargetPlatform = "", ""
+	}
+
+	cooldown := r.CooldownMinutes
+	if cooldown == 0 {
+		cooldown = models.DefaultAlertCooldown
+	}
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+
+	return &models.PriceAlert{
+		ID:              r.ID,
+		UserID:          userID,
+		MarketHashName:  r.MarketHashName,
+		AlertType:       r.AlertType,
+		Platform:        r.Platform,
+		SourcePlatform:  r.SourcePlatform,
+		TargetPlatform:  r.TargetPlatform,
+		Threshold:       r.Threshold,
+		CooldownMinutes: cooldown,
+		EmailEnabled:    r.EmailEnabled,
+		Enabled:         enabled,
+	}, true
+}
+
+// getUserUUIDFromContext 从上下文获取用户ID
+func getUserUUIDFromContext(c *gin.Context) (uuid.UUID, bool) {
+	userIDVal, _ := c.Get("userID")
+	userID, ok := userIDVal.(uuid.UUID)
+	return userID, ok
+}
+
+// GetPriceAlerts 获取当前用户的价格预警列表
+func GetPriceAlerts(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	alerts, code := models.GetUserPriceAlerts(userID)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": alerts,
+	})
+}
+
+// CreatePriceAlert 创建价格预警
+func CreatePriceAlert(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req PriceAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	req.ID = 0
+	alert, valid := req.toModel(userID)
+	if !valid {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  "无效的预警类型或平台",
+		})
+		return
+	}
+
+	code := models.CreatePriceAlert(alert)
+	if code != utils.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  utils.ErrorMessage(code),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  "创建成功",
+		"data": alert,
+	})
+}
+
+// UpdatePriceAlert 更新价格预警
+func UpdatePriceAlert(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req PriceAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	alert, valid := req.toModel(userID)
+	if !valid {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  "无效的预警类型或平台",
+		})
+		return
+	}
+
+	code := models.UpdatePriceAlert(userID, alert)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// DeletePriceAlert 删除价格预警
+func DeletePriceAlert(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	alertID, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || alertID == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.DeletePriceAlert(userID, uint(alertID))
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// GetAlertTriggers 获取预警触发记录，可按 alert_id 过滤
+func GetAlertTriggers(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	alertID, _ := strconv.ParseUint(c.DefaultQuery("alert_id", "0"), 10, 64)
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+
+	triggers, total, code := models.GetAlertTriggers(userID, uint(alertID), pageSize, pageNum)
+	if code != utils.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  utils.ErrorMessage(code),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":      utils.SUCCESS,
+		"data":      triggers,
+		"total":     total,
+		"page_size": pageSize,
+		"page_num":  pageNum,
+	})
+}
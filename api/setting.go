@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 	"uu/models"
 	"uu/utils"
 
@@ -59,3 +60,115 @@ func getUserIdFromContext(c *gin.Context) string {
 		return ""
 	}
 }
+
+// validateSettingProfile 校验设置方案中的平台标识和买卖方案
+func validateSettingProfile(s *models.Settings) bool {
+	if s.Name == "" || len([]rune(s.Name)) > 50 {
+		return false
+	}
+	for _, key := range []string{s.SourcePlatform, s.TargetPlatform} {
+		if _, ok := models.GetPlatformByKey(key); key != "" && !ok {
+			return false
+		}
+	}
+	for _, t := range []string{s.BuyType, s.SellType} {
+		if t != "" && t != "sell" && t != "bidding" {
+			return false
+		}
+	}
+	if s.DisplayCurrency != "" {
+		s.DisplayCurrency = models.NormalizeCurrency(s.DisplayCurrency)
+		if _, ok := models.GetFxRateMap()[s.DisplayCurrency]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSettingProfiles 获取当前用户的所有设置方案
+func GetSettingProfiles(c *gin.Context) {
+	profiles, code := models.GetUserSettingProfiles(getUserIdFromContext(c))
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"data": profiles,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// CreateSettingProfile 新建设置方案
+func CreateSettingProfile(c *gin.Context) {
+	var s models.Settings
+	if err := c.ShouldBindJSON(&s); err != nil || !validateSettingProfile(&s) {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := models.CreateSettingProfile(getUserIdFromContext(c), &s)
+	if code != utils.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  utils.ErrorMessage(code),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"data": s,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// UpdateSettingProfile 更新设置方案，请求体中的 ID 指定方案
+func UpdateSettingProfile(c *gin.Context) {
+	var s models.Settings
+	if err := c.ShouldBindJSON(&s); err != nil || s.ID == 0 || !validateSettingProfile(&s) {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := models.UpdateSettingProfile(getUserIdFromContext(c), s)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// DeleteSettingProfile 删除设置方案
+func DeleteSettingProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := models.DeleteSettingProfile(getUserIdFromContext(c), uint(id))
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// SetDefaultSettingProfile 设为默认方案
+func SetDefaultSettingProfile(c *gin.Context) {
+	var req struct {
+		ID uint `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	code := models.SetDefaultSettingProfile(getUserIdFromContext(c), req.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
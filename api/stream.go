@@ -0,0 +1,130 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uu/config"
+	"uu/middleware"
+	"uu/models"
+	"uu/services"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 推送的搬砖机会数量默认值和上限
+const (
+	defaultStreamLimit = 50
+	maxStreamLimit     = 200
+)
+
+// CreateStreamTicket 生成建立推送连接用的一次性票据，有效期 30 秒
+func CreateStreamTicket(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	ticket, err := models.CreateStreamTicket(c.Request.Context(), token)
+	if err != nil {
+		config.Log.Errorf("Create stream ticket error: %v", err)
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeCreateStreamTicket,
+			"msg":  utils.ErrorMessage(utils.ErrCodeCreateStreamTicket),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": utils.SUCCESS,
+		"msg":  utils.ErrorMessage(utils.SUCCESS),
+		"data": gin.H{"ticket": ticket, "expires_in": int(models.StreamTicketTTL.Seconds())},
+	})
+}
+
+// StreamOpportunities 以 SSE 推送符合用户设置的搬砖机会
+// 连接建立后先推送一次 snapshot（全量），之后每轮行情更新后推送 diff（新增/消失/价格变化）
+// 筛选参数与 /vip/goods/data 相同，limit 为跟踪的机会数量（按排序取前 N 条）
+func StreamOpportunities(c *gin.Context) {
+	q := parseGoodsQuery(c)
+	q.PageNum = 1
+	q.PageSize = defaultStreamLimit
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 && limit <= maxStreamLimit {
+		q.PageSize = limit
+	}
+
+	updates, cancel, ok := services.SubscribeOpportunities(q.UserId)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeRateLimitExceeded,
+			"msg":  utils.ErrorMessage(utils.ErrCodeRateLimitExceeded),
+		})
+		return
+	}
+	defer cancel()
+
+	list, current, code := loadOpportunities(q)
+	if code != utils.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  utils.ErrorMessage(code),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁用 nginx 缓冲
+
+	// 推送的金额与列表接口一样按展示币种换算
+	c.SSEvent("snapshot", convertAmounts(c, list))
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-services.OpportunityStreamsClosing():
+			return false
+		case <-heartbeat.C:
+			// 心跳时同时检查是否已在其他设备登录或 VIP 已过期
+			if !middleware.StillAuthorized(c) {
+				c.SSEvent("unauthorized", gin.H{"code": utils.ErrCodeTokenKicked, "msg": utils.ErrorMessage(utils.ErrCodeTokenKicked)})
+				return false
+			}
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-updates:
+			if !middleware.StillAuthorized(c) {
+				c.SSEvent("unauthorized", gin.H{"code": utils.ErrCodeTokenKicked, "msg": utils.ErrorMessage(utils.ErrCodeTokenKicked)})
+				return false
+			}
+			_, next, code := loadOpportunities(q)
+			if code != utils.SUCCESS {
+				return true
+			}
+			diff := services.DiffOpportunities(current, next)
+			current = next
+			if !diff.Empty() {
+				c.SSEvent("diff", convertAmounts(c, diff))
+			}
+			return true
+		}
+	})
+}
+
+// loadOpportunities 按筛选条件获取当前的搬砖机会，返回排序后的列表和 market_hash_name 索引
+func loadOpportunities(q *models.GoodsQuery) ([]models.Goods, map[string]models.Goods, int) {
+	query := *q // GetGoods 会用设置方案补全条件，复制一份避免影响下一轮
+	goods, _, code := models.GetGoods(&query)
+	if code != utils.SUCCESS {
+		return nil, nil, code
+	}
+	result := make(map[string]models.Goods, len(*goods))
+	for _, g := range *goods {
+		result[g.MarketHashName] = g
+	}
+	return *goods, result, utils.SUCCESS
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTradeCSVSize CSV 导入文件大小上限
+const maxTradeCSVSize = 2 << 20
+
+// TradeRequest 添加/修改交易记录请求，平台为标识（如 uu），时间格式 2006-01-02 15:04:05 或 2006-01-02
+// fee 为空时按卖出平台手续费计算
+type TradeRequest struct {
+	ID             uint     `json:"id"`
+	MarketHashName string   `json:"market_hash_name" binding:"required,max=255"`
+	Quantity       int64    `json:"quantity" binding:"min=1"`
+	BuyPlatform    string   `json:"buy_platform" binding:"required"`
+	BuyPrice       float64  `json:"buy_price" binding:"gt=0"`
+	BuyTime        string   `json:"buy_time" binding:"required"`
+	SellPlatform   string   `json:"sell_platform" binding:"required"`
+	SellPrice      float64  `json:"sell_price" binding:"gt=0"`
+	SellTime       string   `json:"sell_time" binding:"required"`
+	Fee            *float64 `json:"fee" binding:"omitempty,min=0"`
+	Note           string   `json:"note" binding:"max=255"`
+}
+
+// toTrade 与 CSV 导入使用相同的校验
+func (r *TradeRequest) toTrade() (*models.TradeJournal, *float64, error) {
+	fee := ""
+	if r.Fee != nil {
+		fee = strconv.FormatFloat(*r.Fee, 'f', -1, 64)
+	}
+	return models.ParseTrade(r.MarketHashName, strconv.FormatInt(r.Quantity, 10),
+		r.BuyPlatform, strconv.FormatFloat(r.BuyPrice, 'f', -1, 64), r.BuyTime,
+		r.SellPlatform, strconv.FormatFloat(r.SellPrice, 'f', -1, 64), r.SellTime,
+		fee, r.Note)
+}
+
+// GetTrades 分页获取交易记录
+func GetTrades(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page_num", "1"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+
+	trades, total, code := models.GetTrades(userID, pageSize, pageNum)
+	jsonWithCurrency(c, gin.H{
+		"code":  code,
+		"msg":   utils.ErrorMessage(code),
+		"data":  trades,
+		"total": total,
+	})
+}
+
+// CreateTrade 记录一笔已完成的交易，并关联买入时刻的行情
+func CreateTrade(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req TradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	trade, fee, err := req.toTrade()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	trade.UserID = userID
+	code := models.CreateTrade(trade, fee)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": trade,
+	})
+}
+
+// UpdateTrade 修改交易记录
+func UpdateTrade(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req TradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	trade, fee, err := req.toTrade()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	code := models.UpdateTrade(userID, req.ID, trade, fee)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": trade,
+	})
+}
+
+// DeleteTrade 删除交易记录
+func DeleteTrade(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.DeleteTrade(userID, uint(id))
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// GetTradeStats 交易统计：ROI、平均持有天数、胜率、按类别和平台组合的利润
+func GetTradeStats(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	stats, code := models.GetTradeStats(userID)
+	jsonWithCurrency(c, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": stats,
+	})
+}
+
+// ExportTrades 导出全部交易记录为 CSV
+func ExportTrades(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	trades, _, code := models.GetTrades(userID, 0, 0)
+	if code != utils.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"code": code,
+			"msg":  utils.ErrorMessage(code),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，便于 Excel 直接打开
+	w := csv.NewWriter(&buf)
+	_ = w.Write(models.TradeCSVHeader)
+	for i := range trades {
+		_ = w.Write(trades[i].CSVRecord())
+	}
+	w.Flush()
+
+	filename := fmt.Sprintf("trades_%s.csv", time.Now().Format("20060102"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// ImportTrades 从 CSV 文件（表单字段 file）导入交易记录，列与导出一致
+// 任意一行校验失败时整个文件不导入，并返回出错的行
+func ImportTrades(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil || file.Size > maxTradeCSVSize {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeImportTrades,
+			"msg":  utils.ErrorMessage(utils.ErrCodeImportTrades),
+		})
+		return
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	var trades []*models.TradeJournal
+	var fees []*float64
+	var lineErrors []string
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			lineErrors = append(lineErrors, fmt.Sprintf("line %d: %v", line, err))
+			break
+		}
+		if line == 1 {
+			record[0] = strings.TrimPrefix(record[0], "\xEF\xBB\xBF")
+			if strings.EqualFold(strings.TrimSpace(record[0]), models.TradeCSVHeader[0]) {
+				continue
+			}
+		}
+		if len(record) < len(models.TradeCSVHeader)-1 {
+			lineErrors = append(lineErrors, fmt.Sprintf("line %d: expected %d columns", line, len(models.TradeCSVHeader)))
+			continue
+		}
+		if len(record) < len(models.TradeCSVHeader) {
+			record = append(record, "") // 备注列可省略
+		}
+		trade, fee, err := models.ParseTrade(record[0], record[1], record[2], record[3], record[4],
+			record[5], record[6], record[7], record[8], record[9])
+		if err != nil {
+			lineErrors = append(lineErrors, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		trades = append(trades, trade)
+		fees = append(fees, fee)
+		if len(trades) > models.MaxTradesPerImport {
+			lineErrors = append(lineErrors, fmt.Sprintf("at most %d trades per import", models.MaxTradesPerImport))
+			break
+		}
+	}
+	if len(lineErrors) > 0 || len(trades) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+			"data": gin.H{"errors": lineErrors},
+		})
+		return
+	}
+
+	imported, code := models.ImportTrades(userID, trades, fees)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": gin.H{"imported": imported},
+	})
+}
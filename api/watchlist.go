@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WatchlistRequest 添加自选请求
+type WatchlistRequest struct {
+	MarketHashName string `json:"market_hash_name" binding:"required,max=255"`
+}
+
+// GetWatchlist 获取自选列表（含各平台快照和涨跌幅）
+func GetWatchlist(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	items, code := models.GetWatchlist(userID)
+	jsonWithCurrency(c, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+		"data": items,
+	})
+}
+
+// AddToWatchlist 添加自选饰品
+func AddToWatchlist(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	var req WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.AddToWatchlist(userID, req.MarketHashName)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
+
+// RemoveFromWatchlist 移除自选饰品
+func RemoveFromWatchlist(c *gin.Context) {
+	userID, ok := getUserUUIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidToken,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidToken),
+		})
+		return
+	}
+
+	marketHashName := c.Query("market_hash_name")
+	if marketHashName == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"code": utils.ErrCodeInvalidParams,
+			"msg":  utils.ErrorMessage(utils.ErrCodeInvalidParams),
+		})
+		return
+	}
+
+	code := models.RemoveFromWatchlist(userID, marketHashName)
+	c.JSON(http.StatusOK, gin.H{
+		"code": code,
+		"msg":  utils.ErrorMessage(code),
+	})
+}
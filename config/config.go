@@ -18,6 +18,22 @@ type Config struct {
 	SteamDt    *SteamDt            `yaml:"steamDt"`
 	Payment    *Payment            `yaml:"payment"`
 	ErrorAlert *ErrorAlert         `yaml:"errorAlert"`
+
+	PriceProviders *PriceProviders `yaml:"priceProviders"`
+	Platforms      []*Platform     `yaml:"platforms"`
+	Fx             *Fx             `yaml:"fx"`
+	Metrics        *Metrics        `yaml:"metrics"`
+}
+
+// Metrics Prometheus 指标配置
+type Metrics struct {
+	Addr string `yaml:"addr"` // 单独监听的地址，如 127.0.0.1:9100；为空时只能通过管理员接口 /api/v1/admin/metrics 访问
+}
+
+// Fx 汇率配置
+type Fx struct {
+	RatesFile          string            `yaml:"rates_file"`          // 启动时导入的汇率文件（JSON：{"USD": 7.2}），为空不导入
+	PlatformCurrencies map[string]string `yaml:"platform_currencies"` // 覆盖平台报价币种，key: 平台代码，如 STEAM: USD
 }
 
 type Payment struct {
@@ -35,6 +51,27 @@ type SteamDt struct {
 	Key string `yaml:"key"`
 }
 
+// PriceProviders 行情数据源配置
+type PriceProviders struct {
+	Enabled []string `yaml:"enabled"` // 启用的数据源：steamdt, uu, buff，为空时只使用 steamdt
+}
+
+// Platform 额外平台配置（内置平台：悠悠、BUFF、C5、Steam 无需配置）
+type Platform struct {
+	Code            string  `yaml:"code"`             // 平台代码，与数据源报价中的 platform 一致，如 IGXE
+	Key             string  `yaml:"key"`              // 接口参数标识，如 igxe
+	Table           string  `yaml:"table"`            // 行情数据表名，启动时自动建表
+	Name            string  `yaml:"name"`             // 显示名称
+	FullName        string  `yaml:"full_name"`        // 全称，为空时使用 name
+	LinkTemplate    string  `yaml:"link_template"`    // 商品链接模板，{id} 平台商品ID，{name} market_hash_name
+	SupportsBidding bool    `yaml:"supports_bidding"` // 是否支持求购
+	SellerFee       float64 `yaml:"seller_fee"`       // 默认卖家手续费率
+	WithdrawFee     float64 `yaml:"withdraw_fee"`     // 默认提现手续费率
+	MinFee          float64 `yaml:"min_fee"`          // 默认单笔最低手续费
+	Currency        string  `yaml:"currency"`         // 报价币种，默认 CNY
+	MaxAge          int64   `yaml:"max_age"`          // 行情最大有效时长（秒），超过视为过期，默认 1 小时
+}
+
 // ErrorAlert 错误告警配置
 type ErrorAlert struct {
 	Enabled     bool     `yaml:"enabled"`      // 是否启用错误告警
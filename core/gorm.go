@@ -26,10 +26,22 @@ func InitGorm() {
 	if err != nil {
 		config.Log.Panicf("DB connect fail: %s", err)
 	}
-	err = db.AutoMigrate(&models.U{}, &models.BaseGoods{}, &models.User{}, &models.Settings{}, &models.APIKey{}, &models.Buff{}, &models.C5{}, &models.Steam{}, &models.UBaseInfo{}, &models.PriceHistory{}, &models.PaymentOrder{}, &models.SystemConfig{}, &models.Notification{}, &models.NotificationRead{}, &models.VipPlan{}) // migrate schema
+	err = db.AutoMigrate(&models.BaseGoods{}, &models.User{}, &models.Settings{}, &models.APIKey{}, &models.UBaseInfo{}, &models.PriceHistory{}, &models.PaymentOrder{}, &models.SystemConfig{}, &models.Notification{}, &models.NotificationRead{}, &models.VipPlan{}, &models.PlatformFee{}, &models.PriceRisk{}, &models.PriceAlert{}, &models.AlertTrigger{}, &models.Watchlist{}, &models.PriceSnapshot{}, &models.Liquidity{}, &models.FxRate{}, &models.Holding{}, &models.PortfolioValuation{}, &models.TradeJournal{}, &models.BacktestJob{}, &models.AnomalyLog{}, &models.JobRun{}, &models.PriceSweep{}, &models.PriceSweepBatch{}, &models.RefreshPriority{}) // migrate schema
 	if err != nil {
 		config.Log.Panicf("migrate schema fail: %s", err)
 	}
+	// 平台行情表（内置平台 + settings.yaml 中配置的平台）
+	models.RegisterConfigPlatforms(config.CONFIG.Platforms)
+	if config.CONFIG.Fx != nil {
+		models.ApplyPlatformCurrencies(config.CONFIG.Fx.PlatformCurrencies)
+	}
+	if err = models.MigratePlatformTables(db); err != nil {
+		config.Log.Panicf("migrate platform tables fail: %s", err)
+	}
+	// 原有的单一设置迁移为默认设置方案
+	if err = models.MigrateSettingsProfiles(db); err != nil {
+		config.Log.Panicf("migrate settings profiles fail: %s", err)
+	}
 	sqlDb, _ := db.DB()
 	// SetMaxIdleConns: 设置空闲连接池中链接的最大数量
 	sqlDb.SetMaxIdleConns(config.CONFIG.Mysql.MaxIdleConns)
@@ -47,4 +59,23 @@ func InitGorm() {
 	if err := models.InitVipPlans(); err != nil {
 		config.Log.Errorf("init vip plans failed: %v", err)
 	}
+
+	// 初始化平台手续费默认值
+	if err := models.InitPlatformFees(); err != nil {
+		config.Log.Errorf("init platform fees failed: %v", err)
+	}
+
+	// 初始化汇率默认值，配置了汇率文件时以文件为准
+	if err := models.InitFxRates(); err != nil {
+		config.Log.Errorf("init fx rates failed: %v", err)
+	}
+	if config.CONFIG.Fx != nil && config.CONFIG.Fx.RatesFile != "" {
+		if n, err := models.LoadFxRatesFromFile(config.CONFIG.Fx.RatesFile); err != nil {
+			config.Log.Errorf("load fx rates file failed: %v", err)
+		} else {
+			config.Log.Infof("loaded %d fx rates from %s", n, config.CONFIG.Fx.RatesFile)
+		}
+	}
+	// 上次运行中断的回测任务无法继续，标记为失败
+	models.FailInterruptedBacktests()
 }
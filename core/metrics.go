@@ -0,0 +1,26 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"uu/config"
+	"uu/utils"
+)
+
+// InitMetricsServer 配置了 metrics.addr 时在该地址单独提供 /metrics，未配置返回 nil
+// 建议绑定内网或本机地址，该接口不做鉴权
+func InitMetricsServer() *http.Server {
+	if config.CONFIG.Metrics == nil || config.CONFIG.Metrics.Addr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", utils.MetricsHandler())
+	srv := &http.Server{Addr: config.CONFIG.Metrics.Addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			config.Log.Errorf("metrics server start fail: %v", err)
+		}
+	}()
+	config.Log.Infof("metrics server listening on %s", config.CONFIG.Metrics.Addr)
+	return srv
+}
@@ -5,6 +5,7 @@ import (
 	"uu/api"
 	"uu/config"
 	"uu/middleware"
+	"uu/utils"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -12,8 +13,12 @@ import (
 
 func InitRouter() *gin.Engine {
 	gin.SetMode(config.CONFIG.Server.Env)
-	r := gin.Default()
-	r.Use(gin.Recovery(), middleware.Cors(), middleware.Logger())
+	r := gin.New()
+	r.Use(middleware.GinLogger(), gin.Recovery())
+	// 探活接口在全局中间件之前注册，负载均衡的频繁探测不计入请求日志和指标
+	r.GET("healthz", api.Healthz)
+	r.GET("readyz", api.Readyz)
+	r.Use(middleware.Cors(), middleware.Logger())
 
 	// Gzip 压缩（节省带宽，压缩 JSON 响应）
 	r.Use(gzip.Gzip(gzip.DefaultCompression))
@@ -52,6 +57,8 @@ func InitRouter() *gin.Engine {
 		)
 		// 小程序配置（公开API）
 		public.GET("minapp-config", api.GetMinAppConfig)
+		// 汇率列表
+		public.GET("fx-rates", api.GetFxRates)
 	}
 
 	user := v1.Group("user")
@@ -155,8 +162,19 @@ func InitRouter() *gin.Engine {
 		goods.GET("data", api.GetGoods)
 		goods.GET("category", api.GetGoodsCategory)
 		goods.GET("price-history", api.GetPriceHistory)
+		goods.GET("candles", api.GetPriceCandles)
 		goods.GET("price-increase", api.GetPriceIncreaseByU)
 		goods.GET("big-item-bidding", api.GetBigItemBidding)
+		goods.GET("routes", api.GetArbitrageRoutes) // 全平台最优搬砖路线
+		goods.GET("steam-ratio", api.GetSteamRatio) // 挂刀比例
+	}
+
+	// 搬砖机会实时推送（SSE），不经过 vip:goods 限流；EventSource 先用请求头换取一次性票据，再通过 ticket 参数鉴权
+	stream := v1.Group("vip/stream")
+	stream.Use(middleware.StreamTicket(), middleware.AuthMiddleware(), middleware.AuthVIPMiddleware())
+	{
+		stream.POST("ticket", api.CreateStreamTicket)
+		stream.GET("opportunities", api.StreamOpportunities)
 	}
 
 	admin := v1.Group("admin")
@@ -185,6 +203,36 @@ func InitRouter() *gin.Engine {
 		admin.POST("vip-plan", api.CreateVipPlan)
 		admin.PUT("vip-plan", api.UpdateVipPlan)
 		admin.DELETE("vip-plan", api.DeleteVipPlan)
+		// 平台手续费
+		admin.GET("platform-fees", api.GetPlatformFees)
+		admin.PUT("platform-fee", api.UpdatePlatformFee)
+		// 汇率管理
+		admin.GET("fx-rates", api.GetFxRates)
+		admin.PUT("fx-rate", api.UpdateFxRate)
+		admin.POST("fx-rates/reload", api.ReloadFxRates)
+		// 导入系统悠悠/BUFF 账号的库存到自己的持仓
+		admin.POST("portfolio/import", api.ImportInventory)
+		// 异常报价记录
+		admin.GET("anomalies", api.GetAnomalyLogs)
+		// 数据新鲜度
+		admin.GET("freshness", api.GetFreshnessStats)
+		admin.PUT("freshness", api.UpdatePlatformMaxAge)
+		// 定时任务
+		admin.GET("jobs", api.GetJobs)
+		admin.GET("job-runs", api.GetJobRuns)
+		admin.POST("job/pause", api.PauseJob)
+		admin.POST("job/resume", api.ResumeJob)
+		admin.POST("job/trigger", api.TriggerJob)
+		admin.GET("price-sweep", api.GetSweepProgress)
+		admin.GET("refresh-priorities", api.GetRefreshPriorities)
+		// Prometheus 指标，也可配置 metrics.addr 在单独的地址提供
+		admin.GET("metrics", gin.WrapH(utils.MetricsHandler()))
+		// SteamDT API key 池
+		admin.GET("api-keys", api.GetAPIKeys)
+		admin.POST("api-key", api.AddAPIKey)
+		admin.DELETE("api-key", api.DeleteAPIKey)
+		admin.POST("api-key/disable", api.DisableAPIKey)
+		admin.POST("api-key/enable", api.EnableAPIKey)
 	}
 
 	tokens := admin.Group("tokens")
@@ -196,10 +244,66 @@ func InitRouter() *gin.Engine {
 
 	}
 
+	// 自选饰品
+	watchlist := vip.Group("watchlist")
+	{
+		watchlist.GET("", api.GetWatchlist)
+		watchlist.POST("", api.AddToWatchlist)
+		watchlist.DELETE("", api.RemoveFromWatchlist)
+	}
+
+	// 持仓与盈亏
+	portfolio := vip.Group("portfolio")
+	{
+		portfolio.GET("", api.GetPortfolio)
+		portfolio.GET("chart", api.GetPortfolioChart) // 组合价值曲线
+		portfolio.POST("holding", api.CreateHolding)
+		portfolio.PUT("holding", api.UpdateHolding)
+		portfolio.DELETE("holding", api.DeleteHolding)
+		portfolio.POST("holding/sell", api.SellHolding)
+	}
+
+	// 交易记录
+	trades := vip.Group("trades")
+	{
+		trades.GET("", api.GetTrades)
+		trades.POST("", api.CreateTrade)
+		trades.PUT("", api.UpdateTrade)
+		trades.DELETE("", api.DeleteTrade)
+		trades.GET("stats", api.GetTradeStats)
+		trades.GET("export", api.ExportTrades)  // CSV 导出
+		trades.POST("import", api.ImportTrades) // CSV 导入
+	}
+
+	// 历史回测
+	backtests := vip.Group("backtests")
+	{
+		backtests.GET("", api.GetBacktestJobs)
+		backtests.POST("", api.CreateBacktest)
+		backtests.GET("detail", api.GetBacktestJob) // 任务状态和结果
+		backtests.DELETE("", api.DeleteBacktestJob)
+	}
+
+	// 价格预警
+	alerts := vip.Group("alerts")
+	{
+		alerts.GET("", api.GetPriceAlerts)
+		alerts.POST("", api.CreatePriceAlert)
+		alerts.PUT("", api.UpdatePriceAlert)
+		alerts.DELETE("", api.DeletePriceAlert)
+		alerts.GET("triggers", api.GetAlertTriggers) // 触发记录
+	}
+
 	settings := vip.Group("settings")
 	{
 		settings.GET("", api.GetSettings)
 		settings.PUT("", api.UpdateSetting)
+		// 多设置方案
+		settings.GET("profiles", api.GetSettingProfiles)
+		settings.POST("profiles", api.CreateSettingProfile)
+		settings.PUT("profiles", api.UpdateSettingProfile)
+		settings.DELETE("profiles", api.DeleteSettingProfile)
+		settings.POST("profiles/default", api.SetDefaultSettingProfile)
 	}
 
 	// 支付相关API
@@ -1,30 +1,63 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 	"uu/config"
 	"uu/core"
 	"uu/models"
 	"uu/services"
 )
 
+// shutdownTimeout 收到退出信号后等待请求和任务结束的最长时间
+const shutdownTimeout = 60 * time.Second
+
 func main() {
 	core.InitConf()
 	core.InitLogger()
 	core.InitGorm()
 	core.InitRedis()
 	models.InitKeys()
+	services.InitKeyPool()
+
+	// SIGINT/SIGTERM 时取消，定时任务写完当前批次并记录断点后退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	go services.InitSteamItemNameIds()
-	go services.UpdateSteamItemNameIdsScheduler()
-	go services.UpdateSteamPricesScheduler()
-	go services.UpdateBaseGoodsScheduler()
-	go services.UpdateAllGoodsScheduler()
-	go services.UpdateIconScheduler()
-	go services.StartDailyPriceHistoryScheduler()
+	services.InitJobs()
+	services.StartScheduler(ctx)
 	r := core.InitRouter()
-	addr := config.CONFIG.Server.GetAddr()
-	err := r.Run(addr)
-	if err != nil {
-		config.Log.Panicf("server start fail: %s", err)
+	metricsSrv := core.InitMetricsServer()
+	srv := &http.Server{
+		Addr:    config.CONFIG.Server.GetAddr(),
+		Handler: r,
 	}
+	// 进行中的普通请求由 Shutdown 等待完成；机会推送等长连接在 Shutdown 开始时单独结束
+	srv.RegisterOnShutdown(services.CloseOpportunityStreams)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			config.Log.Panicf("server start fail: %s", err)
+		}
+	}()
 
+	<-ctx.Done()
+	stop()
+	config.Log.Info("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		config.Log.Errorf("server shutdown error: %v", err)
+	}
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
+	if err := services.WaitJobs(shutdownCtx); err != nil {
+		config.Log.Errorf("wait running jobs error: %v", err)
+	}
+	config.Log.Info("Server exited")
 }
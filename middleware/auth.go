@@ -8,6 +8,7 @@ import (
 	"uu/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func AuthMiddleware() gin.HandlerFunc {
@@ -67,6 +68,36 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// StreamTicket 浏览器 EventSource 无法设置请求头，允许通过 ticket 参数传递一次性票据，需放在 AuthMiddleware 之前
+func StreamTicket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if ticket := c.Query("ticket"); ticket != "" {
+				if token := models.ConsumeStreamTicket(c.Request.Context(), ticket); token != "" {
+					c.Request.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// StillAuthorized 长连接中重新校验登录状态：token 未被其他设备顶替，且 VIP 未过期
+func StillAuthorized(c *gin.Context) bool {
+	userID, ok := c.Get("userID")
+	if !ok {
+		return false
+	}
+	uid, ok := userID.(uuid.UUID)
+	if !ok {
+		return false
+	}
+	if !models.ValidateTokenVersion(c.Request.Context(), uid, c.GetString("clientType"), c.GetString("tokenVersion")) {
+		return false
+	}
+	return models.CanAccessVIPContent(getRoleFromContext(c), getExpiryFromContext(c))
+}
+
 func AuthVIPMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role := getRoleFromContext(c)
@@ -7,12 +7,50 @@ import (
 	"github.com/rifflock/lfshook"
 	"github.com/sirupsen/logrus"
 	"math"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 	"uu/config"
+	"uu/utils"
 )
 
+// sensitiveQueryParams 访问日志中需要脱敏的查询参数
+var sensitiveQueryParams = []string{"token", "ticket"}
+
+// RedactQuery 将查询参数中的凭据替换为 ***，解析失败时整体隐藏
+func RedactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "***"
+	}
+	redacted := false
+	for _, key := range sensitiveQueryParams {
+		if values.Has(key) {
+			values.Set(key, "***")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// GinLogger gin 默认格式的控制台访问日志，查询参数中的凭据脱敏
+func GinLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		path := p.Path
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i+1] + RedactQuery(path[i+1:])
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"), p.StatusCode, p.Latency, p.ClientIP, p.Method, path, p.ErrorMessage)
+	})
+}
+
 func Logger() gin.HandlerFunc {
 	logger := logrus.New()
 	logDir := "log"
@@ -57,7 +95,18 @@ func Logger() gin.HandlerFunc {
 			dataSize = 0
 		}
 		method := c.Request.Method
-		path := c.Request.URL
+		path := c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + RedactQuery(c.Request.URL.RawQuery)
+		}
+
+		// 按路由模板统计，避免路径参数产生大量标签值
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		utils.HTTPRequestsTotal.WithLabelValues(method, route, strconv.Itoa(statusCode)).Inc()
+		utils.HTTPRequestDuration.WithLabelValues(method, route).Observe(stopTime.Seconds())
 
 		entry := logger.WithFields(logrus.Fields{
 			"HostName":  hostName,
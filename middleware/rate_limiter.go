@@ -83,6 +83,7 @@ func handleRateLimit(c *gin.Context, key string, cfg RateLimiterConfig) {
 	c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime, 10))
 
 	if !allowed {
+		utils.RateLimitRejectedTotal.WithLabelValues(cfg.KeyPrefix).Inc()
 		c.JSON(http.StatusTooManyRequests, gin.H{
 			"code":       utils.ErrCodeRateLimitExceeded,
 			"msg":        utils.ErrorMessage(utils.ErrCodeRateLimitExceeded),
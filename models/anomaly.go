@@ -0,0 +1,260 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/utils"
+)
+
+// 异常报价原因
+const (
+	AnomalySellLow  = "sell_low"  // 在售价远低于历史和其他平台
+	AnomalySellHigh = "sell_high" // 在售价远高于历史和其他平台
+	AnomalyBidHigh  = "bid_high"  // 求购价远高于历史在售价和其他平台
+)
+
+// 异常检测参数
+const (
+	anomalyBaselineDays   = 14   // 历史基准使用最近多少天的每日价格
+	anomalyMinSamples     = 5    // 历史样本少于该数量时不做历史比较
+	anomalyZThreshold     = 6.0  // 稳健 z 分数阈值
+	anomalyMinDeviation   = 0.3  // 相对历史中位数的最小偏离比例，避免低价饰品小幅波动被标记
+	anomalyMinMADRatio    = 0.02 // MAD 下限（占中位数的比例），价格长期不变时 MAD 为 0
+	anomalyCrossDeviation = 0.4  // 相对其他平台当前价格中位数的最大偏离比例
+	anomalyCrossMinCount  = 2    // 其他平台报价少于该数量时不做跨平台比较
+	AnomalyLogRetainDays  = 30
+)
+
+// AnomalyLog 被标记为异常的报价记录（同一饰品平台连续异常只记录一次）
+type AnomalyLog struct {
+	ID             uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	MarketHashName string    `json:"market_hash_name" gorm:"type:varchar(255);index"`
+	Platform       string    `json:"platform" gorm:"type:varchar(20)"` // 平台代码
+	Reason         string    `json:"reason" gorm:"type:varchar(20)"`
+	Price          float64   `json:"price" currency:"amount"`          // 异常的报价（人民币）
+	HistoryMedian  float64   `json:"history_median" currency:"amount"` // 该平台近期每日在售价中位数，无历史时为 0
+	HistoryMAD     float64   `json:"history_mad" currency:"amount"`    // 近期每日在售价的绝对中位差
+	CrossMedian    float64   `json:"cross_median" currency:"amount"`   // 其他平台当前在售价中位数，不足时为 0
+	Score          float64   `json:"score"`                            // 稳健 z 分数，无历史时为 0
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+}
+
+// priceBaseline 某饰品某平台的历史价格基准
+type priceBaseline struct {
+	median float64
+	mad    float64
+	n      int
+}
+
+// 历史基准缓存，每天重建一次
+var baselines struct {
+	mu   sync.Mutex
+	date time.Time
+	data map[string]priceBaseline // key: market_hash_name + "|" + 平台代码
+}
+
+// median 中位数，会对切片排序
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// medianAbsDeviation 绝对中位差
+func medianAbsDeviation(values []float64, m float64) float64 {
+	devs := make([]float64, len(values))
+	for i, v := range values {
+		devs[i] = math.Abs(v - m)
+	}
+	return median(devs)
+}
+
+// loadBaselines 读取最近 anomalyBaselineDays 天的每日价格，计算各饰品各平台的中位数和 MAD
+func loadBaselines() map[string]priceBaseline {
+	today := getLocalToday()
+	baselines.mu.Lock()
+	defer baselines.mu.Unlock()
+	if baselines.data != nil && baselines.date.Equal(today) {
+		return baselines.data
+	}
+
+	result := make(map[string]priceBaseline)
+	rows, err := config.DB.Model(&PriceHistory{}).
+		Select("market_hash_name, platform, sell_price").
+		Where("record_date >= ? AND sell_price > 0", today.AddDate(0, 0, -anomalyBaselineDays)).
+		Order("market_hash_name, platform").Rows()
+	if err != nil {
+		config.Log.Errorf("Load price baselines error: %v", err)
+		return baselines.data
+	}
+	defer rows.Close()
+
+	var key string
+	var values []float64
+	flush := func() {
+		if key != "" && len(values) > 0 {
+			m := median(values)
+			result[key] = priceBaseline{median: m, mad: medianAbsDeviation(values, m), n: len(values)}
+		}
+	}
+	for rows.Next() {
+		var r struct {
+			MarketHashName string
+			Platform       string
+			SellPrice      float64
+		}
+		if err := config.DB.ScanRows(rows, &r); err != nil {
+			config.Log.Errorf("Scan price baseline error: %v", err)
+			return baselines.data
+		}
+		k := r.MarketHashName + "|" + r.Platform
+		if k != key {
+			flush()
+			key, values = k, values[:0]
+		}
+		values = append(values, r.SellPrice)
+	}
+	flush()
+
+	baselines.data = result
+	baselines.date = today
+	config.Log.Infof("Loaded %d price baselines", len(result))
+	return result
+}
+
+// AnomalyDetector 一轮行情更新中的异常检测，结合该平台近期历史和其他平台当前报价
+type AnomalyDetector struct {
+	baselines map[string]priceBaseline
+	logs      []*AnomalyLog
+}
+
+// NewAnomalyDetector 创建本轮的异常检测器
+func NewAnomalyDetector() *AnomalyDetector {
+	return &AnomalyDetector{baselines: loadBaselines()}
+}
+
+// Check 检查某平台的报价，others 为该饰品其他平台当前的在售价（人民币，key: 平台代码）
+// previous 为该平台上一轮的异常标记，新出现或原因变化时记录日志；返回异常原因，正常时为空
+func (d *AnomalyDetector) Check(marketHashName, platform string, sellPrice, biddingPrice float64, others map[string]float64, previous string) string {
+	base, hasHistory := d.baselines[marketHashName+"|"+platform]
+	hasHistory = hasHistory && base.n >= anomalyMinSamples
+
+	var crossValues []float64
+	for code, price := range others {
+		if code != platform && price > 0 {
+			crossValues = append(crossValues, price)
+		}
+	}
+	hasCross := len(crossValues) >= anomalyCrossMinCount
+	crossMedian := 0.0
+	if hasCross {
+		crossMedian = median(crossValues)
+	}
+
+	// 历史和跨平台都可比较时要求两者都偏离，真实的整体涨跌会同时反映在其他平台上
+	outlier := func(price float64) (bool, float64) {
+		histOut, score := false, 0.0
+		if hasHistory {
+			mad := math.Max(base.mad, base.median*anomalyMinMADRatio)
+			score = 0.6745 * (price - base.median) / mad
+			histOut = math.Abs(score) > anomalyZThreshold && math.Abs(price-base.median)/base.median > anomalyMinDeviation
+		}
+		crossOut := hasCross && math.Abs(price-crossMedian)/crossMedian > anomalyCrossDeviation
+		switch {
+		case hasHistory && hasCross:
+			return histOut && crossOut, score
+		case hasHistory:
+			return histOut, score
+		default:
+			return crossOut, score
+		}
+	}
+
+	reason, price, score := "", 0.0, 0.0
+	if sellPrice > 0 {
+		if out, s := outlier(sellPrice); out {
+			reason, price, score = AnomalySellHigh, sellPrice, s
+			if (hasHistory && sellPrice < base.median) || (!hasHistory && sellPrice < crossMedian) {
+				reason = AnomalySellLow
+			}
+		}
+	}
+	// 求购价只关心异常偏高（会制造虚假的求购出售机会）
+	if reason == "" && biddingPrice > 0 {
+		if out, s := outlier(biddingPrice); out && s >= 0 && (hasHistory || biddingPrice > crossMedian) {
+			reason, price, score = AnomalyBidHigh, biddingPrice, s
+		}
+	}
+
+	if reason != "" && reason != previous {
+		d.logs = append(d.logs, &AnomalyLog{
+			MarketHashName: marketHashName,
+			Platform:       platform,
+			Reason:         reason,
+			Price:          price,
+			HistoryMedian:  base.median,
+			HistoryMAD:     base.mad,
+			CrossMedian:    crossMedian,
+			Score:          math.Round(score*100) / 100,
+		})
+	}
+	return reason
+}
+
+// Flush 保存本轮新出现的异常记录
+func (d *AnomalyDetector) Flush() {
+	if len(d.logs) == 0 {
+		return
+	}
+	if err := config.DB.CreateInBatches(d.logs, 200).Error; err != nil {
+		config.Log.Errorf("Save anomaly logs error: %v", err)
+		return
+	}
+	config.Log.Warnf("Flagged %d anomalous quotes", len(d.logs))
+	d.logs = nil
+}
+
+// GetAnomalyLogs 分页获取异常报价记录，platform 为平台代码，为空不限制
+func GetAnomalyLogs(platform, search string, pageSize, pageNum int) ([]AnomalyLog, int64, int) {
+	var logs []AnomalyLog
+	var total int64
+	db := config.DB.Model(&AnomalyLog{})
+	if platform != "" {
+		db = db.Where("platform = ?", platform)
+	}
+	if search != "" {
+		db = db.Where("market_hash_name LIKE ?", "%"+search+"%")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		config.Log.Errorf("Get anomaly logs total error: %v", err)
+		return nil, 0, utils.ErrCodeGetAnomalies
+	}
+	err := db.Order("id DESC").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&logs).Error
+	if err != nil {
+		config.Log.Errorf("Get anomaly logs error: %v", err)
+		return nil, 0, utils.ErrCodeGetAnomalies
+	}
+	return logs, total, utils.SUCCESS
+}
+
+// CleanOldAnomalyLogs 清理超过保留天数的异常记录
+func CleanOldAnomalyLogs(days int) {
+	err := config.DB.Where("created_at < ?", time.Now().AddDate(0, 0, -days)).Delete(&AnomalyLog{}).Error
+	if err != nil {
+		config.Log.Errorf("Clean anomaly logs error: %v", err)
+	}
+}
+
+// anomalyFilter 排除异常报价的条件
+func anomalyFilter(table string) string {
+	return "COALESCE(" + table + ".anomaly, '') = ''"
+}
@@ -0,0 +1,107 @@
+package models
+
+import "testing"
+
+func TestAnomalyDetectorCheck(t *testing.T) {
+	const name = "AK-47 | Redline (Field-Tested)"
+	stable := priceBaseline{median: 100, mad: 2, n: 14}
+
+	tests := []struct {
+		name     string
+		baseline *priceBaseline
+		sell     float64
+		bid      float64
+		others   map[string]float64
+		previous string
+		want     string
+		wantLogs int
+	}{
+		{
+			name:     "normal price",
+			baseline: &stable,
+			sell:     102,
+			bid:      95,
+			others:   map[string]float64{"BUFF": 100, "C5": 101},
+		},
+		{
+			name:     "one yuan listing",
+			baseline: &stable,
+			sell:     1,
+			others:   map[string]float64{"BUFF": 100, "C5": 101},
+			want:     AnomalySellLow,
+			wantLogs: 1,
+		},
+		{
+			name:     "one yuan listing already flagged",
+			baseline: &stable,
+			sell:     1,
+			others:   map[string]float64{"BUFF": 100, "C5": 101},
+			previous: AnomalySellLow,
+			want:     AnomalySellLow,
+		},
+		{
+			name:     "bidding far above history",
+			baseline: &stable,
+			sell:     101,
+			bid:      300,
+			others:   map[string]float64{"BUFF": 100, "C5": 101},
+			want:     AnomalyBidHigh,
+			wantLogs: 1,
+		},
+		{
+			name:     "market-wide move confirmed by other platforms",
+			baseline: &stable,
+			sell:     51,
+			others:   map[string]float64{"BUFF": 50, "C5": 52},
+		},
+		{
+			name:     "cross-platform outlier without history",
+			sell:     200,
+			others:   map[string]float64{"BUFF": 100, "C5": 102},
+			want:     AnomalySellHigh,
+			wantLogs: 1,
+		},
+		{
+			name:     "cross-platform low outlier without history",
+			sell:     40,
+			others:   map[string]float64{"BUFF": 100, "C5": 102},
+			want:     AnomalySellLow,
+			wantLogs: 1,
+		},
+		{
+			name:   "own platform ignored in cross comparison",
+			sell:   200,
+			others: map[string]float64{"YOUPIN": 200, "BUFF": 100},
+		},
+		{
+			name:     "too little history",
+			baseline: &priceBaseline{median: 100, mad: 2, n: anomalyMinSamples - 1},
+			sell:     1,
+			others:   map[string]float64{"BUFF": 100},
+		},
+		{
+			name:     "too little history falls back to cross comparison",
+			baseline: &priceBaseline{median: 100, mad: 2, n: anomalyMinSamples - 1},
+			sell:     1,
+			others:   map[string]float64{"BUFF": 100, "C5": 101},
+			want:     AnomalySellLow,
+			wantLogs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &AnomalyDetector{baselines: map[string]priceBaseline{}}
+			if tt.baseline != nil {
+				d.baselines[name+"|YOUPIN"] = *tt.baseline
+			}
+			got := d.Check(name, "YOUPIN", tt.sell, tt.bid, tt.others, tt.previous)
+			if got != tt.want {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+			if len(d.logs) != tt.wantLogs {
+				t.Errorf("logs = %d, want %d", len(d.logs), tt.wantLogs)
+			}
+		})
+	}
+}
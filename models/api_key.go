@@ -7,15 +7,50 @@ import (
 	"os"
 	"time"
 	"uu/config"
+	"uu/utils"
 )
 
+// API key 状态
+const (
+	APIKeyActive   = "active"
+	APIKeyDisabled = "disabled"
+)
+
+// 新 key 默认额度：SteamDT 批量价格接口每个 key 每分钟 1 次
+const (
+	DefaultKeyQuota  = 1
+	DefaultKeyWindow = 60
+)
+
+// APIKey SteamDT API key 及其使用情况
 type APIKey struct {
-	ID        uint       `gorm:"primaryKey"`
-	Key       string     `gorm:"type:varchar(255);uniqueIndex;not null"`
-	LastUsed  *time.Time `gorm:"index"`
-	FailCount int        `gorm:"default:0"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Key            string     `json:"-" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Remark         string     `json:"remark" gorm:"type:varchar(100)"`
+	Status         string     `json:"status" gorm:"type:varchar(10);default:active"` // active / disabled
+	DisabledReason string     `json:"disabled_reason" gorm:"type:varchar(255)"`
+	Quota          int        `json:"quota" gorm:"default:1"`                         // 每个窗口内可请求的次数
+	Window         int        `json:"window" gorm:"column:window_seconds;default:60"` // 额度窗口（秒）
+	WindowStart    *time.Time `json:"window_start"`
+	Used           int        `json:"used"` // 当前窗口已使用次数
+	SuccessCount   int64      `json:"success_count"`
+	FailCount      int        `json:"fail_count" gorm:"default:0"`
+	RateLimitCount int        `json:"rate_limit_count"`         // 收到 4005 的次数
+	Consecutive    int        `json:"consecutive"`              // 连续失败（含限流）次数，成功后清零
+	Score          float64    `json:"score" gorm:"default:100"` // 健康分 0-100，按最近请求结果滑动计算
+	BackoffUntil   *time.Time `json:"backoff_until"`
+	LastError      string     `json:"last_error" gorm:"type:varchar(255)"`
+	LastUsed       *time.Time `json:"last_used" gorm:"index"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// MaskedKey 只显示首尾 4 位的 key，用于后台展示
+func (k *APIKey) MaskedKey() string {
+	if len(k.Key) <= 8 {
+		return "****"
+	}
+	return k.Key[:4] + "****" + k.Key[len(k.Key)-4:]
 }
 
 type APIKeyConfig struct {
@@ -39,14 +74,15 @@ func LoadAPIKeys(filename string) ([]string, error) {
 	return keyConfig.Keys, nil
 }
 
+// InitKeys 导入 key.json 中的 key，已存在的不覆盖其状态，运行期间通过管理接口维护
 func InitKeys() {
 	Keys, err := LoadAPIKeys("key.json")
-	if err != nil {
+	if err != nil || len(Keys) == 0 {
 		return
 	}
 	var ApiKeys []APIKey
 	for _, key := range Keys {
-		ApiKeys = append(ApiKeys, APIKey{Key: key})
+		ApiKeys = append(ApiKeys, APIKey{Key: key, Status: APIKeyActive, Quota: DefaultKeyQuota, Window: DefaultKeyWindow, Score: 100})
 	}
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Clauses(clause.OnConflict{
@@ -64,18 +100,43 @@ func InitKeys() {
 	config.Log.Info("Init Api key success")
 }
 
-func GetActivateKey() []APIKey {
-	var apiKey []APIKey
-	err := config.DB.Where("last_used is NULL or last_used < ?", time.Now().Add(-60*time.Second)).Find(&apiKey).Error
-	if err != nil {
-		config.Log.Errorf("Get activate key error: %v", err)
+// GetAPIKeys 获取所有 key
+func GetAPIKeys() ([]*APIKey, error) {
+	var keys []*APIKey
+	err := config.DB.Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// CreateAPIKey 新增 key
+func CreateAPIKey(key *APIKey) int {
+	var count int64
+	config.DB.Model(&APIKey{}).Where("`key` = ?", key.Key).Count(&count)
+	if count > 0 {
+		return utils.ErrCodeAPIKeyExists
+	}
+	if err := config.DB.Create(key).Error; err != nil {
+		config.Log.Errorf("Create api key error: %v", err)
+		return utils.ErrCodeAddAPIKey
 	}
-	return apiKey
+	return utils.SUCCESS
 }
 
-func UpdateLastUsed(key *APIKey) {
-	err := config.DB.Model(&key).Update("last_used", time.Now()).Error
-	if err != nil {
-		config.Log.Errorf("Update %v last_used fail: %v", key, err)
+// DeleteAPIKey 删除 key
+func DeleteAPIKey(id uint) int {
+	result := config.DB.Delete(&APIKey{}, id)
+	if result.Error != nil {
+		config.Log.Errorf("Delete api key %d error: %v", id, result.Error)
+		return utils.ErrCodeDeleteAPIKey
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrCodeAPIKeyNotFound
 	}
+	return utils.SUCCESS
+}
+
+// SaveAPIKeyState 保存 key 的状态和计数
+func SaveAPIKeyState(key *APIKey) error {
+	return config.DB.Model(key).Select("status", "disabled_reason", "quota", "window_seconds", "window_start", "used",
+		"success_count", "fail_count", "rate_limit_count", "consecutive", "score", "backoff_until", "last_error", "last_used").
+		Updates(key).Error
 }
@@ -0,0 +1,388 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"github.com/google/uuid"
+)
+
+// 回测任务状态
+const (
+	BacktestPending = "pending"
+	BacktestRunning = "running"
+	BacktestDone    = "done"
+	BacktestFailed  = "failed"
+)
+
+// 回测限制
+const (
+	MaxBacktestDays       = 366 // 回测区间最长天数
+	MaxBacktestHoldDays   = 30  // 最长持有天数
+	DefaultBacktestHold   = 7   // 默认持有天数（交易冷却期）
+	MaxBacktestJobsToKeep = 50  // 每个用户保留的回测任务数
+	backtestTopItems      = 50  // 结果中保留的饰品数量
+)
+
+// BacktestJob 回测任务，结果以 JSON 保存便于之后查看
+type BacktestJob struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uuid.UUID  `json:"-" gorm:"type:char(36);index;not null"`
+	ProfileID  uint       `json:"profile_id"`
+	Source     string     `json:"source" gorm:"type:varchar(20)"` // 买入平台代码
+	Target     string     `json:"target" gorm:"type:varchar(20)"` // 卖出平台代码
+	StartDate  time.Time  `json:"start_date" gorm:"type:date"`
+	EndDate    time.Time  `json:"end_date" gorm:"type:date"`
+	HoldDays   int        `json:"hold_days"`                                // 买入后多少天卖出
+	Settings   string     `json:"settings" gorm:"type:text"`                // 回测时使用的设置快照
+	Status     string     `json:"status" gorm:"type:varchar(10);index"`     // pending / running / done / failed
+	Error      string     `json:"error,omitempty" gorm:"type:varchar(255)"` // 失败原因
+	Result     string     `json:"-" gorm:"type:mediumtext"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// BacktestItem 单个饰品的回测结果
+type BacktestItem struct {
+	MarketHashName  string  `json:"market_hash_name"`
+	Opportunities   int     `json:"opportunities"`                     // 机会出现次数（连续多天算一次）
+	Days            int     `json:"days"`                              // 满足条件的天数
+	AvgProfit       float64 `json:"avg_profit" currency:"amount"`      // 出现当天的平均预期净利润
+	RealizedProfit  float64 `json:"realized_profit" currency:"amount"` // 持有 N 天后卖出的平均实际净利润
+	RealizedSamples int     `json:"realized_samples"`                  // 有 N 天后价格的次数
+}
+
+// BacktestDay 每日机会数量
+type BacktestDay struct {
+	Date          string `json:"date"`
+	Opportunities int    `json:"opportunities"` // 当天满足条件的饰品数
+	New           int    `json:"new"`           // 当天新出现的机会数
+}
+
+// BacktestResult 回测结果
+type BacktestResult struct {
+	Opportunities     int             `json:"opportunities"`                         // 机会次数（同一饰品连续多天算一次）
+	DistinctItems     int             `json:"distinct_items"`                        // 出现过机会的饰品数
+	AvgDurationDays   float64         `json:"avg_duration_days"`                     // 机会平均持续天数
+	MedianDuration    int             `json:"median_duration"`                       // 机会持续天数中位数
+	DurationHistogram map[int]int     `json:"duration_histogram"`                    // key: 持续天数（超过 7 天计入 7），value: 次数
+	AvgExpectedProfit float64         `json:"avg_expected_profit" currency:"amount"` // 出现当天的平均预期净利润
+	AvgExpectedRate   float64         `json:"avg_expected_rate"`
+	RealizedSamples   int             `json:"realized_samples"` // 有 N 天后卖出价的机会数
+	AvgRealizedProfit float64         `json:"avg_realized_profit" currency:"amount"`
+	AvgRealizedRate   float64         `json:"avg_realized_rate"`
+	RealizedWinRate   float64         `json:"realized_win_rate"` // N 天后卖出仍盈利的比例
+	Daily             []*BacktestDay  `json:"daily"`
+	TopItems          []*BacktestItem `json:"top_items"` // 按机会次数排序
+}
+
+// backtestPoint 某饰品某天的买卖价格
+type backtestPoint struct {
+	buyPrice  float64
+	sellPrice float64
+	sellCount int64
+}
+
+// backtestEpisode 一次连续的机会
+type backtestEpisode struct {
+	offset   int // 出现当天距回测开始日期的天数
+	duration int
+	profit   float64
+	rate     float64
+	buyPrice float64
+}
+
+// CreateBacktestJob 创建回测任务，超过保留数量时删除最早的任务
+func CreateBacktestJob(job *BacktestJob) int {
+	job.Status = BacktestPending
+	if err := config.DB.Create(job).Error; err != nil {
+		config.Log.Errorf("Create backtest job error: %v", err)
+		return utils.ErrCodeCreateBacktest
+	}
+	var ids []uint
+	config.DB.Model(&BacktestJob{}).Where("user_id = ?", job.UserID).
+		Order("id DESC").Offset(MaxBacktestJobsToKeep).Pluck("id", &ids)
+	if len(ids) > 0 {
+		config.DB.Where("id IN ?", ids).Delete(&BacktestJob{})
+	}
+	return utils.SUCCESS
+}
+
+// GetBacktestJobs 获取用户的回测任务列表（不含结果）
+func GetBacktestJobs(userID uuid.UUID) ([]BacktestJob, int) {
+	var jobs []BacktestJob
+	err := config.DB.Omit("result").Where("user_id = ?", userID).Order("id DESC").Find(&jobs).Error
+	if err != nil {
+		config.Log.Errorf("Get backtest jobs error: %v", err)
+		return nil, utils.ErrCodeGetBacktest
+	}
+	return jobs, utils.SUCCESS
+}
+
+// GetBacktestJob 获取回测任务及结果
+func GetBacktestJob(userID uuid.UUID, id uint) (*BacktestJob, *BacktestResult, int) {
+	var job BacktestJob
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		return nil, nil, utils.ErrCodeBacktestNotFound
+	}
+	if job.Result == "" {
+		return &job, nil, utils.SUCCESS
+	}
+	var result BacktestResult
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		config.Log.Errorf("Unmarshal backtest result error: %v", err)
+		return &job, nil, utils.ErrCodeGetBacktest
+	}
+	return &job, &result, utils.SUCCESS
+}
+
+// DeleteBacktestJob 删除回测任务
+func DeleteBacktestJob(userID uuid.UUID, id uint) int {
+	res := config.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&BacktestJob{})
+	if res.Error != nil {
+		config.Log.Errorf("Delete backtest job error: %v", res.Error)
+		return utils.ErrCodeGetBacktest
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrCodeBacktestNotFound
+	}
+	return utils.SUCCESS
+}
+
+// CountActiveBacktestJobs 用户未完成的回测任务数
+func CountActiveBacktestJobs(userID uuid.UUID) int64 {
+	var count int64
+	config.DB.Model(&BacktestJob{}).Where("user_id = ? AND status IN ?", userID,
+		[]string{BacktestPending, BacktestRunning}).Count(&count)
+	return count
+}
+
+// MarkBacktestRunning 标记任务开始执行
+func MarkBacktestRunning(id uint) {
+	config.DB.Model(&BacktestJob{}).Where("id = ?", id).Update("status", BacktestRunning)
+}
+
+// FinishBacktestJob 保存回测结果，err 不为空时标记失败
+func FinishBacktestJob(id uint, result *BacktestResult, err error) {
+	now := time.Now()
+	updates := map[string]interface{}{"status": BacktestDone, "finished_at": &now}
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > 255 {
+			msg = msg[:255]
+		}
+		updates["status"], updates["error"] = BacktestFailed, msg
+	} else if data, mErr := json.Marshal(result); mErr != nil {
+		updates["status"], updates["error"] = BacktestFailed, mErr.Error()
+	} else {
+		updates["result"] = string(data)
+	}
+	if dbErr := config.DB.Model(&BacktestJob{}).Where("id = ?", id).Updates(updates).Error; dbErr != nil {
+		config.Log.Errorf("Save backtest result error: %v", dbErr)
+	}
+}
+
+// FailInterruptedBacktests 服务重启时将未完成的任务标记为失败
+func FailInterruptedBacktests() {
+	now := time.Now()
+	config.DB.Model(&BacktestJob{}).Where("status IN ?", []string{BacktestPending, BacktestRunning}).
+		Updates(map[string]interface{}{"status": BacktestFailed, "error": "interrupted by restart", "finished_at": &now})
+}
+
+// RunBacktest 按设置回放 price_history：某天买入平台在售价在 [MinSellPrice, MaxSellPrice) 之间、
+// 卖出平台在售数 > MinSellNum、差价 > MinDiff 且扣除手续费后利润率 >= MinProfitRate 即视为出现机会，
+// 同一饰品连续多天满足条件算作一次机会；对每次机会按出现当天买入、holdDays 天后按卖出平台在售价卖出计算实际利润
+func RunBacktest(settings *SettingsResponse, source, target *PlatformDef, start, end time.Time, holdDays int) (*BacktestResult, error) {
+	var allowed map[string]bool
+	if settings.Categories != "" {
+		var names []string
+		err := config.DB.Model(&UBaseInfo{}).Where("type_name IN ?", strings.Split(settings.Categories, ",")).
+			Pluck("hash_name", &names).Error
+		if err != nil {
+			return nil, err
+		}
+		allowed = make(map[string]bool, len(names))
+		for _, name := range names {
+			allowed[name] = true
+		}
+	}
+
+	fee := target.Fee()
+	days := int(end.Sub(start).Hours()/24) + 1
+	result := &BacktestResult{DurationHistogram: map[int]int{}, Daily: make([]*BacktestDay, days)}
+	for i := range result.Daily {
+		result.Daily[i] = &BacktestDay{Date: start.AddDate(0, 0, i).Format("2006-01-02")}
+	}
+
+	var episodes []backtestEpisode
+	var items []*BacktestItem
+	var realizedSamples, realizedWins int
+	var realizedProfit, realizedRate float64
+
+	// 按饰品流式读取，避免一次加载整个区间
+	rows, err := config.DB.Model(&PriceHistory{}).
+		Select("market_hash_name, platform, sell_price, sell_count, record_date").
+		Where("platform IN ? AND record_date >= ? AND record_date <= ?",
+			[]string{source.Code, target.Code}, start, end.AddDate(0, 0, holdDays)).
+		Order("market_hash_name, record_date").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var current string
+	points := make(map[int]*backtestPoint)
+	flush := func() {
+		if current == "" || (allowed != nil && !allowed[current]) {
+			return
+		}
+		itemEpisodes := backtestItemEpisodes(points, settings, fee, days)
+		if len(itemEpisodes) == 0 {
+			return
+		}
+		item := &BacktestItem{MarketHashName: current, Opportunities: len(itemEpisodes)}
+		var profitSum, realizedSum float64
+		for _, ep := range itemEpisodes {
+			offset := ep.offset
+			item.Days += ep.duration
+			profitSum += ep.profit
+			result.Daily[offset].New++
+			for d := offset; d < offset+ep.duration && d < days; d++ {
+				result.Daily[d].Opportunities++
+			}
+			if p := points[offset+holdDays]; p != nil && p.sellPrice > 0 {
+				realized := fee.NetProceeds(p.sellPrice) - ep.buyPrice
+				realizedSum += realized
+				item.RealizedSamples++
+				realizedSamples++
+				realizedProfit += realized
+				realizedRate += realized / ep.buyPrice
+				if realized > 0 {
+					realizedWins++
+				}
+			}
+			episodes = append(episodes, ep)
+		}
+		item.AvgProfit = math.Round(profitSum/float64(len(itemEpisodes))*100) / 100
+		if item.RealizedSamples > 0 {
+			item.RealizedProfit = math.Round(realizedSum/float64(item.RealizedSamples)*100) / 100
+		}
+		items = append(items, item)
+	}
+
+	for rows.Next() {
+		var r PriceHistory
+		if err := config.DB.ScanRows(rows, &r); err != nil {
+			return nil, err
+		}
+		if r.MarketHashName != current {
+			flush()
+			current = r.MarketHashName
+			points = make(map[int]*backtestPoint)
+		}
+		offset := int(math.Round(r.RecordDate.Sub(start).Hours() / 24))
+		p := points[offset]
+		if p == nil {
+			p = &backtestPoint{}
+			points[offset] = p
+		}
+		if r.Platform == source.Code {
+			p.buyPrice = r.SellPrice
+		} else {
+			p.sellPrice, p.sellCount = r.SellPrice, r.SellCount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	summarizeBacktest(result, episodes, items)
+	if realizedSamples > 0 {
+		result.RealizedSamples = realizedSamples
+		result.AvgRealizedProfit = math.Round(realizedProfit/float64(realizedSamples)*100) / 100
+		result.AvgRealizedRate = math.Round(realizedRate/float64(realizedSamples)*10000) / 10000
+		result.RealizedWinRate = math.Round(float64(realizedWins)/float64(realizedSamples)*10000) / 10000
+	}
+	return result, nil
+}
+
+// backtestItemEpisodes 找出单个饰品在回测区间内的连续机会
+func backtestItemEpisodes(points map[int]*backtestPoint, settings *SettingsResponse, fee *PlatformFee, days int) []backtestEpisode {
+	var episodes []backtestEpisode
+	var cur *backtestEpisode
+	for d := 0; d < days; d++ {
+		p := points[d]
+		ok := false
+		var profit, rate float64
+		if p != nil && p.buyPrice > 0 && p.sellPrice > 0 &&
+			p.buyPrice >= settings.MinSellPrice && p.buyPrice < settings.MaxSellPrice &&
+			p.sellCount > int64(settings.MinSellNum) && p.sellPrice-p.buyPrice > settings.MinDiff {
+			profit = fee.NetProceeds(p.sellPrice) - p.buyPrice
+			rate = profit / p.buyPrice
+			ok = settings.MinProfitRate <= 0 || rate >= settings.MinProfitRate
+		}
+		if !ok {
+			if cur != nil {
+				episodes = append(episodes, *cur)
+				cur = nil
+			}
+			continue
+		}
+		if cur == nil {
+			cur = &backtestEpisode{offset: d, profit: profit, rate: rate, buyPrice: p.buyPrice}
+		}
+		cur.duration++
+	}
+	if cur != nil {
+		episodes = append(episodes, *cur)
+	}
+	return episodes
+}
+
+// summarizeBacktest 汇总机会次数、持续时间和预期利润，并按机会次数取前若干饰品
+func summarizeBacktest(result *BacktestResult, episodes []backtestEpisode, items []*BacktestItem) {
+	result.Opportunities = len(episodes)
+	result.DistinctItems = len(items)
+	if len(episodes) > 0 {
+		durations := make([]int, 0, len(episodes))
+		var totalDuration int
+		var profit, rate float64
+		for _, ep := range episodes {
+			durations = append(durations, ep.duration)
+			totalDuration += ep.duration
+			profit += ep.profit
+			rate += ep.rate
+			bucket := ep.duration
+			if bucket > 7 {
+				bucket = 7
+			}
+			result.DurationHistogram[bucket]++
+		}
+		sort.Ints(durations)
+		n := float64(len(episodes))
+		result.AvgDurationDays = math.Round(float64(totalDuration)/n*100) / 100
+		result.MedianDuration = durations[len(durations)/2]
+		result.AvgExpectedProfit = math.Round(profit/n*100) / 100
+		result.AvgExpectedRate = math.Round(rate/n*10000) / 10000
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Opportunities != items[j].Opportunities {
+			return items[i].Opportunities > items[j].Opportunities
+		}
+		if items[i].Days != items[j].Days {
+			return items[i].Days > items[j].Days
+		}
+		return items[i].MarketHashName < items[j].MarketHashName
+	})
+	if len(items) > backtestTopItems {
+		items = items[:backtestTopItems]
+	}
+	result.TopItems = items
+}
@@ -1,9 +1,6 @@
 package models
 
 import (
-	"strings"
-	"time"
-
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"uu/config"
@@ -15,6 +12,8 @@ type BuffItem struct {
 	ID       int64  `json:"id" gorm:"primaryKey"`
 	Price    string `json:"sell_min_price" gorm:"type:decimal(10,2)"`
 	Count    int64  `json:"sell_num" gorm:"type:int"`
+	BuyPrice string `json:"buy_max_price" gorm:"-"` // 最高求购价（仅行情快照使用）
+	BuyCount int64  `json:"buy_num" gorm:"-"`       // 求购数量（仅行情快照使用）
 	//GoodsInfo      GoodsInfo `json:"goods_info" gorm:"foreignKey:BuffItemID;references:ID"`
 }
 
@@ -40,13 +39,6 @@ func BatchAddBuffItem(buff []*BuffItem) {
 	}
 }
 
-func BatchAddBuffInventory(buff []*BuffInventory) {
-	err := config.DB.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(buff, 50).Error
-	if err != nil {
-		config.Log.Errorf("insert buff inventory error: %s", err)
-	}
-}
-
 // -------------------------------------------------------v2------------------------------------------------------------
 // data from steamDT
 
@@ -62,39 +54,6 @@ type Buff struct {
 	BeforeCount    int64   `json:"beforeCount"`
 	TurnOver       int64   `json:"turn_over"`
 	Link           string  `json:"link"`
-}
-
-func BatchGetBuffGoods(hashNames []string) map[string]*Buff {
-	var buffs []Buff
-	result := make(map[string]*Buff)
-	config.DB.Where("market_hash_name in ?", hashNames).Find(&buffs)
-	for i := range buffs {
-		result[buffs[i].MarketHashName] = &buffs[i]
-	}
-	return result
-}
-
-func BatchUpdateBuffGoods(buff []*Buff) {
-	maxRetries := 3
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		err = config.DB.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(buff, 50).Error; err != nil {
-				return err
-			}
-			return nil
-		})
-		if err == nil {
-			return
-		}
-		if strings.Contains(err.Error(), "Deadlock") || strings.Contains(err.Error(), "SAVEPOINT") {
-			config.Log.Warnf("Update Buff Goods deadlock, retrying (%d/%d)...", i+1, maxRetries)
-			time.Sleep(time.Millisecond * time.Duration(100*(i+1)))
-			continue
-		}
-		break
-	}
-	if err != nil {
-		config.Log.Errorf("Update Buff Goods fail: %v", err)
-	}
+	Currency       string  `json:"currency" gorm:"type:varchar(3);default:CNY"` // 原始报价币种，价格已换算为人民币
+	Anomaly        string  `json:"anomaly" gorm:"type:varchar(20)"`             // 异常报价原因，为空表示正常
 }
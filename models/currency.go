@@ -0,0 +1,154 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"sync"
+)
+
+// AmountTag 金额字段的结构体标签 `currency:"amount"`，接口返回时按展示币种换算
+// 支持 float64、*float64 以及值全部为金额的 map / 切片，如 map[string]float64
+const AmountTag = "amount"
+
+// amountTypes 类型是否（可能）包含金额字段的缓存，key: reflect.Type
+var amountTypes sync.Map
+
+// ConvertAmounts 将 v 中标记为金额的字段从人民币换算为指定币种
+// 返回换算后的副本，不修改 v 本身（可能是多个请求共用的缓存数据）；人民币或不支持的币种原样返回
+func ConvertAmounts(v interface{}, currency string, rates map[string]float64) interface{} {
+	rate, ok := rates[NormalizeCurrency(currency)]
+	if v == nil || !ok || rate <= 0 || rate == 1 {
+		return v
+	}
+	return convertAmounts(reflect.ValueOf(v), rate).Interface()
+}
+
+// convertAmounts 递归复制 v 并换算其中的金额字段，不含金额字段的类型直接返回原值
+func convertAmounts(v reflect.Value, rate float64) reflect.Value {
+	if !hasAmounts(v.Type()) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		return convertAmounts(v.Elem(), rate)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(convertAmounts(v.Elem(), rate))
+		return p
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v
+		}
+		s := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Slice {
+			s.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(convertAmounts(v.Index(i), rate))
+		}
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), convertAmounts(iter.Value(), rate))
+		}
+		return m
+	case reflect.Struct:
+		s := reflect.New(v.Type()).Elem()
+		s.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Tag.Get("currency") == AmountTag {
+				s.Field(i).Set(convertAmount(v.Field(i), rate))
+			} else {
+				s.Field(i).Set(convertAmounts(v.Field(i), rate))
+			}
+		}
+		return s
+	}
+	return v
+}
+
+// convertAmount 换算标记为金额的字段值，保留两位小数
+func convertAmount(v reflect.Value, rate float64) reflect.Value {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := reflect.New(v.Type()).Elem()
+		f.SetFloat(math.Round(v.Float()/rate*100) / 100)
+		return f
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(convertAmount(v.Elem(), rate))
+		return p
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(convertAmount(v.Index(i), rate))
+		}
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), convertAmount(iter.Value(), rate))
+		}
+		return m
+	}
+	return v
+}
+
+// hasAmounts 类型是否可能包含金额字段，interface 类型需要按实际值判断
+func hasAmounts(t reflect.Type) bool {
+	if cached, ok := amountTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	result := typeHasAmounts(t, make(map[reflect.Type]bool))
+	amountTypes.Store(t, result)
+	return result
+}
+
+func typeHasAmounts(t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasAmounts(t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Tag.Get("currency") == AmountTag || typeHasAmounts(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/utils"
+)
+
+// defaultMaxAge 平台行情默认最大有效时长（秒）
+const defaultMaxAge = 3600
+
+// configKeyMaxAgePrefix 系统配置中平台行情最大有效时长的键前缀，后接平台代码
+const configKeyMaxAgePrefix = "freshness_max_age:"
+
+// 过期原因
+const (
+	StaleSource = "source_stale" // 来源平台行情过期
+	StaleTarget = "target_stale" // 目标平台行情过期
+	StaleBoth   = "both_stale"   // 两个平台都过期
+)
+
+// maxAgeCacheTTL 最大有效时长缓存有效期，其他实例修改的配置最迟在该时间后生效
+const maxAgeCacheTTL = time.Minute
+
+// maxAgeEntry 缓存的平台最大有效时长
+type maxAgeEntry struct {
+	seconds  int64
+	loadedAt time.Time
+}
+
+// maxAgeCache 各平台最大有效时长缓存，key: 平台代码
+var maxAgeCache sync.Map
+
+// MaxAge 平台行情最大有效时长（秒），系统配置优先
+func (p *PlatformDef) MaxAge() int64 {
+	if v, ok := maxAgeCache.Load(p.Code); ok {
+		if entry := v.(maxAgeEntry); time.Since(entry.loadedAt) < maxAgeCacheTTL {
+			return entry.seconds
+		}
+	}
+	seconds := p.DefaultMaxAge
+	if v, ok := GetSystemConfig(configKeyMaxAgePrefix + p.Code); ok {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			seconds = n
+		}
+	}
+	maxAgeCache.Store(p.Code, maxAgeEntry{seconds: seconds, loadedAt: time.Now()})
+	return seconds
+}
+
+// SetPlatformMaxAge 设置平台行情最大有效时长（秒）
+func SetPlatformMaxAge(p *PlatformDef, seconds int64) error {
+	defer maxAgeCache.Delete(p.Code)
+	return SetSystemConfig(configKeyMaxAgePrefix+p.Code, strconv.FormatInt(seconds, 10),
+		fmt.Sprintf("%s 行情最大有效时长(秒)", p.Name))
+}
+
+// staleCutoff 早于该时间戳的行情视为过期，maxAge 不为空时覆盖平台配置
+func staleCutoff(p *PlatformDef, maxAge *int64) int64 {
+	age := p.MaxAge()
+	if maxAge != nil {
+		age = *maxAge
+	}
+	return time.Now().Unix() - age
+}
+
+// staleSQL 按来源、目标平台的过期时间生成过期原因表达式
+func staleSQL(sourceTable string, sourceCutoff int64, targetTable string, targetCutoff int64) string {
+	return fmt.Sprintf(`CASE
+			WHEN %s.update_time < %d AND %s.update_time < %d THEN '%s'
+			WHEN %s.update_time < %d THEN '%s'
+			WHEN %s.update_time < %d THEN '%s'
+			ELSE '' END`,
+		sourceTable, sourceCutoff, targetTable, targetCutoff, StaleBoth,
+		sourceTable, sourceCutoff, StaleSource,
+		targetTable, targetCutoff, StaleTarget)
+}
+
+// PlatformFreshness 平台行情更新时间分布
+type PlatformFreshness struct {
+	Platform   string `json:"platform"` // 平台标识
+	Name       string `json:"name"`
+	MaxAge     int64  `json:"max_age"`     // 最大有效时长（秒）
+	Total      int64  `json:"total"`       // 有价格的饰品数
+	Within10m  int64  `json:"within_10m"`  // 10 分钟内更新
+	Within1h   int64  `json:"within_1h"`   // 10 分钟 ~ 1 小时
+	Within1d   int64  `json:"within_1d"`   // 1 小时 ~ 1 天
+	Older      int64  `json:"older"`       // 超过 1 天
+	Never      int64  `json:"never"`       // 没有更新时间
+	Stale      int64  `json:"stale"`       // 超过最大有效时长
+	NewestTime int64  `json:"newest_time"` // 最近一次更新时间，为 0 说明抓取已停止或从未运行
+	NewestAge  int64  `json:"newest_age"`  // 距最近一次更新的秒数
+}
+
+// GetFreshnessStats 统计各平台行情的更新时间分布，用于发现停止工作的数据源
+func GetFreshnessStats() ([]*PlatformFreshness, int) {
+	now := time.Now().Unix()
+	result := make([]*PlatformFreshness, 0, len(Platforms()))
+	for _, p := range Platforms() {
+		stats := &PlatformFreshness{Platform: p.Key, Name: p.Name, MaxAge: p.MaxAge()}
+		selectSQL := fmt.Sprintf(`
+			COUNT(*) as total,
+			SUM(CASE WHEN update_time >= %d THEN 1 ELSE 0 END) as within10m,
+			SUM(CASE WHEN update_time < %d AND update_time >= %d THEN 1 ELSE 0 END) as within1h,
+			SUM(CASE WHEN update_time < %d AND update_time >= %d THEN 1 ELSE 0 END) as within1d,
+			SUM(CASE WHEN update_time < %d AND update_time > 0 THEN 1 ELSE 0 END) as older,
+			SUM(CASE WHEN COALESCE(update_time, 0) = 0 THEN 1 ELSE 0 END) as never,
+			SUM(CASE WHEN COALESCE(update_time, 0) < %d THEN 1 ELSE 0 END) as stale,
+			COALESCE(MAX(update_time), 0) as newest_time`,
+			now-600,
+			now-600, now-3600,
+			now-3600, now-86400,
+			now-86400,
+			now-stats.MaxAge)
+		var row struct {
+			Total      int64
+			Within10m  int64 `gorm:"column:within10m"`
+			Within1h   int64 `gorm:"column:within1h"`
+			Within1d   int64 `gorm:"column:within1d"`
+			Older      int64
+			Never      int64
+			Stale      int64
+			NewestTime int64
+		}
+		err := config.DB.Table(p.Table).Select(selectSQL).Where("sell_price > 0").Scan(&row).Error
+		if err != nil {
+			config.Log.Errorf("Get %s freshness error: %v", p.Code, err)
+			return nil, utils.ErrCodeGetFreshness
+		}
+		stats.Total, stats.Within10m, stats.Within1h, stats.Within1d = row.Total, row.Within10m, row.Within1h, row.Within1d
+		stats.Older, stats.Never, stats.Stale, stats.NewestTime = row.Older, row.Never, row.Stale, row.NewestTime
+		if row.NewestTime > 0 {
+			stats.NewestAge = now - row.NewestTime
+		}
+		result = append(result, stats)
+	}
+	return result, utils.SUCCESS
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"uu/config"
+
+	"gorm.io/gorm/clause"
+)
+
+// BaseCurrency 系统内部统一使用的币种：各平台价格入库前都按汇率换算为人民币
+const BaseCurrency = "CNY"
+
+// FxRate 汇率表（管理员可修改，也可从文件导入）
+type FxRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Currency  string    `json:"currency" gorm:"type:varchar(3);uniqueIndex;not null"` // ISO 4217 币种代码，如 USD
+	Rate      float64   `json:"rate"`                                                 // 1 单位该币种折合人民币
+	Source    string    `json:"source" gorm:"type:varchar(20)"`                       // default / admin / file
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 默认汇率，首次启动时写入 fx_rate 表
+var defaultFxRates = map[string]float64{
+	"CNY": 1,
+	"USD": 7.2,
+	"EUR": 7.8,
+	"GBP": 9.1,
+	"HKD": 0.92,
+	"JPY": 0.048,
+	"KRW": 0.0053,
+	"RUB": 0.078,
+}
+
+// InitFxRates 初始化汇率（缺失的币种插入默认值）
+func InitFxRates() error {
+	for currency, rate := range defaultFxRates {
+		var count int64
+		config.DB.Model(&FxRate{}).Where("currency = ?", currency).Count(&count)
+		if count > 0 {
+			continue
+		}
+		if err := config.DB.Create(&FxRate{Currency: currency, Rate: rate, Source: "default"}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllFxRates 获取所有汇率
+func GetAllFxRates() ([]FxRate, error) {
+	var rates []FxRate
+	if err := config.DB.Order("currency ASC").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// fxRateCacheTTL 汇率缓存有效期，其他实例修改的汇率最迟在该时间后生效
+const fxRateCacheTTL = time.Minute
+
+// fxRateCache 汇率映射缓存，本实例修改或导入汇率时清除
+var fxRateCache struct {
+	sync.RWMutex
+	rates    map[string]float64
+	loadedAt time.Time
+}
+
+// GetFxRateMap 获取汇率映射，key: 币种，value: 折合人民币
+// 数据库读取失败或缺失的币种使用默认值，人民币固定为 1
+func GetFxRateMap() map[string]float64 {
+	fxRateCache.RLock()
+	if fxRateCache.rates != nil && time.Since(fxRateCache.loadedAt) < fxRateCacheTTL {
+		result := maps.Clone(fxRateCache.rates)
+		fxRateCache.RUnlock()
+		return result
+	}
+	fxRateCache.RUnlock()
+
+	result := maps.Clone(defaultFxRates)
+	rates, err := GetAllFxRates()
+	if err != nil {
+		config.Log.Errorf("Get fx rates error: %v", err)
+	}
+	for _, r := range rates {
+		if r.Rate > 0 {
+			result[r.Currency] = r.Rate
+		}
+	}
+	result[BaseCurrency] = 1
+	if err == nil {
+		fxRateCache.Lock()
+		fxRateCache.rates = maps.Clone(result)
+		fxRateCache.loadedAt = time.Now()
+		fxRateCache.Unlock()
+	}
+	return result
+}
+
+// clearFxRateCache 汇率变更后清除缓存
+func clearFxRateCache() {
+	fxRateCache.Lock()
+	fxRateCache.rates = nil
+	fxRateCache.Unlock()
+}
+
+// NormalizeCurrency 统一币种代码格式，为空时为人民币
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return BaseCurrency
+	}
+	return currency
+}
+
+// ToBaseCurrency 将某币种的价格换算为人民币，未知币种原样返回
+func ToBaseCurrency(price float64, currency string, rates map[string]float64) float64 {
+	rate, ok := rates[NormalizeCurrency(currency)]
+	if !ok || rate <= 0 || rate == 1 {
+		return price
+	}
+	return math.Round(price*rate*100) / 100
+}
+
+// FromBaseCurrency 将人民币价格换算为指定币种，未知币种原样返回
+func FromBaseCurrency(price float64, currency string, rates map[string]float64) float64 {
+	rate, ok := rates[NormalizeCurrency(currency)]
+	if !ok || rate <= 0 || rate == 1 {
+		return price
+	}
+	return math.Round(price/rate*100) / 100
+}
+
+// UpdateFxRate 更新汇率（不存在则创建）
+func UpdateFxRate(currency string, rate float64, source string) error {
+	defer clearFxRateCache()
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(&FxRate{Currency: NormalizeCurrency(currency), Rate: rate, Source: source}).Error
+}
+
+// LoadFxRatesFromFile 从 JSON 文件导入汇率，格式：{"USD": 7.2, "EUR": 7.8}
+func LoadFxRatesFromFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var rates map[string]float64
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return 0, err
+	}
+	var loaded int
+	for currency, rate := range rates {
+		if rate <= 0 || NormalizeCurrency(currency) == BaseCurrency {
+			continue
+		}
+		if err := UpdateFxRate(currency, rate, "file"); err != nil {
+			return loaded, err
+		}
+		loaded++
+	}
+	return loaded, nil
+}
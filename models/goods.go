@@ -1,13 +1,10 @@
 package models
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
 	"math"
-	"strconv"
-	"time"
 	"uu/config"
 	"uu/utils"
 
@@ -16,23 +13,33 @@ import (
 )
 
 type Goods struct {
-	Id               string      `json:"id"`
-	MarketHashName   string      `json:"market_hash_name"`
-	UserId           string      `json:"user_id"`
-	Name             string      `json:"name"`
-	SourcePrice      float64     `json:"source_price"`
-	TargetPrice      float64     `json:"target_price"`
-	SourceUpdateTime int64       `json:"source_update_time"`
-	TargetUpdateTime int64       `json:"target_update_time"`
-	BiddingPrice     float64     `json:"bidding_price"`
-	BiddingCount     int64       `json:"bidding_count"`
-	TypeName         string      `json:"type_name"`
-	ImageUrl         string      `json:"image_url"`
-	PriceDiff        float64     `json:"price_diff"`
-	ProfitRate       float64     `json:"profit_rate"`
-	SellCount        int64       `json:"sell_count"`
-	TurnOver         int64       `json:"turn_over"`
-	PlatformList     []*Platform `json:"platform_list" gorm:"-"`
+	Id                string      `json:"id"`
+	MarketHashName    string      `json:"market_hash_name"`
+	UserId            string      `json:"user_id"`
+	Name              string      `json:"name"`
+	SourcePrice       float64     `json:"source_price" currency:"amount"`
+	TargetPrice       float64     `json:"target_price" currency:"amount"`
+	SourceUpdateTime  int64       `json:"source_update_time"`
+	TargetUpdateTime  int64       `json:"target_update_time"`
+	BiddingPrice      float64     `json:"bidding_price" currency:"amount"`
+	BiddingCount      int64       `json:"bidding_count"`
+	TypeName          string      `json:"type_name"`
+	ImageUrl          string      `json:"image_url"`
+	PriceDiff         float64     `json:"price_diff" currency:"amount"`
+	ProfitRate        float64     `json:"profit_rate"`
+	NetProfit         float64     `json:"net_profit" currency:"amount"`          // 扣除手续费后的净利润
+	NetProfitRate     float64     `json:"net_profit_rate"`                       // 净利润率 = 净利润 / 买入价
+	Volatility        float64     `json:"volatility"`                            // 目标平台日收益率标准差
+	MaxDrawdown       float64     `json:"max_drawdown"`                          // 冷却期内目标平台历史最大回撤
+	ExpectedExitPrice float64     `json:"expected_exit_price" currency:"amount"` // 冷却期结束时的预期卖出价
+	DownsidePrice     float64     `json:"downside_price" currency:"amount"`      // 按历史最大回撤估算的最差卖出价
+	SellCount         int64       `json:"sell_count"`
+	TurnOver          int64       `json:"turn_over"`
+	Liquidity         float64     `json:"liquidity"`            // 目标平台估算日成交量
+	LiquidityConf     float64     `json:"liquidity_confidence"` // 流动性估算置信度 0~1
+	Anomaly           bool        `json:"anomaly"`              // 来源或目标平台报价被标记为异常
+	Stale             string      `json:"stale"`                // 行情过期原因，为空表示来源和目标平台行情都在有效期内
+	PlatformList      []*Platform `json:"platform_list" gorm:"-"`
 }
 
 type BaseGoods struct {
@@ -71,11 +78,11 @@ func SearchGoodsByKeyword(keyword string, limit int) ([]SearchResult, error) {
 type Platform struct {
 	Id           string  `json:"platformItemId"`
 	Name         string  `json:"platformName" gorm:"-"`
-	SellPrice    float64 `json:"sellPrice"`
+	SellPrice    float64 `json:"sellPrice" currency:"amount"`
 	SellCount    int64   `json:"sellCount"`
-	BiddingPrice float64 `json:"biddingPrice"`
+	BiddingPrice float64 `json:"biddingPrice" currency:"amount"`
 	BiddingCount int64   `json:"biddingCount"`
-	PriceDiff    float64 `json:"price_diff"`
+	PriceDiff    float64 `json:"price_diff" currency:"amount"`
 	UpdateTime   int64   `json:"updateTime"`
 	Link         string  `json:"link"`
 }
@@ -185,13 +192,14 @@ func UpdateBaseGoodsIcon() {
 	config.Log.Infof("Update BaseGoods Icon Success, updated %d records", len(toUpdate))
 }
 
+// GetHashNames 所有饰品名称，按 ID 排序，新饰品排在最后
 func GetHashNames() ([]string, error) {
 	var hashNames []string
-	err := config.DB.Model(&BaseGoods{}).Pluck("market_hash_name", &hashNames).Error
+	err := config.DB.Model(&BaseGoods{}).Order("id").Pluck("market_hash_name", &hashNames).Error
 	return hashNames, err
 }
 
-// GetPlatformListBatch 批量获取多个商品的平台列表
+// GetPlatformListBatch 批量获取多个商品的平台列表（按平台注册顺序）
 func GetPlatformListBatch(marketHashNames []string) map[string][]*Platform {
 	result := make(map[string][]*Platform)
 
@@ -199,154 +207,153 @@ func GetPlatformListBatch(marketHashNames []string) map[string][]*Platform {
 		return result
 	}
 
-	// 批量查询 UU 平台
-	var uList []U
-	config.DB.Where("market_hash_name IN ?", marketHashNames).Find(&uList)
-	for _, u := range uList {
-		result[u.MarketHashName] = append(result[u.MarketHashName], &Platform{
-			Id:           u.Id,
-			SellPrice:    u.SellPrice,
-			SellCount:    u.SellCount,
-			BiddingPrice: u.BiddingPrice,
-			BiddingCount: u.BiddingCount,
-			UpdateTime:   u.UpdateTime,
-			Link:         u.Link,
-			Name:         "悠悠",
-		})
+	for _, p := range Platforms() {
+		var rows []PlatformRow
+		config.DB.Table(p.Table).Where("market_hash_name IN ?", marketHashNames).Find(&rows)
+		for _, row := range rows {
+			result[row.MarketHashName] = append(result[row.MarketHashName], &Platform{
+				Id:           row.Id,
+				SellPrice:    row.SellPrice,
+				SellCount:    row.SellCount,
+				BiddingPrice: row.BiddingPrice,
+				BiddingCount: row.BiddingCount,
+				UpdateTime:   row.UpdateTime,
+				Link:         row.Link,
+				Name:         p.Name,
+			})
+		}
 	}
 
-	// 批量查询 Buff 平台
-	var buffList []Buff
-	config.DB.Where("market_hash_name IN ?", marketHashNames).Find(&buffList)
-	for _, buff := range buffList {
-		result[buff.MarketHashName] = append(result[buff.MarketHashName], &Platform{
-			Id:           buff.Id,
-			SellPrice:    buff.SellPrice,
-			SellCount:    buff.SellCount,
-			BiddingPrice: buff.BiddingPrice,
-			BiddingCount: buff.BiddingCount,
-			UpdateTime:   buff.UpdateTime,
-			Link:         buff.Link,
-			Name:         "BUFF",
-		})
-	}
+	return result
+}
 
-	// 批量查询 C5 平台
-	var c5List []C5
-	config.DB.Where("market_hash_name IN ?", marketHashNames).Find(&c5List)
-	for _, c5 := range c5List {
-		result[c5.MarketHashName] = append(result[c5.MarketHashName], &Platform{
-			Id:           c5.Id,
-			SellPrice:    c5.SellPrice,
-			SellCount:    c5.SellCount,
-			BiddingPrice: c5.BiddingPrice,
-			BiddingCount: c5.BiddingCount,
-			UpdateTime:   c5.UpdateTime,
-			Link:         c5.Link,
-			Name:         "C5GAME",
-		})
-	}
+// GoodsQuery 搬砖数据查询条件
+type GoodsQuery struct {
+	UserId    string
+	ProfileId uint // 设置方案ID，为 0 使用默认方案
+	PageSize  int
+	PageNum   int
+	IsDesc    bool
+	SortField string
+	Search    string
+	Source    string
+	Target    string
+	Category  string
+	BuyType   string // sell(在售价购买) / bidding(求购价购买)
+	SellType  string // sell(在售价出售) / bidding(求购价出售)
+
+	MinNetProfit     *float64 // 最小净利润（扣除目标平台手续费后），为空不限制
+	MinNetProfitRate *float64 // 最小净利润率，为空不限制
+	ExcludeDrawdown  bool     // 排除冷却期历史最大回撤会吃掉全部利润的饰品
+	Watchlist        bool     // 只显示自选饰品
+	MinLiquidity     *float64 // 目标平台最小估算日成交量，为空不限制
+	ExcludeAnomaly   bool     // 排除来源或目标平台报价异常的饰品
+	MaxAge           *int64   // 行情最大有效时长（秒），为空使用各平台配置
+	ExcludeStale     bool     // 排除来源或目标平台行情过期的饰品，否则只标记过期原因
+}
 
-	// 批量查询 Steam 平台
-	var steamList []Steam
-	config.DB.Where("market_hash_name IN ?", marketHashNames).Find(&steamList)
-	for _, steam := range steamList {
-		result[steam.MarketHashName] = append(result[steam.MarketHashName], &Platform{
-			Id:           steam.Id,
-			SellPrice:    steam.SellPrice,
-			SellCount:    steam.SellCount,
-			BiddingPrice: steam.BiddingPrice,
-			BiddingCount: steam.BiddingCount,
-			UpdateTime:   steam.UpdateTime,
-			Link:         steam.Link,
-			Name:         "Steam",
-		})
+// applyProfile 用设置方案补全请求中未指定的条件，请求参数优先
+func (q *GoodsQuery) applyProfile(s *SettingsResponse) {
+	if q.Source == "" {
+		q.Source = s.SourcePlatform
+	}
+	if q.Target == "" {
+		q.Target = s.TargetPlatform
+	}
+	if q.BuyType == "" {
+		q.BuyType = s.BuyType
+	}
+	if q.SellType == "" {
+		q.SellType = s.SellType
+	}
+	if q.Category == "" {
+		q.Category = s.Categories
+	}
+	if q.MinLiquidity == nil && s.MinLiquidity > 0 {
+		q.MinLiquidity = &s.MinLiquidity
 	}
-
-	return result
 }
 
 // GetGoods 获取搬砖数据
 // buyType: sell(在售价购买) / bidding(求购价购买)
 // sellType: sell(在售价出售) / bidding(求购价出售)
-func GetGoods(userId string, pageSize, pageNum int, isDesc bool, sortField, search, source, target, category, buyType, sellType string) (*[]Goods, int64, int) {
+func GetGoods(q *GoodsQuery) (*[]Goods, int64, int) {
 	var goods []Goods
 	var total int64
 	validFields := map[string]bool{
-		"price_diff":  true,
-		"profit_rate": true,
-	}
-	var targetMap map[string]interface{}
-	tableMap := map[string]string{
-		"uu":    "u",
-		"buff":  "buff",
-		"c5":    "c5",
-		"steam": "steam",
+		"price_diff":      true,
+		"profit_rate":     true,
+		"net_profit":      true,
+		"net_profit_rate": true,
+		"liquidity":       true,
+	}
+
+	settings, code := GetUserSetting(q.UserId)
+	if q.ProfileId > 0 {
+		settings, code = GetUserSettingProfile(q.UserId, q.ProfileId)
+		if code != utils.SUCCESS {
+			return &goods, 0, code
+		}
 	}
+	q.applyProfile(settings)
 
-	sourceTable := tableMap[source]
+	// 未知平台默认悠悠
+	sourceDef, ok := GetPlatformByKey(q.Source)
+	if !ok {
+		sourceDef = Platforms()[0]
+	}
+	targetDef, ok := GetPlatformByKey(q.Target)
+	if !ok {
+		targetDef = Platforms()[0]
+	}
+	sourceTable := sourceDef.Table
 
+	sortField := q.SortField
 	if !validFields[sortField] {
 		sortField = "profit_rate" // 默认按利润率排序
 	}
 
 	order := sortField
-	if isDesc {
+	if q.IsDesc {
 		order += " DESC"
 	}
 
-	settings, code := GetUserSetting(userId)
-
-	switch target {
-	case "uu":
-		targetMap = map[string]interface{}{
-			"model": &U{},
-			"table": tableMap[target],
-		}
-	case "buff":
-		targetMap = map[string]interface{}{
-			"model": &Buff{},
-			"table": tableMap[target],
-		}
-	case "steam":
-		targetMap = map[string]interface{}{
-			"model": &Steam{},
-			"table": tableMap[target],
-		}
-	case "c5":
-		targetMap = map[string]interface{}{
-			"model": &C5{},
-			"table": tableMap[target],
-		}
-	default:
-		targetMap = map[string]interface{}{
-			"model": &U{},
-			"table": tableMap[target],
-		}
-	}
-
-	targetTable := targetMap["table"].(string)
+	targetTable := targetDef.Table
+	targetFee := targetDef.Fee()
+	sourceCutoff := staleCutoff(sourceDef, q.MaxAge)
+	targetCutoff := staleCutoff(targetDef, q.MaxAge)
 
 	// 根据 buyType 和 sellType 确定使用的价格字段
 	// buyType: 购买方案 - sell(在售价购买) / bidding(求购价购买)
 	// sellType: 出售方案 - sell(在售价出售) / bidding(求购价出售)
 	var sourcePriceField, targetPriceField string
-	if buyType == "bidding" {
+	if q.BuyType == "bidding" {
 		sourcePriceField = "bidding_price" // 求购价购买：用来源平台的求购价作为买入价
 	} else {
 		sourcePriceField = "sell_price" // 在售价购买：用来源平台的在售价作为买入价
 	}
-	if sellType == "bidding" {
+	if q.SellType == "bidding" {
 		targetPriceField = "bidding_price" // 求购价出售：用目标平台的求购价作为卖出价
 	} else {
 		targetPriceField = "sell_price" // 在售价出售：用目标平台的在售价作为卖出价
 	}
 
+	sourcePrice := fmt.Sprintf("%s.%s", sourceTable, sourcePriceField)
+	targetPrice := fmt.Sprintf("%s.%s", targetTable, targetPriceField)
+	// 净利润 = 目标平台扣除卖家手续费和提现手续费后的到手金额 - 买入价
+	netProfit := fmt.Sprintf("(%s - %s)", targetFee.NetProceedsSQL(targetPrice), sourcePrice)
+	// 冷却期后的最差卖出价 = 卖出价 * (1 - 目标平台历史最大回撤)
+	downsidePrice := fmt.Sprintf("(%s * (1 - COALESCE(price_risk.max_drawdown, 0)))", targetPrice)
+	riskJoin := fmt.Sprintf("left join price_risk ON %s.market_hash_name = price_risk.market_hash_name AND price_risk.platform = '%s'",
+		targetTable, targetDef.Code)
+
 	// 构建 SELECT 语句
 	// source_price: 买入价（来源平台）
 	// target_price: 卖出价（目标平台）
 	// price_diff: 差价 = 卖出价 - 买入价
 	// profit_rate: 利润率 = 差价 / 买入价
+	// net_profit: 净利润 = 卖出到手金额 - 买入价
+	// net_profit_rate: 净利润率 = 净利润 / 买入价
 	selectSQL := fmt.Sprintf(`
 		%s.id as id,
 		%s.sell_count as sell_count,
@@ -357,10 +364,20 @@ func GetGoods(userId string, pageSize, pageNum int, isDesc bool, sortField, sear
 		base_goods.name as name,
 		base_goods.icon_url as image_url,
 		u_base_info.type_name as type_name,
-		%s.%s as target_price,
-		%s.%s as source_price,
-		(%s.%s - %s.%s) as price_diff,
-		ROUND((%s.%s - %s.%s) / %s.%s, 4) as profit_rate,
+		%s as target_price,
+		%s as source_price,
+		(%s - %s) as price_diff,
+		ROUND((%s - %s) / %s, 4) as profit_rate,
+		ROUND(%s, 2) as net_profit,
+		ROUND(%s / %s, 4) as net_profit_rate,
+		COALESCE(price_risk.volatility, 0) as volatility,
+		COALESCE(price_risk.max_drawdown, 0) as max_drawdown,
+		COALESCE(liquidity.sales_per_day, 0) as liquidity,
+		COALESCE(liquidity.confidence, 0) as liquidity_conf,
+		(COALESCE(%s.anomaly, '') <> '' OR COALESCE(%s.anomaly, '') <> '') as anomaly,
+		%s as stale,
+		ROUND(%s * (1 + COALESCE(price_risk.expected_return, 0)), 2) as expected_exit_price,
+		ROUND(%s, 2) as downside_price,
 		%s.update_time as target_update_time,
 		%s.update_time as source_update_time`,
 		targetTable,
@@ -368,10 +385,16 @@ func GetGoods(userId string, pageSize, pageNum int, isDesc bool, sortField, sear
 		targetTable,
 		targetTable,
 		targetTable,
-		targetTable, targetPriceField,
-		sourceTable, sourcePriceField,
-		targetTable, targetPriceField, sourceTable, sourcePriceField,
-		targetTable, targetPriceField, sourceTable, sourcePriceField, sourceTable, sourcePriceField,
+		targetPrice,
+		sourcePrice,
+		targetPrice, sourcePrice,
+		targetPrice, sourcePrice, sourcePrice,
+		netProfit,
+		netProfit, sourcePrice,
+		sourceTable, targetTable,
+		staleSQL(sourceTable, sourceCutoff, targetTable, targetCutoff),
+		targetPrice,
+		downsidePrice,
 		targetTable,
 		sourceTable)
 
@@ -381,42 +404,91 @@ func GetGoods(userId string, pageSize, pageNum int, isDesc bool, sortField, sear
 	// 来源平台买入价 < 最大价格 且 > 最小价格
 	// 同时要求使用的价格字段 > 0（避免除零错误和无效数据）
 	whereSQL := fmt.Sprintf(`
-		(%s.%s - %s.%s) > ? AND
+		(%s - %s) > ? AND
 		%s.sell_count > ? AND
-		%s.%s < ? AND
-		%s.%s > ? AND
-		%s.%s > 0 AND
-		%s.%s > 0`,
-		targetTable, targetPriceField, sourceTable, sourcePriceField,
+		%s < ? AND
+		%s > ? AND
+		%s > 0 AND
+		%s > 0`,
+		targetPrice, sourcePrice,
 		targetTable,
-		sourceTable, sourcePriceField,
-		sourceTable, sourcePriceField,
-		sourceTable, sourcePriceField,
-		targetTable, targetPriceField)
+		sourcePrice,
+		sourcePrice,
+		sourcePrice,
+		targetPrice)
 
-	query1 := config.DB.Model(targetMap["model"]).
+	query1 := config.DB.Table(targetTable).
 		Select(selectSQL).
 		Joins(fmt.Sprintf("join %s ON %s.market_hash_name = %s.market_hash_name", sourceTable, targetTable, sourceTable)).
 		Joins(fmt.Sprintf("join base_goods ON %s.market_hash_name = base_goods.market_hash_name", targetTable)).
 		Joins(fmt.Sprintf("left join u_base_info ON %s.market_hash_name = u_base_info.hash_name", targetTable)).
+		Joins(riskJoin).
+		Joins(liquidityJoin(targetTable, targetDef.Code)).
 		Where(whereSQL, settings.MinDiff, settings.MinSellNum, settings.MaxSellPrice, settings.MinSellPrice)
 
-	query2 := config.DB.Model(targetMap["model"]).
+	query2 := config.DB.Table(targetTable).
 		Joins(fmt.Sprintf("join %s ON %s.market_hash_name = %s.market_hash_name", sourceTable, targetTable, sourceTable)).
 		Joins(fmt.Sprintf("join base_goods ON %s.market_hash_name = base_goods.market_hash_name", targetTable)).
 		Joins(fmt.Sprintf("left join u_base_info ON %s.market_hash_name = u_base_info.hash_name", targetTable)).
+		Joins(riskJoin).
+		Joins(liquidityJoin(targetTable, targetDef.Code)).
 		Where(whereSQL, settings.MinDiff, settings.MinSellNum, settings.MaxSellPrice, settings.MinSellPrice)
 
-	if category != "" {
+	if q.Category != "" {
 		// 支持多个类别，逗号分隔
-		categories := strings.Split(category, ",")
+		categories := strings.Split(q.Category, ",")
 		query1 = query1.Where("u_base_info.type_name IN ?", categories)
 		query2 = query2.Where("u_base_info.type_name IN ?", categories)
 	}
 
-	if search != "" {
-		query1 = query1.Where("base_goods.name LIKE ?", "%"+search+"%")
-		query2 = query2.Where("base_goods.name LIKE ?", "%"+search+"%")
+	if q.Search != "" {
+		query1 = query1.Where("base_goods.name LIKE ?", "%"+q.Search+"%")
+		query2 = query2.Where("base_goods.name LIKE ?", "%"+q.Search+"%")
+	}
+
+	if q.Watchlist {
+		query1 = query1.Where(watchlistFilter(targetTable), q.UserId)
+		query2 = query2.Where(watchlistFilter(targetTable), q.UserId)
+	}
+
+	if q.MinNetProfit != nil {
+		query1 = query1.Where(netProfit+" >= ?", *q.MinNetProfit)
+		query2 = query2.Where(netProfit+" >= ?", *q.MinNetProfit)
+	}
+
+	if q.MinNetProfitRate != nil {
+		query1 = query1.Where(fmt.Sprintf("%s / %s >= ?", netProfit, sourcePrice), *q.MinNetProfitRate)
+		query2 = query2.Where(fmt.Sprintf("%s / %s >= ?", netProfit, sourcePrice), *q.MinNetProfitRate)
+	}
+
+	if q.MinLiquidity != nil {
+		query1 = query1.Where("COALESCE(liquidity.sales_per_day, 0) >= ?", *q.MinLiquidity)
+		query2 = query2.Where("COALESCE(liquidity.sales_per_day, 0) >= ?", *q.MinLiquidity)
+	}
+
+	if q.ExcludeAnomaly {
+		anomalyWhere := anomalyFilter(sourceTable) + " AND " + anomalyFilter(targetTable)
+		query1 = query1.Where(anomalyWhere)
+		query2 = query2.Where(anomalyWhere)
+	}
+
+	if q.ExcludeStale {
+		staleWhere := fmt.Sprintf("%s.update_time >= ? AND %s.update_time >= ?", sourceTable, targetTable)
+		query1 = query1.Where(staleWhere, sourceCutoff, targetCutoff)
+		query2 = query2.Where(staleWhere, sourceCutoff, targetCutoff)
+	}
+
+	if settings.MinProfitRate > 0 {
+		profitRateWhere := fmt.Sprintf("(%s - %s) / %s >= ?", targetPrice, sourcePrice, sourcePrice)
+		query1 = query1.Where(profitRateWhere, settings.MinProfitRate)
+		query2 = query2.Where(profitRateWhere, settings.MinProfitRate)
+	}
+
+	if q.ExcludeDrawdown {
+		// 按最差卖出价扣除手续费后仍需高于买入价
+		downsideWhere := fmt.Sprintf("%s > %s", targetFee.NetProceedsSQL(downsidePrice), sourcePrice)
+		query1 = query1.Where(downsideWhere)
+		query2 = query2.Where(downsideWhere)
 	}
 
 	err := query2.Count(&total).Error
@@ -426,8 +498,8 @@ func GetGoods(userId string, pageSize, pageNum int, isDesc bool, sortField, sear
 	}
 	err = query1.
 		Order(order).
-		Limit(pageSize).
-		Offset((pageNum - 1) * pageSize).
+		Limit(q.PageSize).
+		Offset((q.PageNum - 1) * q.PageSize).
 		Scan(&goods).
 		Error
 	if err != nil {
@@ -466,47 +538,74 @@ type BigItemBidding struct {
 	Name           string      `json:"name"`
 	ImageUrl       string      `json:"image_url"`
 	TypeName       string      `json:"type_name"`
-	SellPrice      float64     `json:"sell_price"`
+	SellPrice      float64     `json:"sell_price" currency:"amount"`
 	SellCount      int64       `json:"sell_count"`
-	BiddingPrice   float64     `json:"bidding_price"`
+	BiddingPrice   float64     `json:"bidding_price" currency:"amount"`
 	BiddingCount   int64       `json:"bidding_count"`
-	PriceDiff      float64     `json:"price_diff"`  // 价差 = sell_price - bidding_price
-	ProfitRate     float64     `json:"profit_rate"` // 利润率 = (sell_price - bidding_price) / bidding_price
+	PriceDiff      float64     `json:"price_diff" currency:"amount"` // 价差 = sell_price - bidding_price
+	ProfitRate     float64     `json:"profit_rate"`                  // 利润率 = (sell_price - bidding_price) / bidding_price
+	NetProfit      float64     `json:"net_profit" currency:"amount"` // 净利润 = 在售价扣除手续费后到手金额 - bidding_price
+	NetProfitRate  float64     `json:"net_profit_rate"`              // 净利润率 = net_profit / bidding_price
+	Liquidity      float64     `json:"liquidity"`                    // 估算日成交量
+	LiquidityConf  float64     `json:"liquidity_confidence"`         // 流动性估算置信度 0~1
+	Anomaly        bool        `json:"anomaly"`                      // 报价被标记为异常
 	UpdateTime     int64       `json:"update_time"`
 	PlatformList   []*Platform `json:"platform_list" gorm:"-"`
 }
 
-func GetBigItemBidding(pageSize, pageNum int, isDesc bool, sortField, search, platform, category string) (*[]BigItemBidding, int64, int) {
+// BigItemBiddingQuery 大件求购查询条件
+type BigItemBiddingQuery struct {
+	UserId    string
+	PageSize  int
+	PageNum   int
+	IsDesc    bool
+	SortField string
+	Search    string
+	Platform  string
+	Category  string
+
+	MinNetProfit     *float64 // 最小净利润，为空不限制
+	MinNetProfitRate *float64 // 最小净利润率，为空不限制
+	Watchlist        bool     // 只显示自选饰品
+	MinLiquidity     *float64 // 最小估算日成交量，为空不限制
+	ExcludeAnomaly   bool     // 排除报价异常的饰品
+}
+
+func GetBigItemBidding(q *BigItemBiddingQuery) (*[]BigItemBidding, int64, int) {
 	var items []BigItemBidding
 	var total int64
 
 	validFields := map[string]bool{
-		"price_diff":    true,
-		"profit_rate":   true,
-		"sell_price":    true,
-		"bidding_price": true,
+		"price_diff":      true,
+		"profit_rate":     true,
+		"net_profit":      true,
+		"net_profit_rate": true,
+		"sell_price":      true,
+		"bidding_price":   true,
+		"liquidity":       true,
 	}
 
-	tableMap := map[string]string{
-		"uu":   "u",
-		"buff": "buff",
-		"c5":   "c5",
-	}
-
-	platformTable, ok := tableMap[platform]
-	if !ok {
-		platformTable = "u" // 默认悠悠
+	// 只有支持求购、且价格随数据源更新的平台参与大件求购（Steam 余额无法提现，不参与）
+	platformDef, ok := GetPlatformByKey(q.Platform)
+	if !ok || !platformDef.SupportsBidding || platformDef.ExternalPrice {
+		platformDef = Platforms()[0] // 默认悠悠
 	}
+	platformTable := platformDef.Table
 
+	sortField := q.SortField
 	if !validFields[sortField] {
 		sortField = "profit_rate" // 默认按利润率排序
 	}
 
 	order := sortField
-	if isDesc {
+	if q.IsDesc {
 		order += " DESC"
 	}
 
+	// 求购买入、在售价卖出，都在同一平台，手续费按该平台计算
+	fee := platformDef.Fee()
+	netProfit := fmt.Sprintf("(%s - %s.bidding_price)", fee.NetProceedsSQL(platformTable+".sell_price"), platformTable)
+
 	// 构建查询
 	selectFields := fmt.Sprintf(`
 		%s.id as id,
@@ -520,36 +619,72 @@ func GetBigItemBidding(pageSize, pageNum int, isDesc bool, sortField, search, pl
 		%s.bidding_count as bidding_count,
 		(%s.sell_price - %s.bidding_price) as price_diff,
 		ROUND((%s.sell_price - %s.bidding_price) / %s.bidding_price, 4) as profit_rate,
+		ROUND(%s, 2) as net_profit,
+		ROUND(%s / %s.bidding_price, 4) as net_profit_rate,
+		COALESCE(liquidity.sales_per_day, 0) as liquidity,
+		COALESCE(liquidity.confidence, 0) as liquidity_conf,
+		COALESCE(%s.anomaly, '') <> '' as anomaly,
 		%s.update_time as update_time
 	`, platformTable, platformTable, platformTable, platformTable, platformTable, platformTable,
-		platformTable, platformTable, platformTable, platformTable, platformTable, platformTable)
+		platformTable, platformTable, platformTable, platformTable, platformTable,
+		netProfit, netProfit, platformTable, platformTable, platformTable)
 
 	query1 := config.DB.Table(platformTable).
 		Select(selectFields).
 		Joins(fmt.Sprintf("JOIN base_goods ON %s.market_hash_name = base_goods.market_hash_name", platformTable)).
 		Joins(fmt.Sprintf("LEFT JOIN u_base_info ON %s.market_hash_name = u_base_info.hash_name", platformTable)).
+		Joins(liquidityJoin(platformTable, platformDef.Code)).
 		Where(fmt.Sprintf("%s.bidding_price > 0 AND %s.sell_price > 0 AND %s.sell_count > 10", platformTable, platformTable, platformTable)).
 		Where(fmt.Sprintf("%s.sell_price > %s.bidding_price", platformTable, platformTable))
 
 	query2 := config.DB.Table(platformTable).
 		Joins(fmt.Sprintf("JOIN base_goods ON %s.market_hash_name = base_goods.market_hash_name", platformTable)).
 		Joins(fmt.Sprintf("LEFT JOIN u_base_info ON %s.market_hash_name = u_base_info.hash_name", platformTable)).
+		Joins(liquidityJoin(platformTable, platformDef.Code)).
 		Where(fmt.Sprintf("%s.bidding_price > 0 AND %s.sell_price > 0 AND %s.sell_count > 10", platformTable, platformTable, platformTable)).
 		Where(fmt.Sprintf("%s.sell_price > %s.bidding_price", platformTable, platformTable))
 
 	// 类别筛选（默认手套和刀具）
-	if category == "" || category == "all" {
+	if q.Category == "" || q.Category == "all" {
 		query1 = query1.Where("u_base_info.type_name IN ?", []string{"手套", "匕首"})
 		query2 = query2.Where("u_base_info.type_name IN ?", []string{"手套", "匕首"})
 	} else {
-		query1 = query1.Where("u_base_info.type_name = ?", category)
-		query2 = query2.Where("u_base_info.type_name = ?", category)
+		query1 = query1.Where("u_base_info.type_name = ?", q.Category)
+		query2 = query2.Where("u_base_info.type_name = ?", q.Category)
 	}
 
 	// 搜索
-	if search != "" {
-		query1 = query1.Where("base_goods.name LIKE ?", "%"+search+"%")
-		query2 = query2.Where("base_goods.name LIKE ?", "%"+search+"%")
+	if q.Search != "" {
+		query1 = query1.Where("base_goods.name LIKE ?", "%"+q.Search+"%")
+		query2 = query2.Where("base_goods.name LIKE ?", "%"+q.Search+"%")
+	}
+
+	// 自选筛选
+	if q.Watchlist {
+		query1 = query1.Where(watchlistFilter(platformTable), q.UserId)
+		query2 = query2.Where(watchlistFilter(platformTable), q.UserId)
+	}
+
+	// 净利润筛选
+	if q.MinNetProfit != nil {
+		query1 = query1.Where(netProfit+" >= ?", *q.MinNetProfit)
+		query2 = query2.Where(netProfit+" >= ?", *q.MinNetProfit)
+	}
+	if q.MinNetProfitRate != nil {
+		query1 = query1.Where(fmt.Sprintf("%s / %s.bidding_price >= ?", netProfit, platformTable), *q.MinNetProfitRate)
+		query2 = query2.Where(fmt.Sprintf("%s / %s.bidding_price >= ?", netProfit, platformTable), *q.MinNetProfitRate)
+	}
+
+	// 流动性筛选
+	if q.MinLiquidity != nil {
+		query1 = query1.Where("COALESCE(liquidity.sales_per_day, 0) >= ?", *q.MinLiquidity)
+		query2 = query2.Where("COALESCE(liquidity.sales_per_day, 0) >= ?", *q.MinLiquidity)
+	}
+
+	// 异常报价筛选
+	if q.ExcludeAnomaly {
+		query1 = query1.Where(anomalyFilter(platformTable))
+		query2 = query2.Where(anomalyFilter(platformTable))
 	}
 
 	// 计算总数
@@ -562,8 +697,8 @@ func GetBigItemBidding(pageSize, pageNum int, isDesc bool, sortField, search, pl
 	// 查询数据
 	err = query1.
 		Order(order).
-		Limit(pageSize).
-		Offset((pageNum - 1) * pageSize).
+		Limit(q.PageSize).
+		Offset((q.PageNum - 1) * q.PageSize).
 		Scan(&items).
 		Error
 	if err != nil {
@@ -584,26 +719,6 @@ func GetBigItemBidding(pageSize, pageNum int, isDesc bool, sortField, search, pl
 	return &items, total, utils.SUCCESS
 }
 
-func SetLastIndex(index int) {
-	ctx := context.Background()
-	err := config.RDB.Set(ctx, "hash_name_index", index, time.Minute*5).Err()
-	if err != nil {
-		config.Log.Errorf("Set hash name index error: %v", err)
-	}
-}
-
-func GetLastIndex() int {
-	ctx := context.Background()
-	index, err := config.RDB.Get(ctx, "hash_name_index").Result()
-	if err != nil {
-		config.Log.Errorf("Get hash name index error: %v", err)
-		return 0
-	}
-	newIndex, _ := strconv.Atoi(index)
-
-	return newIndex
-}
-
 // PublicHomeData GetPublicHomeData 获取公开首页数据（不需要登录）
 // 包含：饰品榜单前10，挂刀搬砖利润率前10（使用管理员settings）
 type PublicHomeData struct {
@@ -617,7 +732,7 @@ type RelatedWearItem struct {
 	Wear        string  `json:"wear"`
 	WearShort   string  `json:"wear_short"`
 	IconUrl     string  `json:"icon_url"`
-	Price       float64 `json:"price"`
+	Price       float64 `json:"price" currency:"amount"`
 	QualityName string  `json:"quality_name"`
 }
 
@@ -1010,23 +1125,26 @@ func GetPublicHomeData() (*PublicHomeData, error) {
 	}
 	result.RankingList = rankingList
 
-	// 2. 获取搬砖数据前10（使用管理员settings）
+	// 2. 获取搬砖数据前10（使用管理员settings，按扣除手续费后的净利润率排序）
 	adminSettings, _ := GetAdminSetting()
 
 	var goods []Goods
 	// 默认 uu -> buff 的搬砖数据
 	sourceTable := "u"
 	targetTable := "buff"
+	targetFee := GetPlatformFee("BUFF")
+	netProfit := fmt.Sprintf("(%s - %s.sell_price)", targetFee.NetProceedsSQL(targetTable+".sell_price"), sourceTable)
 
-	query := config.DB.Model(&Buff{}).
-		Select(fmt.Sprintf("%s.id as id, %s.sell_count as sell_count, %s.turn_over as turn_over, %s.bidding_count as bidding_count, %s.bidding_price as bidding_price, base_goods.market_hash_name as market_hash_name, base_goods.name as name, base_goods.icon_url as image_url, %s.sell_price as target_price, %s.sell_price as source_price, (%s.sell_price - %s.sell_price) as price_diff, ROUND((%s.sell_price - %s.sell_price)/%s.sell_price,4) as profit_rate, %s.update_time as target_update_time, %s.update_time as source_update_time",
-			targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, sourceTable, targetTable, sourceTable, targetTable, sourceTable, sourceTable, targetTable, sourceTable)).
+	query := config.DB.Table(targetTable).
+		Select(fmt.Sprintf("%s.id as id, %s.sell_count as sell_count, %s.turn_over as turn_over, %s.bidding_count as bidding_count, %s.bidding_price as bidding_price, base_goods.market_hash_name as market_hash_name, base_goods.name as name, base_goods.icon_url as image_url, %s.sell_price as target_price, %s.sell_price as source_price, (%s.sell_price - %s.sell_price) as price_diff, ROUND((%s.sell_price - %s.sell_price)/%s.sell_price,4) as profit_rate, ROUND(%s, 2) as net_profit, ROUND(%s/%s.sell_price,4) as net_profit_rate, %s.update_time as target_update_time, %s.update_time as source_update_time",
+			targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, sourceTable, targetTable, sourceTable, targetTable, sourceTable, sourceTable, netProfit, netProfit, sourceTable, targetTable, sourceTable)).
 		Joins(fmt.Sprintf("join %s ON %s.market_hash_name = %s.market_hash_name", sourceTable, targetTable, sourceTable)).
 		Joins(fmt.Sprintf("join base_goods ON %s.market_hash_name = base_goods.market_hash_name", targetTable)).
 		Where(fmt.Sprintf("(%s.sell_price - %s.sell_price) > ? and %s.sell_count > ? and %s.sell_price < ? and %s.sell_price > ?",
 			targetTable, sourceTable, targetTable, sourceTable, sourceTable),
 			adminSettings.MinDiff, adminSettings.MinSellNum, adminSettings.MaxSellPrice, adminSettings.MinSellPrice).
-		Order("profit_rate DESC").
+		Where(netProfit + " > 0").
+		Order("net_profit_rate DESC").
 		Limit(10)
 
 	err = query.Scan(&goods).Error
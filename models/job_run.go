@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+	"uu/config"
+	"uu/utils"
+)
+
+// 定时任务运行状态
+const (
+	JobRunRunning  = "running"
+	JobRunSuccess  = "success"
+	JobRunFailed   = "failed"
+	JobRunCanceled = "canceled" // 服务退出时中断
+)
+
+// 触发方式
+const (
+	JobTriggerSchedule = "schedule" // 按计划执行
+	JobTriggerManual   = "manual"   // 管理员手动触发
+)
+
+// JobRunRetainDays 运行记录保留天数
+const JobRunRetainDays = 30
+
+// JobRun 定时任务运行记录
+type JobRun struct {
+	ID        uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Job       string     `json:"job" gorm:"type:varchar(50);index"`
+	Trigger   string     `json:"trigger" gorm:"type:varchar(10)"` // schedule / manual
+	Status    string     `json:"status" gorm:"type:varchar(10)"`  // running / success / failed / canceled
+	Error     string     `json:"error,omitempty" gorm:"type:varchar(1000)"`
+	Items     int        `json:"items"`    // 处理的条目数
+	Duration  int64      `json:"duration"` // 耗时（毫秒）
+	StartTime time.Time  `json:"start_time" gorm:"index"`
+	EndTime   *time.Time `json:"end_time"`
+}
+
+// StartJobRun 记录一次运行开始
+func StartJobRun(job, trigger string) *JobRun {
+	run := &JobRun{Job: job, Trigger: trigger, Status: JobRunRunning, StartTime: time.Now()}
+	if err := config.DB.Create(run).Error; err != nil {
+		config.Log.Errorf("Create job run %s error: %v", job, err)
+	}
+	return run
+}
+
+// FinishJobRun 记录运行结果，err 为空表示成功
+func FinishJobRun(run *JobRun, items int, err error) {
+	end := time.Now()
+	run.EndTime = &end
+	run.Duration = end.Sub(run.StartTime).Milliseconds()
+	run.Items = items
+	run.Status = JobRunSuccess
+	if err != nil {
+		run.Status = JobRunFailed
+		if errors.Is(err, context.Canceled) {
+			run.Status = JobRunCanceled
+		}
+		run.Error = err.Error()
+		if len(run.Error) > 1000 {
+			run.Error = run.Error[:1000]
+		}
+	}
+	if run.ID == 0 {
+		return
+	}
+	if e := config.DB.Save(run).Error; e != nil {
+		config.Log.Errorf("Save job run %s error: %v", run.Job, e)
+	}
+}
+
+// GetLastJobRuns 每个任务最近一次运行记录，key: 任务名
+func GetLastJobRuns() map[string]*JobRun {
+	var runs []*JobRun
+	err := config.DB.Where("id IN (?)", config.DB.Model(&JobRun{}).Select("MAX(id)").Group("job")).Find(&runs).Error
+	if err != nil {
+		config.Log.Errorf("Get last job runs error: %v", err)
+	}
+	result := make(map[string]*JobRun, len(runs))
+	for _, r := range runs {
+		result[r.Job] = r
+	}
+	return result
+}
+
+// GetLastSuccessJobRun 任务最近一次成功的运行记录，没有时返回 nil
+func GetLastSuccessJobRun(job string) (*JobRun, error) {
+	var runs []JobRun
+	err := config.DB.Where("job = ? AND status = ?", job, JobRunSuccess).Order("id DESC").Limit(1).Find(&runs).Error
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// GetJobRuns 分页获取运行记录，job 为空不限制
+func GetJobRuns(job, status string, pageSize, pageNum int) ([]JobRun, int64, int) {
+	var runs []JobRun
+	var total int64
+	db := config.DB.Model(&JobRun{})
+	if job != "" {
+		db = db.Where("job = ?", job)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		config.Log.Errorf("Get job runs total error: %v", err)
+		return nil, 0, utils.ErrCodeGetJobRuns
+	}
+	err := db.Order("id DESC").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&runs).Error
+	if err != nil {
+		config.Log.Errorf("Get job runs error: %v", err)
+		return nil, 0, utils.ErrCodeGetJobRuns
+	}
+	return runs, total, utils.SUCCESS
+}
+
+// FailInterruptedJobRuns 启动时将上次运行中断的记录标记为失败
+func FailInterruptedJobRuns() {
+	config.DB.Model(&JobRun{}).Where("status = ?", JobRunRunning).
+		Updates(map[string]interface{}{"status": JobRunFailed, "error": "interrupted by restart"})
+}
+
+// CleanOldJobRuns 清理超过保留天数的运行记录
+func CleanOldJobRuns(days int) (int, error) {
+	result := config.DB.Where("start_time < ?", time.Now().AddDate(0, 0, -days)).Delete(&JobRun{})
+	return int(result.RowsAffected), result.Error
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+	"uu/config"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// LiquidityWindowDays 估算流动性使用的快照天数
+	LiquidityWindowDays = 3
+	// liquiditySampleHours 平均每隔多少小时有一次变化即认为样本充足
+	liquiditySampleHours = 4
+)
+
+// Liquidity 流动性估算（根据日内快照定时重新计算）
+type Liquidity struct {
+	MarketHashName string  `json:"market_hash_name" gorm:"type:varchar(255);primaryKey"`
+	Platform       string  `json:"platform" gorm:"type:varchar(20);primaryKey"`
+	SalesPerDay    float64 `json:"sales_per_day" gorm:"index"` // 估算的日成交量
+	Confidence     float64 `json:"confidence"`                 // 置信度 0~1，快照越多、覆盖时间越长越高
+	Samples        int     `json:"samples"`                    // 参与计算的快照条数
+	UpdateTime     int64   `json:"update_time"`
+}
+
+// TableName 自定义表名
+func (Liquidity) TableName() string {
+	return "liquidity"
+}
+
+// estimateLiquidity 根据按时间升序的快照估算日成交量和置信度
+// 在售数减少且最低价未下降，视为最低价的挂单被买走；最低价下降时的减少更可能是下架改价，按一半计
+// 求购数减少且最高求购价未上升，视为求购被卖家成交，因可能是撤单同样按一半计
+// 在售数增加（新上架）不计入成交
+func estimateLiquidity(snapshots []PriceSnapshot, window time.Duration) (float64, float64) {
+	if len(snapshots) < 2 {
+		return 0, 0
+	}
+	var sold float64
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := &snapshots[i-1], &snapshots[i]
+		if d := prev.SellCount - cur.SellCount; d > 0 {
+			if cur.SellPrice >= prev.SellPrice {
+				sold += float64(d)
+			} else {
+				sold += float64(d) / 2
+			}
+		}
+		if d := prev.BiddingCount - cur.BiddingCount; d > 0 && cur.BiddingPrice <= prev.BiddingPrice {
+			sold += float64(d) / 2
+		}
+	}
+
+	span := snapshots[len(snapshots)-1].SnapshotTime.Sub(snapshots[0].SnapshotTime)
+	if span < time.Hour {
+		return 0, 0
+	}
+	salesPerDay := sold / span.Hours() * 24
+
+	coverage := math.Min(1, float64(span)/float64(window))
+	density := math.Min(1, float64(len(snapshots))/(window.Hours()/liquiditySampleHours))
+	return math.Round(salesPerDay*100) / 100, math.Round(coverage*density*100) / 100
+}
+
+// RebuildLiquidity 根据最近 LiquidityWindowDays 天的日内快照重新计算所有饰品的流动性，返回估算的条数
+func RebuildLiquidity() (int, error) {
+	window := LiquidityWindowDays * 24 * time.Hour
+	rows, err := config.DB.Model(&PriceSnapshot{}).
+		Select("market_hash_name, platform, sell_price, sell_count, bidding_price, bidding_count, snapshot_time").
+		Where("snapshot_time >= ?", time.Now().Add(-window)).
+		Order("platform ASC, market_hash_name ASC, snapshot_time ASC").
+		Rows()
+	if err != nil {
+		return 0, fmt.Errorf("query price snapshot for liquidity: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now().Unix()
+	var result []*Liquidity
+	var curName, curPlatform string
+	var series []PriceSnapshot
+
+	flush := func() {
+		if curName == "" {
+			return
+		}
+		salesPerDay, confidence := estimateLiquidity(series, window)
+		result = append(result, &Liquidity{
+			MarketHashName: curName,
+			Platform:       curPlatform,
+			SalesPerDay:    salesPerDay,
+			Confidence:     confidence,
+			Samples:        len(series),
+			UpdateTime:     now,
+		})
+	}
+
+	for rows.Next() {
+		var s PriceSnapshot
+		if err := config.DB.ScanRows(rows, &s); err != nil {
+			return 0, fmt.Errorf("scan price snapshot for liquidity: %w", err)
+		}
+		if s.MarketHashName != curName || s.Platform != curPlatform {
+			flush()
+			curName, curPlatform = s.MarketHashName, s.Platform
+			series = series[:0]
+		}
+		series = append(series, s)
+	}
+	flush()
+
+	if len(result) == 0 {
+		return 0, nil
+	}
+	err = config.DB.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(result, 500).Error
+	if err != nil {
+		return 0, fmt.Errorf("save liquidity: %w", err)
+	}
+	// 窗口内没有快照的饰品不再有成交依据，删除旧估算
+	config.DB.Where("update_time < ?", now).Delete(&Liquidity{})
+	config.Log.Infof("Rebuilt liquidity for %d items", len(result))
+	return len(result), nil
+}
+
+// GetLiquidityByHashName 获取饰品在各平台的流动性，key: 平台代码
+func GetLiquidityByHashName(marketHashName string) map[string]*Liquidity {
+	var list []Liquidity
+	config.DB.Where("market_hash_name = ?", marketHashName).Find(&list)
+	result := make(map[string]*Liquidity, len(list))
+	for i := range list {
+		result[list[i].Platform] = &list[i]
+	}
+	return result
+}
+
+// liquidityJoin 关联指定平台的流动性
+func liquidityJoin(table, platformCode string) string {
+	return "left join liquidity ON " + table + ".market_hash_name = liquidity.market_hash_name AND liquidity.platform = '" + platformCode + "'"
+}
@@ -9,9 +9,10 @@ import (
 	"gorm.io/gorm"
 )
 
-// Notification 通知表（管理员发布的全局通知）
+// Notification 通知表（管理员发布的全局通知，UserID 不为空时为仅该用户可见的个人通知）
 type Notification struct {
 	ID        uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    *uuid.UUID     `json:"user_id,omitempty" gorm:"type:char(36);index;default:NULL"`
 	Title     string         `json:"title" gorm:"type:varchar(255);not null"`
 	Content   string         `json:"content" gorm:"type:text;not null"`
 	ImageURL  string         `json:"image_url" gorm:"type:varchar(500)"` // 图片URL（可选）
@@ -54,16 +55,39 @@ func CreateNotification(title, content, imageURL string) (*Notification, int) {
 	return notification, utils.SUCCESS
 }
 
+// CreateUserNotification 创建个人通知（系统发送给指定用户，如价格预警）
+func CreateUserNotification(userID uuid.UUID, title, content string) (*Notification, int) {
+	notification := &Notification{
+		ID:      uuid.New(),
+		UserID:  &userID,
+		Title:   title,
+		Content: content,
+	}
+	err := config.DB.Create(notification).Error
+	if err != nil {
+		config.Log.Errorf("Create user notification error: %v", err)
+		return nil, utils.ErrCodeCreateNotification
+	}
+	return notification, utils.SUCCESS
+}
+
+// visibleTo 用户可见的通知：全局通知 + 自己的个人通知
+func visibleTo(userID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(user_id IS NULL OR user_id = ?)", userID)
+	}
+}
+
 // GetNotifications 获取通知列表（带用户已读状态）
 func GetNotifications(userID uuid.UUID, pageSize, pageNum int) ([]NotificationResponse, int64, int) {
 	var notifications []Notification
 	var total int64
 
 	// 获取通知总数
-	config.DB.Model(&Notification{}).Count(&total)
+	config.DB.Model(&Notification{}).Scopes(visibleTo(userID)).Count(&total)
 
 	// 获取通知列表
-	err := config.DB.Order("created_at DESC").
+	err := config.DB.Scopes(visibleTo(userID)).Order("created_at DESC").
 		Limit(pageSize).
 		Offset((pageNum - 1) * pageSize).
 		Find(&notifications).Error
@@ -107,7 +131,7 @@ func GetUnreadCount(userID uuid.UUID) (int64, int) {
 	var readCount int64
 
 	// 获取通知总数
-	config.DB.Model(&Notification{}).Count(&totalCount)
+	config.DB.Model(&Notification{}).Scopes(visibleTo(userID)).Count(&totalCount)
 
 	// 获取用户已读数量
 	config.DB.Model(&NotificationRead{}).
@@ -126,7 +150,7 @@ func GetUnreadCount(userID uuid.UUID) (int64, int) {
 func MarkAsRead(userID uuid.UUID, notificationID uuid.UUID) int {
 	// 检查通知是否存在
 	var notification Notification
-	if err := config.DB.First(&notification, "id = ?", notificationID).Error; err != nil {
+	if err := config.DB.Scopes(visibleTo(userID)).First(&notification, "id = ?", notificationID).Error; err != nil {
 		return utils.ErrCodeNotificationNotFound
 	}
 
@@ -155,7 +179,7 @@ func MarkAsRead(userID uuid.UUID, notificationID uuid.UUID) int {
 func MarkAllAsRead(userID uuid.UUID) int {
 	// 获取所有通知ID
 	var notificationIDs []uuid.UUID
-	config.DB.Model(&Notification{}).Pluck("id", &notificationIDs)
+	config.DB.Model(&Notification{}).Scopes(visibleTo(userID)).Pluck("id", &notificationIDs)
 
 	// 获取用户已读的通知ID
 	var readNotificationIDs []uuid.UUID
@@ -212,9 +236,9 @@ func GetAllNotifications(pageSize, pageNum int) ([]NotificationResponse, int64,
 	var notifications []Notification
 	var total int64
 
-	config.DB.Model(&Notification{}).Count(&total)
+	config.DB.Model(&Notification{}).Where("user_id IS NULL").Count(&total)
 
-	err := config.DB.Order("created_at DESC").
+	err := config.DB.Where("user_id IS NULL").Order("created_at DESC").
 		Limit(pageSize).
 		Offset((pageNum - 1) * pageSize).
 		Find(&notifications).Error
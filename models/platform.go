@@ -0,0 +1,254 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PlatformDef 平台定义：新增平台只需注册一条定义（或在 settings.yaml 的 platforms 中配置）
+type PlatformDef struct {
+	Code            string      // 平台代码：price_history、手续费、数据源报价使用，如 YOUPIN
+	Key             string      // 接口参数中的平台标识，如 uu
+	Table           string      // 行情数据表，如 u
+	Name            string      // 简称（搬砖列表的平台列表），如 悠悠
+	FullName        string      // 全称（饰品详情），如 悠悠有品
+	LinkTemplate    string      // 商品链接模板，{id} 为平台商品ID，{name} 为 market_hash_name
+	SupportsBidding bool        // 是否支持求购
+	ExternalPrice   bool        // 价格由独立任务维护（如 Steam），数据源报价只更新链接
+	DefaultFee      PlatformFee // 默认手续费，首次启动时写入 platform_fee 表
+	Currency        string      // 报价币种，入库前按汇率换算为人民币，默认 CNY
+	DefaultMaxAge   int64       // 行情最大有效时长（秒），超过视为过期，管理员可在系统配置中覆盖，默认 1 小时
+	Model           interface{} // 数据表模型，为空时使用 PlatformRow 建表
+}
+
+// PlatformRow 平台行情表的通用行结构，所有平台表结构相同
+type PlatformRow struct {
+	Id             string  `json:"platformItemId" gorm:"primaryKey"`
+	MarketHashName string  `json:"marketHashName" gorm:"type:varchar(255);uniqueIndex;not null"`
+	SellPrice      float64 `json:"sellPrice" gorm:"index" currency:"amount"`
+	SellCount      int64   `json:"sellCount" gorm:"index"`
+	BiddingPrice   float64 `json:"biddingPrice" gorm:"index" currency:"amount"`
+	BiddingCount   int64   `json:"biddingCount"`
+	UpdateTime     int64   `json:"updateTime"`
+	BeforeTime     int64   `json:"beforeTime"`
+	BeforeCount    int64   `json:"beforeCount"`
+	TurnOver       int64   `json:"turn_over"`
+	Link           string  `json:"link"`
+	Currency       string  `json:"currency" gorm:"type:varchar(3);default:CNY"` // 原始报价币种，价格已换算为人民币
+	Anomaly        string  `json:"anomaly" gorm:"type:varchar(20)"`             // 异常报价原因，为空表示正常
+}
+
+// 已注册的平台，按注册顺序排列
+var platformRegistry []*PlatformDef
+
+func init() {
+	RegisterPlatform(&PlatformDef{
+		Code:            "YOUPIN",
+		Key:             "uu",
+		Table:           "u",
+		Name:            "悠悠",
+		FullName:        "悠悠有品",
+		LinkTemplate:    "https://www.youpin898.com/market/goods-list?listType=10&templateId={id}&gameId=730",
+		SupportsBidding: true,
+		DefaultFee:      PlatformFee{SellerFee: 0.01, WithdrawFee: 0.01, MinFee: 0.01},
+		Model:           &U{},
+	})
+	RegisterPlatform(&PlatformDef{
+		Code:            "BUFF",
+		Key:             "buff",
+		Table:           "buff",
+		Name:            "BUFF",
+		FullName:        "BUFF",
+		LinkTemplate:    "https://buff.163.com/goods/{id}?from=market#tab=selling",
+		SupportsBidding: true,
+		DefaultFee:      PlatformFee{SellerFee: 0.025, WithdrawFee: 0.01, MinFee: 0.01},
+		Model:           &Buff{},
+	})
+	RegisterPlatform(&PlatformDef{
+		Code:            "C5",
+		Key:             "c5",
+		Table:           "c5",
+		Name:            "C5GAME",
+		FullName:        "C5GAME",
+		LinkTemplate:    "https://www.c5game.com/csgo/{id}/{name}/sell",
+		SupportsBidding: true,
+		DefaultFee:      PlatformFee{SellerFee: 0.01, WithdrawFee: 0.01, MinFee: 0.01},
+		Model:           &C5{},
+	})
+	RegisterPlatform(&PlatformDef{
+		Code:            "STEAM",
+		Key:             "steam",
+		Table:           "steam",
+		Name:            "Steam",
+		FullName:        "Steam",
+		LinkTemplate:    "https://steamcommunity.com/market/listings/730/{name}",
+		SupportsBidding: true,
+		ExternalPrice:   true,
+		DefaultFee:      PlatformFee{SellerFee: 0.15, WithdrawFee: 0, MinFee: 0.01}, // Steam 余额无法提现
+		DefaultMaxAge:   86400,                                                      // Steam 价格逐个抓取，一轮需要较长时间
+		Model:           &Steam{},
+	})
+}
+
+// RegisterPlatform 注册平台，代码或标识重复时覆盖原定义
+func RegisterPlatform(def *PlatformDef) {
+	def.DefaultFee.Platform = def.Code
+	def.Currency = NormalizeCurrency(def.Currency)
+	if def.DefaultMaxAge <= 0 {
+		def.DefaultMaxAge = defaultMaxAge
+	}
+	for i, p := range platformRegistry {
+		if p.Code == def.Code || p.Key == def.Key {
+			platformRegistry[i] = def
+			return
+		}
+	}
+	platformRegistry = append(platformRegistry, def)
+}
+
+// RegisterConfigPlatforms 注册 settings.yaml 中配置的平台
+func RegisterConfigPlatforms(platforms []*config.Platform) {
+	for _, p := range platforms {
+		if p.Code == "" || p.Key == "" || p.Table == "" {
+			config.Log.Warnf("Skip invalid platform config: %+v", p)
+			continue
+		}
+		fullName := p.FullName
+		if fullName == "" {
+			fullName = p.Name
+		}
+		RegisterPlatform(&PlatformDef{
+			Code:            p.Code,
+			Key:             p.Key,
+			Table:           p.Table,
+			Name:            p.Name,
+			FullName:        fullName,
+			LinkTemplate:    p.LinkTemplate,
+			SupportsBidding: p.SupportsBidding,
+			DefaultFee:      PlatformFee{SellerFee: p.SellerFee, WithdrawFee: p.WithdrawFee, MinFee: p.MinFee},
+			Currency:        p.Currency,
+			DefaultMaxAge:   p.MaxAge,
+		})
+	}
+}
+
+// ApplyPlatformCurrencies 按配置覆盖平台报价币种，key: 平台代码
+func ApplyPlatformCurrencies(currencies map[string]string) {
+	for code, currency := range currencies {
+		p, ok := GetPlatformByCode(code)
+		if !ok {
+			config.Log.Warnf("Skip currency config of unknown platform: %s", code)
+			continue
+		}
+		p.Currency = NormalizeCurrency(currency)
+	}
+}
+
+// Platforms 返回所有已注册平台
+func Platforms() []*PlatformDef {
+	return platformRegistry
+}
+
+// GetPlatformByKey 按接口参数标识查找平台
+func GetPlatformByKey(key string) (*PlatformDef, bool) {
+	for _, p := range platformRegistry {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// GetPlatformByCode 按平台代码查找平台
+func GetPlatformByCode(code string) (*PlatformDef, bool) {
+	for _, p := range platformRegistry {
+		if p.Code == code {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// MigratePlatformTables 创建/更新所有平台的行情数据表
+func MigratePlatformTables(db *gorm.DB) error {
+	for _, p := range platformRegistry {
+		var err error
+		if p.Model != nil {
+			err = db.AutoMigrate(p.Model)
+		} else {
+			err = db.Table(p.Table).AutoMigrate(&PlatformRow{})
+		}
+		if err != nil {
+			return fmt.Errorf("migrate platform %s: %w", p.Code, err)
+		}
+	}
+	return nil
+}
+
+// BuildLink 根据链接模板生成商品链接
+func (p *PlatformDef) BuildLink(platformItemId, marketHashName string) string {
+	return strings.NewReplacer("{id}", platformItemId, "{name}", marketHashName).Replace(p.LinkTemplate)
+}
+
+// Fee 平台当前手续费
+func (p *PlatformDef) Fee() *PlatformFee {
+	return GetPlatformFee(p.Code)
+}
+
+// BatchGetPlatformRows 批量获取平台行情，key: market_hash_name
+func BatchGetPlatformRows(p *PlatformDef, hashNames []string) map[string]*PlatformRow {
+	var rows []PlatformRow
+	err := config.DB.Table(p.Table).Where("market_hash_name in ?", hashNames).Find(&rows).Error
+	if err != nil {
+		config.Log.Errorf("Batch get %s goods error: %v", p.Code, err)
+	}
+	result := make(map[string]*PlatformRow, len(rows))
+	for i := range rows {
+		result[rows[i].MarketHashName] = &rows[i]
+	}
+	return result
+}
+
+// BatchUpdatePlatformRows 批量写入平台行情，死锁时重试
+// ExternalPrice 的平台只更新链接，价格由独立任务维护
+func BatchUpdatePlatformRows(p *PlatformDef, rows []*PlatformRow) {
+	if len(rows) == 0 {
+		return
+	}
+	onConflict := clause.OnConflict{UpdateAll: true}
+	if p.ExternalPrice {
+		onConflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "market_hash_name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"link"}),
+		}
+	}
+
+	maxRetries := 3
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			// 减小批量大小，降低锁冲突概率
+			return tx.Table(p.Table).Clauses(onConflict).CreateInBatches(rows, 50).Error
+		})
+		if err == nil {
+			utils.PlatformRowsUpsertedTotal.WithLabelValues(p.Code).Add(float64(len(rows)))
+			return
+		}
+		// 如果是死锁错误，等待后重试
+		if strings.Contains(err.Error(), "Deadlock") || strings.Contains(err.Error(), "SAVEPOINT") {
+			config.Log.Warnf("Update %s Goods deadlock, retrying (%d/%d)...", p.Code, i+1, maxRetries)
+			time.Sleep(time.Millisecond * time.Duration(100*(i+1))) // 递增等待
+			continue
+		}
+		break // 其他错误不重试
+	}
+	if err != nil {
+		config.Log.Errorf("Update %s Goods fail: %v", p.Code, err)
+	}
+}
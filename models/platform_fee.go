@@ -0,0 +1,153 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"uu/config"
+)
+
+// PlatformFee 平台手续费配置（管理员可修改）
+type PlatformFee struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Platform    string    `json:"platform" gorm:"type:varchar(20);uniqueIndex;not null"` // YOUPIN, BUFF, C5, STEAM
+	SellerFee   float64   `json:"seller_fee"`                                            // 卖家手续费率，0.025 表示 2.5%
+	WithdrawFee float64   `json:"withdraw_fee"`                                          // 提现手续费率
+	MinFee      float64   `json:"min_fee"`                                               // 单笔最低手续费（元）
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// InitPlatformFees 初始化平台手续费（缺失的平台插入平台定义中的默认值）
+func InitPlatformFees() error {
+	for _, p := range Platforms() {
+		fee := p.DefaultFee
+		var count int64
+		config.DB.Model(&PlatformFee{}).Where("platform = ?", fee.Platform).Count(&count)
+		if count > 0 {
+			continue
+		}
+		if err := config.DB.Create(&fee).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllPlatformFees 获取所有平台手续费
+func GetAllPlatformFees() ([]PlatformFee, error) {
+	var fees []PlatformFee
+	if err := config.DB.Order("id ASC").Find(&fees).Error; err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
+
+// platformFeeCacheTTL 手续费缓存有效期，其他实例修改的手续费最迟在该时间后生效
+const platformFeeCacheTTL = time.Minute
+
+// platformFeeCache 平台手续费缓存，本实例修改手续费时清除
+var platformFeeCache struct {
+	sync.RWMutex
+	fees     map[string]PlatformFee
+	loadedAt time.Time
+}
+
+// GetPlatformFeeMap 获取平台手续费映射，key: 平台代码
+// 数据库读取失败或缺失的平台使用默认值；返回的是副本，调用方可以修改
+func GetPlatformFeeMap() map[string]*PlatformFee {
+	platformFeeCache.RLock()
+	if platformFeeCache.fees != nil && time.Since(platformFeeCache.loadedAt) < platformFeeCacheTTL {
+		result := copyPlatformFees(platformFeeCache.fees)
+		platformFeeCache.RUnlock()
+		return result
+	}
+	platformFeeCache.RUnlock()
+
+	fees := make(map[string]PlatformFee)
+	for _, p := range Platforms() {
+		fees[p.DefaultFee.Platform] = p.DefaultFee
+	}
+	rows, err := GetAllPlatformFees()
+	if err != nil {
+		config.Log.Errorf("Get platform fees error: %v", err)
+	}
+	for _, f := range rows {
+		fees[f.Platform] = f
+	}
+	if err == nil {
+		platformFeeCache.Lock()
+		platformFeeCache.fees = fees
+		platformFeeCache.loadedAt = time.Now()
+		platformFeeCache.Unlock()
+	}
+	return copyPlatformFees(fees)
+}
+
+func copyPlatformFees(fees map[string]PlatformFee) map[string]*PlatformFee {
+	result := make(map[string]*PlatformFee, len(fees))
+	for code, fee := range fees {
+		result[code] = &fee
+	}
+	return result
+}
+
+// clearPlatformFeeCache 手续费变更后清除缓存
+func clearPlatformFeeCache() {
+	platformFeeCache.Lock()
+	platformFeeCache.fees = nil
+	platformFeeCache.Unlock()
+}
+
+// GetPlatformFee 获取单个平台手续费，不存在时返回零手续费
+func GetPlatformFee(platform string) *PlatformFee {
+	if fee, ok := GetPlatformFeeMap()[platform]; ok {
+		return fee
+	}
+	return &PlatformFee{Platform: platform}
+}
+
+// UpdatePlatformFee 更新平台手续费（不存在则创建）
+func UpdatePlatformFee(platform string, sellerFee, withdrawFee, minFee float64) error {
+	defer clearPlatformFeeCache()
+
+	var fee PlatformFee
+	err := config.DB.Where("platform = ?", platform).First(&fee).Error
+	if err != nil {
+		fee = PlatformFee{
+			Platform:    platform,
+			SellerFee:   sellerFee,
+			WithdrawFee: withdrawFee,
+			MinFee:      minFee,
+		}
+		return config.DB.Create(&fee).Error
+	}
+	return config.DB.Model(&fee).Updates(map[string]interface{}{
+		"seller_fee":   sellerFee,
+		"withdraw_fee": withdrawFee,
+		"min_fee":      minFee,
+	}).Error
+}
+
+// NetProceeds 按指定价格卖出后实际到手金额（扣除卖家手续费和提现手续费）
+func (f *PlatformFee) NetProceeds(price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	fee := math.Max(price*f.SellerFee, f.MinFee)
+	return (price - fee) * (1 - f.WithdrawFee)
+}
+
+// NetProceedsSQL 生成与 NetProceeds 等价的 SQL 表达式，priceExpr 为价格列或表达式
+func (f *PlatformFee) NetProceedsSQL(priceExpr string) string {
+	return fmt.Sprintf("((%s - GREATEST(%s * %s, %s)) * %s)",
+		priceExpr, priceExpr,
+		formatSQLFloat(f.SellerFee),
+		formatSQLFloat(f.MinFee),
+		formatSQLFloat(1-f.WithdrawFee))
+}
+
+func formatSQLFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,453 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// MaxHoldingsPerUser 每个用户最多可记录的持仓条数（含已卖出）
+const MaxHoldingsPerUser = 1000
+
+// 持仓来源
+const (
+	HoldingSourceManual = "manual"
+	HoldingSourceUU     = "uu"
+	HoldingSourceBuff   = "buff"
+)
+
+// Holding 用户持仓记录，SellDate 为空表示仍持有
+// 价格均为单件人民币价格，BuyPrice 为 0 表示未填写买入价（如从库存导入），不计入成本和盈亏
+type Holding struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:char(36);index:idx_holding_user;not null"`
+	MarketHashName string     `json:"market_hash_name" gorm:"type:varchar(255);not null"`
+	Quantity       int64      `json:"quantity" gorm:"not null;default:1"`
+	BuyPrice       float64    `json:"buy_price" currency:"amount"`
+	BuyPlatform    string     `json:"buy_platform" gorm:"type:varchar(20)"` // 平台代码
+	BuyDate        time.Time  `json:"buy_date" gorm:"type:date"`
+	Source         string     `json:"source" gorm:"type:varchar(10);default:manual"` // manual / uu / buff
+	Note           string     `json:"note" gorm:"type:varchar(255)"`
+	SellPrice      float64    `json:"sell_price" currency:"amount"`          // 成交价
+	SellNet        float64    `json:"sell_net" currency:"amount"`            // 扣除卖出平台手续费后的到手金额
+	SellPlatform   string     `json:"sell_platform" gorm:"type:varchar(20)"` // 平台代码
+	SellDate       *time.Time `json:"sell_date" gorm:"type:date"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// PortfolioValuation 每日组合估值，用于绘制组合价值曲线
+type PortfolioValuation struct {
+	ID             uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID         uuid.UUID `json:"-" gorm:"type:char(36);uniqueIndex:idx_user_valuation_date,priority:1;not null"`
+	RecordDate     time.Time `json:"-" gorm:"type:date;uniqueIndex:idx_user_valuation_date,priority:2"`
+	Cost           float64   `json:"cost" currency:"amount"`            // 持有中饰品的买入成本
+	MarketValue    float64   `json:"market_value" currency:"amount"`    // 持有中饰品按最佳平台到手价的估值
+	RealizedProfit float64   `json:"realized_profit" currency:"amount"` // 截至当日已实现盈亏
+}
+
+// HoldingValuation 持有中饰品的估值
+type HoldingValuation struct {
+	Holding
+	Name             string             `json:"name"`
+	ImageUrl         string             `json:"image_url"`
+	BestPlatform     string             `json:"best_platform"`                     // 到手金额最高的平台标识
+	BestPlatformName string             `json:"best_platform_name"`                // 到手金额最高的平台名称
+	BestSellPrice    float64            `json:"best_sell_price" currency:"amount"` // 该平台在售价
+	BestNetPrice     float64            `json:"best_net_price" currency:"amount"`  // 该平台扣除手续费后的单件到手金额
+	MarketValue      float64            `json:"market_value" currency:"amount"`    // best_net_price * quantity
+	UnrealizedProfit float64            `json:"unrealized_profit" currency:"amount"`
+	UnrealizedRate   float64            `json:"unrealized_rate"`
+	PlatformPrices   map[string]float64 `json:"platform_prices" currency:"amount"` // 各平台最近一次记录的在售价，key: 平台标识
+	PriceDate        string             `json:"price_date"`                        // 估值使用的价格日期
+}
+
+// PortfolioSummary 组合概览
+type PortfolioSummary struct {
+	Holdings         []*HoldingValuation `json:"holdings"`
+	Sold             []*Holding          `json:"sold"`
+	Cost             float64             `json:"cost" currency:"amount"`
+	MarketValue      float64             `json:"market_value" currency:"amount"`
+	UnrealizedProfit float64             `json:"unrealized_profit" currency:"amount"`
+	RealizedProfit   float64             `json:"realized_profit" currency:"amount"`
+	Unpriced         int                 `json:"unpriced"` // 未填写买入价的持仓数，其估值不计入未实现盈亏
+}
+
+// PortfolioPoint 组合价值曲线上的一个点
+type PortfolioPoint struct {
+	Date string `json:"date"`
+	PortfolioValuation
+}
+
+// valuationLookbackDays 估值时向前查找价格记录的天数，超过则视为无价格
+const valuationLookbackDays = 7
+
+// historyPrice 某平台在某日的价格记录
+type historyPrice struct {
+	Platform  string
+	SellPrice float64
+	Date      time.Time
+}
+
+// latestHistoryPrices 各饰品在 date 当天及之前最近一次的各平台价格，key: market_hash_name -> 平台代码
+func latestHistoryPrices(hashNames []string, date time.Time) (map[string]map[string]historyPrice, error) {
+	result := make(map[string]map[string]historyPrice, len(hashNames))
+	if len(hashNames) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		MarketHashName string
+		Platform       string
+		SellPrice      float64
+		RecordDate     time.Time
+	}
+	err := config.DB.Model(&PriceHistory{}).
+		Select("market_hash_name, platform, sell_price, record_date").
+		Where("market_hash_name IN ? AND record_date <= ? AND record_date > ? AND sell_price > 0",
+			hashNames, date, date.AddDate(0, 0, -valuationLookbackDays)).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		m := result[r.MarketHashName]
+		if m == nil {
+			m = make(map[string]historyPrice)
+			result[r.MarketHashName] = m
+		}
+		if cur, ok := m[r.Platform]; !ok || r.RecordDate.After(cur.Date) {
+			m[r.Platform] = historyPrice{Platform: r.Platform, SellPrice: r.SellPrice, Date: r.RecordDate}
+		}
+	}
+	return result, nil
+}
+
+// bestSellPlace 扣除手续费后到手金额最高的平台
+func bestSellPlace(prices map[string]historyPrice, fees map[string]*PlatformFee) (*PlatformDef, historyPrice, float64) {
+	var best *PlatformDef
+	var bestPrice historyPrice
+	bestNet := 0.0
+	for _, p := range Platforms() {
+		hp, ok := prices[p.Code]
+		if !ok {
+			continue
+		}
+		net := fees[p.Code].NetProceeds(hp.SellPrice)
+		if net > bestNet {
+			best, bestPrice, bestNet = p, hp, net
+		}
+	}
+	return best, bestPrice, bestNet
+}
+
+// CreateHolding 添加持仓
+func CreateHolding(h *Holding) int {
+	var count int64
+	config.DB.Model(&Holding{}).Where("user_id = ?", h.UserID).Count(&count)
+	if count >= MaxHoldingsPerUser {
+		return utils.ErrCodeHoldingLimit
+	}
+	if h.Source == "" {
+		h.Source = HoldingSourceManual
+	}
+	if err := config.DB.Create(h).Error; err != nil {
+		config.Log.Errorf("Create holding error: %v", err)
+		return utils.ErrCodeCreateHolding
+	}
+	return utils.SUCCESS
+}
+
+// GetHolding 获取用户的某条持仓
+func GetHolding(userID uuid.UUID, id uint) (*Holding, int) {
+	var h Holding
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&h).Error; err != nil {
+		return nil, utils.ErrCodeHoldingNotFound
+	}
+	return &h, utils.SUCCESS
+}
+
+// UpdateHolding 修改持仓的数量、买入信息和备注
+func UpdateHolding(userID uuid.UUID, id uint, updates *Holding) int {
+	h, code := GetHolding(userID, id)
+	if code != utils.SUCCESS {
+		return code
+	}
+	err := config.DB.Model(h).Select("quantity", "buy_price", "buy_platform", "buy_date", "note").Updates(updates).Error
+	if err != nil {
+		config.Log.Errorf("Update holding error: %v", err)
+		return utils.ErrCodeUpdateHolding
+	}
+	return utils.SUCCESS
+}
+
+// DeleteHolding 删除持仓记录
+func DeleteHolding(userID uuid.UUID, id uint) int {
+	res := config.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&Holding{})
+	if res.Error != nil {
+		config.Log.Errorf("Delete holding error: %v", res.Error)
+		return utils.ErrCodeDeleteHolding
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrCodeHoldingNotFound
+	}
+	return utils.SUCCESS
+}
+
+// SellHolding 记录卖出，部分卖出时拆分出一条已卖出记录，剩余数量继续持有
+func SellHolding(userID uuid.UUID, id uint, quantity int64, price float64, platform *PlatformDef, date time.Time) int {
+	h, code := GetHolding(userID, id)
+	if code != utils.SUCCESS {
+		return code
+	}
+	if h.SellDate != nil || quantity <= 0 || quantity > h.Quantity {
+		return utils.ErrCodeInvalidParams
+	}
+
+	net := math.Round(platform.Fee().NetProceeds(price)*100) / 100
+	sold := *h
+	sold.Quantity = quantity
+	sold.SellPrice = price
+	sold.SellNet = net
+	sold.SellPlatform = platform.Code
+	sold.SellDate = &date
+
+	tx := config.DB.Begin()
+	if quantity == h.Quantity {
+		if err := tx.Save(&sold).Error; err != nil {
+			tx.Rollback()
+			config.Log.Errorf("Sell holding error: %v", err)
+			return utils.ErrCodeUpdateHolding
+		}
+	} else {
+		sold.ID = 0
+		if err := tx.Create(&sold).Error; err != nil {
+			tx.Rollback()
+			config.Log.Errorf("Sell holding error: %v", err)
+			return utils.ErrCodeUpdateHolding
+		}
+		if err := tx.Model(h).Update("quantity", h.Quantity-quantity).Error; err != nil {
+			tx.Rollback()
+			config.Log.Errorf("Sell holding error: %v", err)
+			return utils.ErrCodeUpdateHolding
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		config.Log.Errorf("Sell holding error: %v", err)
+		return utils.ErrCodeUpdateHolding
+	}
+	return utils.SUCCESS
+}
+
+// ImportHoldings 导入平台库存：已导入且仍持有的同名饰品只更新数量，其余新建
+// price 为导入时平台给出的参考价，作为默认买入价，用户可之后修改
+func ImportHoldings(userID uuid.UUID, source string, items []*Holding) (int, int) {
+	var existing []Holding
+	err := config.DB.Where("user_id = ? AND source = ? AND sell_date IS NULL", userID, source).Find(&existing).Error
+	if err != nil {
+		config.Log.Errorf("Get imported holdings error: %v", err)
+		return 0, utils.ErrCodeImportInventory
+	}
+	existingMap := make(map[string]*Holding, len(existing))
+	for i := range existing {
+		existingMap[existing[i].MarketHashName] = &existing[i]
+	}
+
+	var count int64
+	config.DB.Model(&Holding{}).Where("user_id = ?", userID).Count(&count)
+
+	imported := 0
+	for _, item := range items {
+		if h, ok := existingMap[item.MarketHashName]; ok {
+			if h.Quantity != item.Quantity {
+				config.DB.Model(h).Update("quantity", item.Quantity)
+			}
+			imported++
+			continue
+		}
+		if count >= MaxHoldingsPerUser {
+			return imported, utils.ErrCodeHoldingLimit
+		}
+		item.UserID = userID
+		item.Source = source
+		if err := config.DB.Create(item).Error; err != nil {
+			config.Log.Errorf("Import holding error: %v", err)
+			return imported, utils.ErrCodeImportInventory
+		}
+		count++
+		imported++
+	}
+	return imported, utils.SUCCESS
+}
+
+// GetPortfolio 获取用户组合：持有中饰品按最近一日价格估值，并给出到手金额最高的卖出平台
+func GetPortfolio(userID uuid.UUID) (*PortfolioSummary, int) {
+	var holdings []Holding
+	if err := config.DB.Where("user_id = ?", userID).Order("buy_date DESC, id DESC").Find(&holdings).Error; err != nil {
+		config.Log.Errorf("Get holdings error: %v", err)
+		return nil, utils.ErrCodeGetPortfolio
+	}
+
+	summary := &PortfolioSummary{Holdings: []*HoldingValuation{}, Sold: []*Holding{}}
+	var held []*HoldingValuation
+	var hashNames []string
+	for i := range holdings {
+		h := &holdings[i]
+		if h.SellDate != nil {
+			summary.Sold = append(summary.Sold, h)
+			if h.BuyPrice > 0 {
+				summary.RealizedProfit += (h.SellNet - h.BuyPrice) * float64(h.Quantity)
+			}
+			continue
+		}
+		held = append(held, &HoldingValuation{Holding: *h, PlatformPrices: map[string]float64{}})
+		hashNames = append(hashNames, h.MarketHashName)
+	}
+
+	prices, err := latestHistoryPrices(hashNames, getLocalToday())
+	if err != nil {
+		config.Log.Errorf("Get holding prices error: %v", err)
+		return nil, utils.ErrCodeGetPortfolio
+	}
+	fees := GetPlatformFeeMap()
+	infos := goodsBaseInfo(hashNames)
+	for _, v := range held {
+		if info, ok := infos[v.MarketHashName]; ok {
+			v.Name, v.ImageUrl = info.Name, info.IconUrl
+		}
+		for code, hp := range prices[v.MarketHashName] {
+			if p, ok := GetPlatformByCode(code); ok {
+				v.PlatformPrices[p.Key] = hp.SellPrice
+			}
+		}
+		cost := v.BuyPrice * float64(v.Quantity)
+		summary.Cost += cost
+		if best, hp, net := bestSellPlace(prices[v.MarketHashName], fees); best != nil {
+			v.BestPlatform, v.BestPlatformName = best.Key, best.Name
+			v.BestSellPrice = hp.SellPrice
+			v.BestNetPrice = math.Round(net*100) / 100
+			v.MarketValue = math.Round(net*float64(v.Quantity)*100) / 100
+			v.PriceDate = hp.Date.Format("2006-01-02")
+		}
+		summary.MarketValue += v.MarketValue
+		if cost <= 0 {
+			summary.Unpriced++
+			summary.Holdings = append(summary.Holdings, v)
+			continue
+		}
+		v.UnrealizedProfit = math.Round((v.MarketValue-cost)*100) / 100
+		v.UnrealizedRate = math.Round(v.UnrealizedProfit/cost*10000) / 10000
+		summary.UnrealizedProfit += v.MarketValue - cost
+		summary.Holdings = append(summary.Holdings, v)
+	}
+	sort.SliceStable(summary.Holdings, func(i, j int) bool {
+		return summary.Holdings[i].MarketValue > summary.Holdings[j].MarketValue
+	})
+
+	summary.Cost = math.Round(summary.Cost*100) / 100
+	summary.MarketValue = math.Round(summary.MarketValue*100) / 100
+	summary.UnrealizedProfit = math.Round(summary.UnrealizedProfit*100) / 100
+	summary.RealizedProfit = math.Round(summary.RealizedProfit*100) / 100
+	return summary, utils.SUCCESS
+}
+
+// goodsBaseInfo 饰品名称和图标，key: market_hash_name
+func goodsBaseInfo(hashNames []string) map[string]BaseGoods {
+	result := make(map[string]BaseGoods, len(hashNames))
+	if len(hashNames) == 0 {
+		return result
+	}
+	var goods []BaseGoods
+	config.DB.Where("market_hash_name IN ?", hashNames).Find(&goods)
+	for _, g := range goods {
+		result[g.MarketHashName] = g
+	}
+	return result
+}
+
+// RecordPortfolioValuations 按指定日期的价格记录所有用户的组合估值
+func RecordPortfolioValuations(date time.Time) {
+	var holdings []Holding
+	if err := config.DB.Where("buy_date <= ?", date).Find(&holdings).Error; err != nil {
+		config.Log.Errorf("Get holdings for valuation error: %v", err)
+		return
+	}
+	if len(holdings) == 0 {
+		return
+	}
+
+	nameSet := make(map[string]bool)
+	for _, h := range holdings {
+		if h.SellDate == nil {
+			nameSet[h.MarketHashName] = true
+		}
+	}
+	hashNames := make([]string, 0, len(nameSet))
+	for name := range nameSet {
+		hashNames = append(hashNames, name)
+	}
+	prices, err := latestHistoryPrices(hashNames, date)
+	if err != nil {
+		config.Log.Errorf("Get prices for valuation error: %v", err)
+		return
+	}
+	fees := GetPlatformFeeMap()
+
+	valuations := make(map[uuid.UUID]*PortfolioValuation)
+	for _, h := range holdings {
+		v := valuations[h.UserID]
+		if v == nil {
+			v = &PortfolioValuation{UserID: h.UserID, RecordDate: date}
+			valuations[h.UserID] = v
+		}
+		// 未填写买入价的持仓不计入估值，避免成本为 0 时盈亏失真
+		if h.BuyPrice <= 0 {
+			continue
+		}
+		if h.SellDate != nil && !h.SellDate.After(date) {
+			v.RealizedProfit += (h.SellNet - h.BuyPrice) * float64(h.Quantity)
+			continue
+		}
+		v.Cost += h.BuyPrice * float64(h.Quantity)
+		if _, _, net := bestSellPlace(prices[h.MarketHashName], fees); net > 0 {
+			v.MarketValue += net * float64(h.Quantity)
+		}
+	}
+
+	rows := make([]*PortfolioValuation, 0, len(valuations))
+	for _, v := range valuations {
+		v.Cost = math.Round(v.Cost*100) / 100
+		v.MarketValue = math.Round(v.MarketValue*100) / 100
+		v.RealizedProfit = math.Round(v.RealizedProfit*100) / 100
+		rows = append(rows, v)
+	}
+	err = config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "record_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cost", "market_value", "realized_profit"}),
+	}).CreateInBatches(rows, 200).Error
+	if err != nil {
+		config.Log.Errorf("Record portfolio valuations error: %v", err)
+		return
+	}
+	config.Log.Infof("Recorded portfolio valuations for %d users", len(rows))
+}
+
+// GetPortfolioChart 获取最近 days 天的组合价值曲线
+func GetPortfolioChart(userID uuid.UUID, days int) ([]PortfolioPoint, int) {
+	var rows []PortfolioValuation
+	err := config.DB.Where("user_id = ? AND record_date >= ?", userID, getLocalToday().AddDate(0, 0, -days)).
+		Order("record_date ASC").Find(&rows).Error
+	if err != nil {
+		config.Log.Errorf("Get portfolio chart error: %v", err)
+		return nil, utils.ErrCodeGetPortfolio
+	}
+	points := make([]PortfolioPoint, 0, len(rows))
+	for _, r := range rows {
+		points = append(points, PortfolioPoint{Date: r.RecordDate.Format("2006-01-02"), PortfolioValuation: r})
+	}
+	return points, utils.SUCCESS
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"github.com/google/uuid"
+)
+
+// 预警类型
+const (
+	AlertTypePriceBelow  = "price_below"  // 指定平台在售价低于阈值
+	AlertTypePriceAbove  = "price_above"  // 指定平台在售价高于阈值
+	AlertTypeSpreadAbove = "spread_above" // 源平台 -> 目标平台价差百分比高于阈值
+)
+
+const (
+	// MaxAlertsPerUser 每个用户最多可创建的预警数量
+	MaxAlertsPerUser = 50
+	// DefaultAlertCooldown 默认冷却时间（分钟）
+	DefaultAlertCooldown = 60
+)
+
+// PriceAlert 用户价格预警
+type PriceAlert struct {
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:char(36);index;not null"`
+	MarketHashName  string     `json:"market_hash_name" gorm:"type:varchar(255);index;not null"`
+	AlertType       string     `json:"alert_type" gorm:"type:varchar(20);not null"`
+	Platform        string     `json:"platform" gorm:"type:varchar(20)"`        // 价格预警使用：uu, buff, c5, steam
+	SourcePlatform  string     `json:"source_platform" gorm:"type:varchar(20)"` // 价差预警使用：买入平台
+	TargetPlatform  string     `json:"target_platform" gorm:"type:varchar(20)"` // 价差预警使用：卖出平台
+	Threshold       float64    `json:"threshold"`                               // 价格（元）或价差百分比
+	CooldownMinutes int        `json:"cooldown_minutes" gorm:"default:60"`      // 触发后多少分钟内不再重复通知
+	EmailEnabled    bool       `json:"email_enabled" gorm:"default:false"`      // 是否同时发送邮件
+	Enabled         bool       `json:"enabled" gorm:"default:true"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at" gorm:"type:datetime"`
+	TriggerCount    int64      `json:"trigger_count" gorm:"default:0"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"type:datetime;default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"type:datetime;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+}
+
+// AlertTrigger 预警触发记录
+type AlertTrigger struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	AlertID        uint      `json:"alert_id" gorm:"index;not null"`
+	UserID         uuid.UUID `json:"user_id" gorm:"type:char(36);index;not null"`
+	MarketHashName string    `json:"market_hash_name" gorm:"type:varchar(255)"`
+	AlertType      string    `json:"alert_type" gorm:"type:varchar(20)"`
+	Value          float64   `json:"value"`     // 触发时的价格或价差百分比
+	Threshold      float64   `json:"threshold"` // 触发时的阈值
+	Message        string    `json:"message" gorm:"type:varchar(500)"`
+	EmailSent      bool      `json:"email_sent"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:datetime;default:CURRENT_TIMESTAMP;index"`
+}
+
+// IsValidAlertType 校验预警类型
+func IsValidAlertType(alertType string) bool {
+	switch alertType {
+	case AlertTypePriceBelow, AlertTypePriceAbove, AlertTypeSpreadAbove:
+		return true
+	}
+	return false
+}
+
+// IsValidAlertPlatform 校验预警平台标识
+func IsValidAlertPlatform(platform string) bool {
+	_, ok := GetPlatformByKey(platform)
+	return ok
+}
+
+// InCooldown 是否处于冷却期
+func (a *PriceAlert) InCooldown(now time.Time) bool {
+	if a.LastTriggeredAt == nil {
+		return false
+	}
+	return now.Sub(*a.LastTriggeredAt) < time.Duration(a.CooldownMinutes)*time.Minute
+}
+
+// CreatePriceAlert 创建价格预警
+func CreatePriceAlert(alert *PriceAlert) int {
+	var count int64
+	config.DB.Model(&PriceAlert{}).Where("user_id = ?", alert.UserID).Count(&count)
+	if count >= MaxAlertsPerUser {
+		return utils.ErrCodeAlertLimitExceeded
+	}
+	if !alertGoodsExists(alert.MarketHashName) {
+		return utils.ErrCodeAlertGoodsNotFound
+	}
+	if err := config.DB.Create(alert).Error; err != nil {
+		config.Log.Errorf("Create price alert error: %v", err)
+		return utils.ErrCodeCreateAlert
+	}
+	return utils.SUCCESS
+}
+
+// alertGoodsExists 预警的饰品是否在饰品库中
+func alertGoodsExists(marketHashName string) bool {
+	var count int64
+	config.DB.Model(&BaseGoods{}).Where("market_hash_name = ?", marketHashName).Count(&count)
+	return count > 0
+}
+
+// GetUserPriceAlerts 获取用户的所有价格预警
+func GetUserPriceAlerts(userID uuid.UUID) ([]PriceAlert, int) {
+	var alerts []PriceAlert
+	err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&alerts).Error
+	if err != nil {
+		config.Log.Errorf("Get price alerts error: %v", err)
+		return nil, utils.ErrCodeGetAlerts
+	}
+	return alerts, utils.SUCCESS
+}
+
+// UpdatePriceAlert 更新价格预警（只能修改自己的）
+func UpdatePriceAlert(userID uuid.UUID, alert *PriceAlert) int {
+	var existing PriceAlert
+	if err := config.DB.Where("id = ? AND user_id = ?", alert.ID, userID).First(&existing).Error; err != nil {
+		return utils.ErrCodeAlertNotFound
+	}
+	if !alertGoodsExists(alert.MarketHashName) {
+		return utils.ErrCodeAlertGoodsNotFound
+	}
+	err := config.DB.Model(&existing).Select(
+		"market_hash_name", "alert_type", "platform", "source_platform", "target_platform",
+		"threshold", "cooldown_minutes", "email_enabled", "enabled",
+	).Updates(alert).Error
+	if err != nil {
+		config.Log.Errorf("Update price alert error: %v", err)
+		return utils.ErrCodeUpdateAlert
+	}
+	return utils.SUCCESS
+}
+
+// DeletePriceAlert 删除价格预警及其触发记录
+func DeletePriceAlert(userID uuid.UUID, alertID uint) int {
+	result := config.DB.Where("id = ? AND user_id = ?", alertID, userID).Delete(&PriceAlert{})
+	if result.Error != nil {
+		config.Log.Errorf("Delete price alert error: %v", result.Error)
+		return utils.ErrCodeDeleteAlert
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrCodeAlertNotFound
+	}
+	config.DB.Delete(&AlertTrigger{}, "alert_id = ?", alertID)
+	return utils.SUCCESS
+}
+
+// GetAlertTriggers 获取用户的预警触发记录，alertID 为 0 时返回全部
+func GetAlertTriggers(userID uuid.UUID, alertID uint, pageSize, pageNum int) ([]AlertTrigger, int64, int) {
+	var triggers []AlertTrigger
+	var total int64
+
+	query := config.DB.Model(&AlertTrigger{}).Where("user_id = ?", userID)
+	if alertID > 0 {
+		query = query.Where("alert_id = ?", alertID)
+	}
+	query.Count(&total)
+
+	err := query.Order("created_at DESC").
+		Limit(pageSize).
+		Offset((pageNum - 1) * pageSize).
+		Find(&triggers).Error
+	if err != nil {
+		config.Log.Errorf("Get alert triggers error: %v", err)
+		return nil, 0, utils.ErrCodeGetAlerts
+	}
+	return triggers, total, utils.SUCCESS
+}
+
+// GetEnabledPriceAlerts 获取所有启用的预警（后台评估使用）
+func GetEnabledPriceAlerts() ([]PriceAlert, error) {
+	var alerts []PriceAlert
+	err := config.DB.Where("enabled = ?", true).Find(&alerts).Error
+	return alerts, err
+}
+
+// GetPlatformSellPrices 批量获取指定平台的在售价，被标记为异常的报价不返回，key: market_hash_name
+func GetPlatformSellPrices(platform string, hashNames []string) map[string]float64 {
+	result := make(map[string]float64)
+	p, ok := GetPlatformByKey(platform)
+	if !ok || len(hashNames) == 0 {
+		return result
+	}
+
+	var rows []struct {
+		MarketHashName string
+		SellPrice      float64
+	}
+	err := config.DB.Table(p.Table).
+		Select("market_hash_name, sell_price").
+		Where("market_hash_name IN ? AND sell_price > 0 AND "+anomalyFilter(p.Table), hashNames).
+		Find(&rows).Error
+	if err != nil {
+		config.Log.Errorf("Get %s sell prices error: %v", platform, err)
+		return result
+	}
+	for _, r := range rows {
+		result[r.MarketHashName] = r.SellPrice
+	}
+	return result
+}
+
+// RecordAlertTrigger 记录一次触发并更新预警的最后触发时间
+func RecordAlertTrigger(alert *PriceAlert, trigger *AlertTrigger) error {
+	now := time.Now()
+	if err := config.DB.Create(trigger).Error; err != nil {
+		return err
+	}
+	alert.LastTriggeredAt = &now
+	alert.TriggerCount++
+	return config.DB.Model(&PriceAlert{}).Where("id = ?", alert.ID).Updates(map[string]interface{}{
+		"last_triggered_at": now,
+		"trigger_count":     alert.TriggerCount,
+	}).Error
+}
+
+// MarkAlertEmailSent 预警邮件发送成功后更新触发记录
+func MarkAlertEmailSent(triggerID uint) error {
+	return config.DB.Model(&AlertTrigger{}).Where("id = ?", triggerID).Update("email_sent", true).Error
+}
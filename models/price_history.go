@@ -18,9 +18,15 @@ type PriceHistory struct {
 	ID             uint      `gorm:"primaryKey;autoIncrement"`
 	MarketHashName string    `gorm:"type:varchar(255);index:idx_hash_platform_date,priority:1;index:idx_query,priority:3;not null"`
 	Platform       string    `gorm:"type:varchar(20);index:idx_hash_platform_date,priority:2;index:idx_platform_date,priority:1;index:idx_query,priority:1;not null"`
-	SellPrice      float64   `json:"sellPrice" gorm:"index:idx_query,priority:4"`
+	SellPrice      float64   `json:"sellPrice" gorm:"index:idx_query,priority:4" currency:"amount"`
 	SellCount      int64     `json:"sellCount"`
 	RecordDate     time.Time `gorm:"type:date;index:idx_hash_platform_date,priority:3;index:idx_date;index:idx_platform_date,priority:2;index:idx_query,priority:2;"` // 记录日期
+	// 以下字段由日内快照压缩得到，没有快照的日期为 0
+	OpenPrice  float64 `json:"openPrice" currency:"amount"`
+	HighPrice  float64 `json:"highPrice" currency:"amount"`
+	LowPrice   float64 `json:"lowPrice" currency:"amount"`
+	ClosePrice float64 `json:"closePrice" currency:"amount"`
+	Volume     int64   `json:"volume"`
 }
 
 // PriceIncreaseItem 带价格趋势和各时间段涨幅的数据
@@ -29,13 +35,13 @@ type PriceIncreaseItem struct {
 	Name            string   `json:"name" gorm:"column:name"`
 	IconUrl         string   `json:"iconUrl" gorm:"column:icon_url"`
 	Platform        string   `json:"platform" gorm:"column:platform"`
-	TodayPrice      float64  `json:"todayPrice" gorm:"column:today_price"`
-	YesterdayPrice  float64  `json:"yesterdayPrice" gorm:"column:yesterday_price"`
-	Price3DaysAgo   *float64 `json:"price3DaysAgo" gorm:"column:price_3_days_ago"`
-	Price7DaysAgo   *float64 `json:"price7DaysAgo" gorm:"column:price_7_days_ago"`
-	Price15DaysAgo  *float64 `json:"price15DaysAgo" gorm:"column:price_15_days_ago"`
-	Price30DaysAgo  *float64 `json:"price30DaysAgo" gorm:"column:price_30_days_ago"`
-	PriceChange     float64  `json:"priceChange" gorm:"column:price_change"`
+	TodayPrice      float64  `json:"todayPrice" gorm:"column:today_price" currency:"amount"`
+	YesterdayPrice  float64  `json:"yesterdayPrice" gorm:"column:yesterday_price" currency:"amount"`
+	Price3DaysAgo   *float64 `json:"price3DaysAgo" gorm:"column:price_3_days_ago" currency:"amount"`
+	Price7DaysAgo   *float64 `json:"price7DaysAgo" gorm:"column:price_7_days_ago" currency:"amount"`
+	Price15DaysAgo  *float64 `json:"price15DaysAgo" gorm:"column:price_15_days_ago" currency:"amount"`
+	Price30DaysAgo  *float64 `json:"price30DaysAgo" gorm:"column:price_30_days_ago" currency:"amount"`
+	PriceChange     float64  `json:"priceChange" gorm:"column:price_change" currency:"amount"`
 	IncreaseRate1D  float64  `json:"increaseRate1D" gorm:"column:increase_rate_1d"`
 	IncreaseRate3D  *float64 `json:"increaseRate3D" gorm:"column:increase_rate_3d"`
 	IncreaseRate7D  *float64 `json:"increaseRate7D" gorm:"column:increase_rate_7d"`
@@ -64,7 +70,7 @@ func (PriceHistory) TableName() string {
 // PriceHistoryItem 返回给前端的历史数据项
 type PriceHistoryItem struct {
 	Date      string  `json:"date"`
-	SellPrice float64 `json:"sellPrice"`
+	SellPrice float64 `json:"sellPrice" currency:"amount"`
 	SellCount int64   `json:"sellCount"`
 }
 
@@ -76,10 +82,10 @@ type PriceHistoryResponse struct {
 
 // PriceChangeItem 价格变化项
 type PriceChangeItem struct {
-	Label      string  `json:"label"`      // 今日、本周、本月
-	PriceDiff  float64 `json:"priceDiff"`  // 价格差
-	ChangeRate float64 `json:"changeRate"` // 涨跌幅百分比
-	IsUp       bool    `json:"isUp"`       // 是否上涨
+	Label      string  `json:"label"`                       // 今日、本周、本月
+	PriceDiff  float64 `json:"priceDiff" currency:"amount"` // 价格差
+	ChangeRate float64 `json:"changeRate"`                  // 涨跌幅百分比
+	IsUp       bool    `json:"isUp"`                        // 是否上涨
 }
 
 // GoodsDetailResponse 商品详情响应
@@ -92,15 +98,16 @@ type GoodsDetailResponse struct {
 	PriceHistory   map[string][]PriceHistoryItem `json:"priceHistory"` // 所有平台的历史数据，key: 平台名
 	PlatformList   []*GoodsPlatformInfo          `json:"platformList"` // 各平台当前在售信息
 	PriceChange    []PriceChangeItem             `json:"priceChange"`  // 悠悠平台的涨幅信息（今日、本周、本月）
+	Liquidity      map[string]*Liquidity         `json:"liquidity"`    // 各平台流动性估算，key: 平台代码
 }
 
 // GoodsPlatformInfo 各平台在售信息
 type GoodsPlatformInfo struct {
 	Platform     string  `json:"platform"`
 	PlatformName string  `json:"platformName"`
-	SellPrice    float64 `json:"sellPrice"`
+	SellPrice    float64 `json:"sellPrice" currency:"amount"`
 	SellCount    int64   `json:"sellCount"`
-	BiddingPrice float64 `json:"biddingPrice"`
+	BiddingPrice float64 `json:"biddingPrice" currency:"amount"`
 	BiddingCount int64   `json:"biddingCount"`
 	UpdateTime   int64   `json:"updateTime"`
 	Link         string  `json:"link"`
@@ -202,6 +209,7 @@ func GetGoodsDetail(marketHashName string, days int) (*GoodsDetailResponse, erro
 		PriceHistory:   historyResponse.Platforms,
 		PlatformList:   platformList,
 		PriceChange:    priceChange,
+		Liquidity:      GetLiquidityByHashName(marketHashName),
 	}, nil
 }
 
@@ -263,65 +271,22 @@ func calcPriceChangeItem(label, marketHashName string, currentPrice float64, com
 
 // GetAllPlatformInfo 获取指定商品在所有平台的当前在售信息
 func GetAllPlatformInfo(marketHashName string) []*GoodsPlatformInfo {
-	result := make([]*GoodsPlatformInfo, 0, 4)
+	result := make([]*GoodsPlatformInfo, 0, len(Platforms()))
 
-	// 悠悠有品
-	var u U
-	if err := config.DB.Where("market_hash_name = ?", marketHashName).First(&u).Error; err == nil {
-		result = append(result, &GoodsPlatformInfo{
-			Platform:     "YOUPIN",
-			PlatformName: "悠悠有品",
-			SellPrice:    u.SellPrice,
-			SellCount:    u.SellCount,
-			BiddingPrice: u.BiddingPrice,
-			BiddingCount: u.BiddingCount,
-			UpdateTime:   u.UpdateTime,
-			Link:         u.Link,
-		})
-	}
-
-	// BUFF
-	var buff Buff
-	if err := config.DB.Where("market_hash_name = ?", marketHashName).First(&buff).Error; err == nil {
-		result = append(result, &GoodsPlatformInfo{
-			Platform:     "BUFF",
-			PlatformName: "BUFF",
-			SellPrice:    buff.SellPrice,
-			SellCount:    buff.SellCount,
-			BiddingPrice: buff.BiddingPrice,
-			BiddingCount: buff.BiddingCount,
-			UpdateTime:   buff.UpdateTime,
-			Link:         buff.Link,
-		})
-	}
-
-	// C5GAME
-	var c5 C5
-	if err := config.DB.Where("market_hash_name = ?", marketHashName).First(&c5).Error; err == nil {
-		result = append(result, &GoodsPlatformInfo{
-			Platform:     "C5",
-			PlatformName: "C5GAME",
-			SellPrice:    c5.SellPrice,
-			SellCount:    c5.SellCount,
-			BiddingPrice: c5.BiddingPrice,
-			BiddingCount: c5.BiddingCount,
-			UpdateTime:   c5.UpdateTime,
-			Link:         c5.Link,
-		})
-	}
-
-	// Steam
-	var steam Steam
-	if err := config.DB.Where("market_hash_name = ?", marketHashName).First(&steam).Error; err == nil {
+	for _, p := range Platforms() {
+		var row PlatformRow
+		if err := config.DB.Table(p.Table).Where("market_hash_name = ?", marketHashName).First(&row).Error; err != nil {
+			continue
+		}
 		result = append(result, &GoodsPlatformInfo{
-			Platform:     "STEAM",
-			PlatformName: "Steam",
-			SellPrice:    steam.SellPrice,
-			SellCount:    steam.SellCount,
-			BiddingPrice: steam.BiddingPrice,
-			BiddingCount: steam.BiddingCount,
-			UpdateTime:   steam.UpdateTime,
-			Link:         steam.Link,
+			Platform:     p.Code,
+			PlatformName: p.FullName,
+			SellPrice:    row.SellPrice,
+			SellCount:    row.SellCount,
+			BiddingPrice: row.BiddingPrice,
+			BiddingCount: row.BiddingCount,
+			UpdateTime:   row.UpdateTime,
+			Link:         row.Link,
 		})
 	}
 
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"math"
+	"time"
+	"uu/config"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// RiskHoldDays 交易冷却期（买入后需持有的天数）
+	RiskHoldDays = 7
+	// RiskLookbackDays 计算波动率使用的历史天数
+	RiskLookbackDays = 30
+)
+
+// PriceRisk 持有期风险模型（每日根据 price_history 重新计算）
+type PriceRisk struct {
+	MarketHashName string  `json:"market_hash_name" gorm:"type:varchar(255);primaryKey"`
+	Platform       string  `json:"platform" gorm:"type:varchar(20);primaryKey"`
+	Volatility     float64 `json:"volatility"`      // 日收益率标准差
+	ExpectedReturn float64 `json:"expected_return"` // 持有期预期收益率（日均收益率 * 持有天数）
+	MaxDrawdown    float64 `json:"max_drawdown"`    // 持有期内历史最大回撤，0.1 表示 10%
+	Samples        int     `json:"samples"`         // 参与计算的历史记录条数
+	UpdateTime     int64   `json:"update_time"`
+}
+
+// TableName 自定义表名
+func (PriceRisk) TableName() string {
+	return "price_risk"
+}
+
+// calcHoldingRisk 根据按日期升序的价格序列计算持有期风险
+// 返回：日收益率标准差、持有期预期收益率、持有期最大回撤
+func calcHoldingRisk(prices []float64, holdDays int) (float64, float64, float64) {
+	if len(prices) < 2 {
+		return 0, 0, 0
+	}
+
+	returns := make([]float64, 0, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		returns = append(returns, prices[i]/prices[i-1]-1)
+	}
+
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	volatility := math.Sqrt(variance / float64(len(returns)))
+
+	// 每个买入日之后 holdDays 天内的最低价相对买入价的跌幅，取最大值
+	var maxDrawdown float64
+	for i := 0; i < len(prices)-1; i++ {
+		end := i + holdDays
+		if end >= len(prices) {
+			end = len(prices) - 1
+		}
+		for j := i + 1; j <= end; j++ {
+			if dd := 1 - prices[j]/prices[i]; dd > maxDrawdown {
+				maxDrawdown = dd
+			}
+		}
+	}
+
+	return volatility, mean * float64(holdDays), maxDrawdown
+}
+
+// RebuildPriceRisk 根据最近 RiskLookbackDays 天的历史价格重新计算所有商品的持有期风险
+func RebuildPriceRisk() {
+	startDate := getLocalToday().AddDate(0, 0, -RiskLookbackDays)
+	rows, err := config.DB.Model(&PriceHistory{}).
+		Select("market_hash_name, platform, sell_price").
+		Where("record_date >= ? AND sell_price > 0", startDate).
+		Order("platform ASC, market_hash_name ASC, record_date ASC").
+		Rows()
+	if err != nil {
+		config.Log.Errorf("Query price history for risk error: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now().Unix()
+	var risks []*PriceRisk
+	var curName, curPlatform string
+	var prices []float64
+
+	flush := func() {
+		if curName == "" || len(prices) < 2 {
+			return
+		}
+		volatility, expectedReturn, maxDrawdown := calcHoldingRisk(prices, RiskHoldDays)
+		risks = append(risks, &PriceRisk{
+			MarketHashName: curName,
+			Platform:       curPlatform,
+			Volatility:     math.Round(volatility*10000) / 10000,
+			ExpectedReturn: math.Round(expectedReturn*10000) / 10000,
+			MaxDrawdown:    math.Round(maxDrawdown*10000) / 10000,
+			Samples:        len(prices),
+			UpdateTime:     now,
+		})
+	}
+
+	for rows.Next() {
+		var name, platform string
+		var price float64
+		if err := rows.Scan(&name, &platform, &price); err != nil {
+			config.Log.Errorf("Scan price history for risk error: %v", err)
+			return
+		}
+		if name != curName || platform != curPlatform {
+			flush()
+			curName, curPlatform = name, platform
+			prices = prices[:0]
+		}
+		prices = append(prices, price)
+	}
+	flush()
+
+	if len(risks) == 0 {
+		return
+	}
+	err = config.DB.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(risks, 500).Error
+	if err != nil {
+		config.Log.Errorf("Save price risk error: %v", err)
+		return
+	}
+	config.Log.Infof("Rebuilt price risk for %d items", len(risks))
+}
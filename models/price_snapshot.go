@@ -0,0 +1,260 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"uu/config"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PriceSnapshotRetentionDays 日内快照保留天数，更早的快照压缩进 price_history
+const PriceSnapshotRetentionDays = 7
+
+// CandleResolutions 支持的K线周期（秒）
+var CandleResolutions = map[string]int64{
+	"1h": 3600,
+	"4h": 4 * 3600,
+	"1d": 24 * 3600,
+}
+
+// PriceSnapshot 日内价格快照，每轮行情更新只记录价格、在售数或求购发生变化的饰品
+type PriceSnapshot struct {
+	ID             uint64    `gorm:"primaryKey;autoIncrement"`
+	MarketHashName string    `gorm:"type:varchar(255);index:idx_snapshot_query,priority:1;not null"`
+	Platform       string    `gorm:"type:varchar(20);index:idx_snapshot_query,priority:2;not null"`
+	SellPrice      float64   `json:"sellPrice" currency:"amount"`
+	SellCount      int64     `json:"sellCount"`
+	BiddingPrice   float64   `json:"biddingPrice" currency:"amount"`
+	BiddingCount   int64     `json:"biddingCount"`
+	SnapshotTime   time.Time `gorm:"index:idx_snapshot_query,priority:3;index:idx_snapshot_time;not null"`
+}
+
+func (PriceSnapshot) TableName() string {
+	return "price_snapshot"
+}
+
+// Candle K线数据，成交量按相邻快照在售数变化的绝对值累加估算
+type Candle struct {
+	Time      int64   `json:"time"` // 周期开始时间（秒）
+	Open      float64 `json:"open" currency:"amount"`
+	High      float64 `json:"high" currency:"amount"`
+	Low       float64 `json:"low" currency:"amount"`
+	Close     float64 `json:"close" currency:"amount"`
+	Volume    int64   `json:"volume"`
+	SellCount int64   `json:"sellCount"` // 周期结束时的在售数
+}
+
+// BatchCreatePriceSnapshots 批量写入日内快照
+func BatchCreatePriceSnapshots(snapshots []*PriceSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return config.DB.CreateInBatches(snapshots, 500).Error
+}
+
+// bucketStart 按本地时区对齐的周期开始时间
+func bucketStart(t time.Time, resolution int64) int64 {
+	_, offset := t.Zone()
+	return (t.Unix()+int64(offset))/resolution*resolution - int64(offset)
+}
+
+// appendSnapshot 将一条快照并入K线序列，prevCount 为上一条快照的在售数（-1 表示没有）
+func appendSnapshot(candles []Candle, s *PriceSnapshot, resolution int64, prevCount int64) []Candle {
+	var volume int64
+	if prevCount >= 0 {
+		volume = int64(math.Abs(float64(s.SellCount - prevCount)))
+	}
+	start := bucketStart(s.SnapshotTime, resolution)
+	if n := len(candles); n > 0 && candles[n-1].Time == start {
+		c := &candles[n-1]
+		c.High = math.Max(c.High, s.SellPrice)
+		c.Low = math.Min(c.Low, s.SellPrice)
+		c.Close = s.SellPrice
+		c.Volume += volume
+		c.SellCount = s.SellCount
+		return candles
+	}
+	return append(candles, Candle{
+		Time:      start,
+		Open:      s.SellPrice,
+		High:      s.SellPrice,
+		Low:       s.SellPrice,
+		Close:     s.SellPrice,
+		Volume:    volume,
+		SellCount: s.SellCount,
+	})
+}
+
+// historyCandle 由每日历史记录生成日K，未经快照压缩的日期四个价格都取当日记录价
+func historyCandle(h *PriceHistory) Candle {
+	y, m, d := h.RecordDate.Date()
+	c := Candle{
+		Time:      time.Date(y, m, d, 0, 0, 0, 0, time.Local).Unix(),
+		Open:      h.OpenPrice,
+		High:      h.HighPrice,
+		Low:       h.LowPrice,
+		Close:     h.ClosePrice,
+		Volume:    h.Volume,
+		SellCount: h.SellCount,
+	}
+	if c.Close == 0 {
+		c.Open, c.High, c.Low, c.Close = h.SellPrice, h.SellPrice, h.SellPrice, h.SellPrice
+	}
+	return c
+}
+
+// GetPriceCandles 获取饰品在某平台最近N天的K线
+// 1h/4h 只使用日内快照；1d 在快照覆盖范围之前使用 price_history 的每日数据
+func GetPriceCandles(marketHashName, platform, resolution string, days int) ([]Candle, error) {
+	res, ok := CandleResolutions[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unsupported resolution: %s", resolution)
+	}
+	since := getLocalToday().AddDate(0, 0, -days+1)
+
+	var snapshots []PriceSnapshot
+	err := config.DB.Where("market_hash_name = ? AND platform = ? AND snapshot_time >= ?", marketHashName, platform, since).
+		Order("snapshot_time ASC").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]Candle, 0)
+	if resolution == "1d" {
+		historyEnd := getLocalToday().AddDate(0, 0, 1)
+		if len(snapshots) > 0 {
+			t := snapshots[0].SnapshotTime.In(time.Local)
+			historyEnd = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		}
+		var histories []PriceHistory
+		err = config.DB.Where("market_hash_name = ? AND platform = ? AND record_date >= ? AND record_date < ?",
+			marketHashName, platform, since, historyEnd).
+			Order("record_date ASC").
+			Find(&histories).Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range histories {
+			candles = append(candles, historyCandle(&histories[i]))
+		}
+	}
+
+	prevCount := int64(-1)
+	for i := range snapshots {
+		candles = appendSnapshot(candles, &snapshots[i], res, prevCount)
+		prevCount = snapshots[i].SellCount
+	}
+	return candles, nil
+}
+
+// CompactPriceSnapshots 将超过保留天数的日内快照按天压缩为 OHLC 写入 price_history，然后删除
+func CompactPriceSnapshots(retentionDays int) {
+	cutoff := getLocalToday().AddDate(0, 0, -retentionDays)
+	for {
+		var oldest PriceSnapshot
+		err := config.DB.Where("snapshot_time < ?", cutoff).Order("snapshot_time ASC").First(&oldest).Error
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				config.Log.Errorf("Find oldest price snapshot error: %v", err)
+			}
+			return
+		}
+		t := oldest.SnapshotTime.In(time.Local)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		if err := compactSnapshotDay(day); err != nil {
+			config.Log.Errorf("Compact price snapshots of %s error: %v", day.Format("2006-01-02"), err)
+			return
+		}
+	}
+}
+
+// compactSnapshotDay 压缩某一天的快照：已有当日历史记录的补充 OHLC，没有的新建一条
+func compactSnapshotDay(day time.Time) error {
+	next := day.AddDate(0, 0, 1)
+
+	type dayKey struct{ hashName, platform string }
+	candles := make(map[dayKey][]Candle)
+	prevCounts := make(map[dayKey]int64)
+
+	var batch []PriceSnapshot
+	err := config.DB.Where("snapshot_time >= ? AND snapshot_time < ?", day, next).
+		FindInBatches(&batch, 5000, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				key := dayKey{batch[i].MarketHashName, batch[i].Platform}
+				prevCount, ok := prevCounts[key]
+				if !ok {
+					prevCount = -1
+				}
+				candles[key] = appendSnapshot(candles[key], &batch[i], CandleResolutions["1d"], prevCount)
+				prevCounts[key] = batch[i].SellCount
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	var existing []PriceHistory
+	if err := config.DB.Where("record_date = ?", day.Format("2006-01-02")).Find(&existing).Error; err != nil {
+		return err
+	}
+	existingMap := make(map[dayKey]*PriceHistory, len(existing))
+	for i := range existing {
+		existingMap[dayKey{existing[i].MarketHashName, existing[i].Platform}] = &existing[i]
+	}
+
+	var updates, inserts []*PriceHistory
+	for key, list := range candles {
+		c := list[len(list)-1]
+		h := existingMap[key]
+		if h == nil {
+			h = &PriceHistory{
+				MarketHashName: key.hashName,
+				Platform:       key.platform,
+				SellPrice:      c.Close,
+				SellCount:      c.SellCount,
+				RecordDate:     day,
+			}
+			inserts = append(inserts, h)
+		} else {
+			updates = append(updates, h)
+		}
+		h.OpenPrice, h.HighPrice, h.LowPrice, h.ClosePrice, h.Volume = c.Open, c.High, c.Low, c.Close, c.Volume
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.AssignmentColumns([]string{"open_price", "high_price", "low_price", "close_price", "volume"}),
+			}).CreateInBatches(updates, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(inserts) > 0 {
+			return tx.CreateInBatches(inserts, 500).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 分批删除，避免长时间锁表
+	for {
+		result := config.DB.Where("snapshot_time >= ? AND snapshot_time < ?", day, next).Limit(10000).Delete(&PriceSnapshot{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 10000 {
+			break
+		}
+	}
+	config.Log.Infof("Compacted %d price snapshot series of %s", len(candles), day.Format("2006-01-02"))
+	return nil
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+	"uu/config"
+
+	"gorm.io/gorm"
+)
+
+// 扫描状态
+const (
+	SweepRunning   = "running"
+	SweepCompleted = "completed"
+)
+
+// 批次状态
+const (
+	BatchPending = "pending" // 待获取，失败后等待重试的批次也是该状态
+	BatchFetched = "fetched" // 已获取报价，尚未写入平台表
+	BatchMerged  = "merged"  // 已写入平台表
+	BatchFailed  = "failed"  // 重试次数用尽，本轮扫描跳过
+)
+
+// SweepBatchMaxAttempts 单个批次在一轮扫描中最多获取的次数
+const SweepBatchMaxAttempts = 3
+
+// PriceSweep 一轮全量行情扫描，开始时固定饰品列表并拆分为批次，中断后从未完成的批次继续
+type PriceSweep struct {
+	ID        uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Status    string     `json:"status" gorm:"type:varchar(10);index"` // running / completed
+	Total     int        `json:"total"`                                // 饰品数
+	BatchSize int        `json:"batch_size"`
+	Batches   int        `json:"batches"`
+	Cursor    int        `json:"cursor"` // 已处理到的批次序号（不含）
+	Merged    int        `json:"merged"` // 已写入的批次数
+	Failed    int        `json:"failed"` // 重试用尽的批次数
+	Items     int        `json:"items"`  // 已写入报价的饰品数
+	StartTime time.Time  `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// PriceSweepBatch 扫描中的一个批次
+type PriceSweepBatch struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	SweepID   uint64 `gorm:"uniqueIndex:idx_sweep_batch"`
+	Idx       int    `gorm:"uniqueIndex:idx_sweep_batch"` // 批次序号
+	Status    string `gorm:"type:varchar(10)"`
+	Attempts  int    // 已获取次数
+	Error     string `gorm:"type:varchar(255)"`
+	HashNames string `gorm:"type:mediumtext"` // JSON 数组
+	Quotes    string `gorm:"type:mediumtext"` // fetched 状态暂存的报价 JSON，写入后清空
+	Items     int    // 写入报价的饰品数
+	UpdatedAt time.Time
+}
+
+// Names 批次的饰品列表
+func (b *PriceSweepBatch) Names() []string {
+	var names []string
+	if err := json.Unmarshal([]byte(b.HashNames), &names); err != nil {
+		config.Log.Errorf("Unmarshal sweep batch %d names error: %v", b.ID, err)
+	}
+	return names
+}
+
+// GetRunningSweep 当前未完成的扫描，没有时返回 nil
+func GetRunningSweep() (*PriceSweep, error) {
+	var sweeps []PriceSweep
+	err := config.DB.Where("status = ?", SweepRunning).Order("id DESC").Limit(1).Find(&sweeps).Error
+	if err != nil || len(sweeps) == 0 {
+		return nil, err
+	}
+	return &sweeps[0], nil
+}
+
+// GetLastCompletedSweep 最近一次完成的扫描，没有时返回 nil
+func GetLastCompletedSweep() (*PriceSweep, error) {
+	var sweeps []PriceSweep
+	err := config.DB.Where("status = ?", SweepCompleted).Order("id DESC").Limit(1).Find(&sweeps).Error
+	if err != nil || len(sweeps) == 0 {
+		return nil, err
+	}
+	return &sweeps[0], nil
+}
+
+// CreateSweep 按批次大小拆分饰品列表，创建一轮新的扫描
+func CreateSweep(hashNames []string, batchSize int) (*PriceSweep, error) {
+	n := (len(hashNames) + batchSize - 1) / batchSize
+	sweep := &PriceSweep{Status: SweepRunning, Total: len(hashNames), BatchSize: batchSize, Batches: n, StartTime: time.Now()}
+	batches := make([]*PriceSweepBatch, 0, n)
+	for i := 0; i < n; i++ {
+		end := min((i+1)*batchSize, len(hashNames))
+		names, _ := json.Marshal(hashNames[i*batchSize : end])
+		batches = append(batches, &PriceSweepBatch{Idx: i, Status: BatchPending, HashNames: string(names)})
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(sweep).Error; err != nil {
+			return err
+		}
+		for _, b := range batches {
+			b.SweepID = sweep.ID
+		}
+		if len(batches) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(batches, 100).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sweep, nil
+}
+
+// GetFetchedBatches 已获取但未写入的批次，上次运行在写入前中断时存在
+func GetFetchedBatches(sweepID uint64) ([]*PriceSweepBatch, error) {
+	var batches []*PriceSweepBatch
+	err := config.DB.Where("sweep_id = ? AND status = ?", sweepID, BatchFetched).Order("idx").Find(&batches).Error
+	return batches, err
+}
+
+// NextSweepBatch 下一个待获取的批次，先按序号走完首次获取，再重试失败过的批次；没有时返回 nil
+func NextSweepBatch(sweepID uint64) (*PriceSweepBatch, error) {
+	var batches []*PriceSweepBatch
+	err := config.DB.Where("sweep_id = ? AND status = ?", sweepID, BatchPending).
+		Order("attempts, idx").Limit(1).Find(&batches).Error
+	if err != nil || len(batches) == 0 {
+		return nil, err
+	}
+	return batches[0], nil
+}
+
+// MarkBatchFetched 暂存批次获取到的报价
+func MarkBatchFetched(batch *PriceSweepBatch, quotes string) error {
+	batch.Status = BatchFetched
+	batch.Attempts++
+	batch.Error = ""
+	batch.Quotes = quotes
+	return config.DB.Model(batch).Select("status", "attempts", "error", "quotes").Updates(batch).Error
+}
+
+// MarkBatchMerged 批次报价已写入平台表，更新扫描进度
+func MarkBatchMerged(sweep *PriceSweep, batch *PriceSweepBatch, items int) error {
+	batch.Status = BatchMerged
+	batch.Quotes = ""
+	batch.Items = items
+	sweep.Merged++
+	sweep.Items += items
+	sweep.Cursor = max(sweep.Cursor, batch.Idx+1)
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(batch).Select("status", "quotes", "items").Updates(batch).Error; err != nil {
+			return err
+		}
+		return tx.Model(sweep).Select("merged", "items", "cursor").Updates(sweep).Error
+	})
+}
+
+// FailSweepBatch 记录批次获取失败，未达到重试上限时回到 pending 等待重试
+func FailSweepBatch(sweep *PriceSweep, batch *PriceSweepBatch, cause error) error {
+	batch.Attempts++
+	batch.Error = cause.Error()
+	if len(batch.Error) > 255 {
+		batch.Error = batch.Error[:255]
+	}
+	batch.Status = BatchPending
+	if batch.Attempts >= SweepBatchMaxAttempts {
+		batch.Status = BatchFailed
+		sweep.Failed++
+	}
+	sweep.Cursor = max(sweep.Cursor, batch.Idx+1)
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(batch).Select("status", "attempts", "error").Updates(batch).Error; err != nil {
+			return err
+		}
+		return tx.Model(sweep).Select("failed", "cursor").Updates(sweep).Error
+	})
+}
+
+// CompleteSweep 结束扫描，删除批次明细和超过保留天数的扫描记录
+func CompleteSweep(sweep *PriceSweep) error {
+	now := time.Now()
+	sweep.Status = SweepCompleted
+	sweep.EndTime = &now
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(sweep).Select("status", "end_time").Updates(sweep).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("sweep_id = ?", sweep.ID).Delete(&PriceSweepBatch{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("status = ? AND start_time < ?", SweepCompleted, now.AddDate(0, 0, -JobRunRetainDays)).
+			Delete(&PriceSweep{}).Error
+	})
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 刷新档位
+const (
+	RefreshHot  = "hot"
+	RefreshWarm = "warm"
+	RefreshCold = "cold"
+)
+
+// 档位阈值（优先级分数）
+const (
+	refreshHotScore  = 70
+	refreshWarmScore = 40
+)
+
+// 优先级各指标权重，指标先按在全部饰品中的百分位归一化
+const (
+	weightVolume     = 0.35
+	weightSpread     = 0.25
+	weightVolatility = 0.15
+	weightInterest   = 0.25
+)
+
+// RefreshPriority 饰品的刷新优先级，成交活跃、价差大、波动大、用户关注的饰品刷新更频繁
+type RefreshPriority struct {
+	MarketHashName string  `json:"market_hash_name" gorm:"type:varchar(255);primaryKey"`
+	Score          float64 `json:"score" gorm:"index"`           // 0~100
+	Tier           string  `json:"tier" gorm:"type:varchar(10)"` // hot / warm / cold
+	Volume         float64 `json:"volume"`                       // 各平台估算日成交量之和
+	Spread         float64 `json:"spread"`                       // 各平台在售价的最大价差率
+	Volatility     float64 `json:"volatility"`                   // 各平台日收益率标准差的最大值
+	Interest       int     `json:"interest"`                     // 自选、持仓、启用的预警数量
+	UpdateTime     int64   `json:"update_time"`
+}
+
+// TableName 自定义表名
+func (RefreshPriority) TableName() string {
+	return "refresh_priority"
+}
+
+// hashNameValue 按饰品聚合的指标
+type hashNameValue struct {
+	MarketHashName string
+	Value          float64
+}
+
+// RebuildRefreshPriorities 根据流动性、跨平台价差、波动率和用户关注重新计算所有饰品的刷新优先级，返回饰品数
+func RebuildRefreshPriorities() (int, error) {
+	names, err := GetHashNames()
+	if err != nil {
+		return 0, fmt.Errorf("get hash names: %w", err)
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	volume, err := groupByHashName(config.DB.Model(&Liquidity{}).Select("market_hash_name, SUM(sales_per_day) AS value"))
+	if err != nil {
+		return 0, fmt.Errorf("get liquidity: %w", err)
+	}
+	volatility, err := groupByHashName(config.DB.Model(&PriceRisk{}).Select("market_hash_name, MAX(volatility) AS value"))
+	if err != nil {
+		return 0, fmt.Errorf("get price risk: %w", err)
+	}
+	interest := make(map[string]float64)
+	for _, q := range []struct {
+		model interface{}
+		where string
+	}{
+		{&Watchlist{}, ""},
+		{&Holding{}, ""},
+		{&PriceAlert{}, "enabled = true"},
+	} {
+		db := config.DB.Model(q.model).Select("market_hash_name, COUNT(*) AS value")
+		if q.where != "" {
+			db = db.Where(q.where)
+		}
+		counts, err := groupByHashName(db)
+		if err != nil {
+			return 0, fmt.Errorf("get user interest: %w", err)
+		}
+		for name, n := range counts {
+			interest[name] += n
+		}
+	}
+	spread := crossPlatformSpread()
+
+	volumeRank := percentileRanks(volume, names)
+	spreadRank := percentileRanks(spread, names)
+	volatilityRank := percentileRanks(volatility, names)
+	interestRank := percentileRanks(interest, names)
+
+	now := time.Now().Unix()
+	result := make([]*RefreshPriority, 0, len(names))
+	for _, name := range names {
+		score := 100 * (weightVolume*volumeRank[name] + weightSpread*spreadRank[name] +
+			weightVolatility*volatilityRank[name] + weightInterest*interestRank[name])
+		score = math.Round(score*100) / 100
+		result = append(result, &RefreshPriority{
+			MarketHashName: name,
+			Score:          score,
+			Tier:           refreshTier(score, interest[name] > 0),
+			Volume:         volume[name],
+			Spread:         math.Round(spread[name]*10000) / 10000,
+			Volatility:     volatility[name],
+			Interest:       int(interest[name]),
+			UpdateTime:     now,
+		})
+	}
+
+	err = config.DB.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(result, 500).Error
+	if err != nil {
+		return 0, fmt.Errorf("save refresh priority: %w", err)
+	}
+	// 已删除的饰品
+	config.DB.Where("update_time < ?", now).Delete(&RefreshPriority{})
+	config.Log.Infof("Rebuilt refresh priority for %d items", len(result))
+	return len(result), nil
+}
+
+// GetAllRefreshPriorities 所有饰品的刷新优先级，key: market_hash_name
+func GetAllRefreshPriorities() (map[string]*RefreshPriority, error) {
+	var list []*RefreshPriority
+	if err := config.DB.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]*RefreshPriority, len(list))
+	for _, p := range list {
+		result[p.MarketHashName] = p
+	}
+	return result, nil
+}
+
+// GetRefreshPriorities 按分数从高到低分页获取刷新优先级，tier 为空不限制
+func GetRefreshPriorities(tier string, pageSize, pageNum int) ([]RefreshPriority, int64, int) {
+	var list []RefreshPriority
+	var total int64
+	db := config.DB.Model(&RefreshPriority{})
+	if tier != "" {
+		db = db.Where("tier = ?", tier)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		config.Log.Errorf("Get refresh priority total error: %v", err)
+		return nil, 0, utils.ErrCodeGetRefreshPriority
+	}
+	err := db.Order("score DESC").Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&list).Error
+	if err != nil {
+		config.Log.Errorf("Get refresh priority error: %v", err)
+		return nil, 0, utils.ErrCodeGetRefreshPriority
+	}
+	return list, total, utils.SUCCESS
+}
+
+// refreshTier 按分数划分档位，有用户关注的饰品至少为 warm
+func refreshTier(score float64, watched bool) string {
+	switch {
+	case score >= refreshHotScore:
+		return RefreshHot
+	case score >= refreshWarmScore || watched:
+		return RefreshWarm
+	default:
+		return RefreshCold
+	}
+}
+
+// groupByHashName 执行按 market_hash_name 分组的聚合查询，查询需选出 value 列
+func groupByHashName(db *gorm.DB) (map[string]float64, error) {
+	var rows []hashNameValue
+	if err := db.Group("market_hash_name").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		result[r.MarketHashName] = r.Value
+	}
+	return result, nil
+}
+
+// crossPlatformSpread 各饰品在所有平台在售价（已换算为人民币）的最大价差率 (max - min) / min
+func crossPlatformSpread() map[string]float64 {
+	minPrice := make(map[string]float64)
+	maxPrice := make(map[string]float64)
+	for _, p := range Platforms() {
+		var rows []hashNameValue
+		err := config.DB.Table(p.Table).Select("market_hash_name, sell_price AS value").Where("sell_price > 0").Scan(&rows).Error
+		if err != nil {
+			config.Log.Errorf("Get %s prices for refresh priority error: %v", p.Code, err)
+			continue
+		}
+		for _, r := range rows {
+			if v, ok := minPrice[r.MarketHashName]; !ok || r.Value < v {
+				minPrice[r.MarketHashName] = r.Value
+			}
+			if r.Value > maxPrice[r.MarketHashName] {
+				maxPrice[r.MarketHashName] = r.Value
+			}
+		}
+	}
+	result := make(map[string]float64, len(minPrice))
+	for name, low := range minPrice {
+		result[name] = (maxPrice[name] - low) / low
+	}
+	return result
+}
+
+// percentileRanks 各饰品的指标在全部饰品中的百分位（严格小于它的饰品占比），没有指标的按 0 计
+func percentileRanks(values map[string]float64, names []string) map[string]float64 {
+	sorted := make([]float64, len(names))
+	for i, name := range names {
+		sorted[i] = values[name]
+	}
+	sort.Float64s(sorted)
+	result := make(map[string]float64, len(names))
+	for _, name := range names {
+		result[name] = float64(sort.SearchFloat64s(sorted, values[name])) / float64(len(names))
+	}
+	return result
+}
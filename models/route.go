@@ -0,0 +1,309 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/utils"
+)
+
+// 买卖方式：sell 按在售价（买入即扫最低挂单，卖出即上架），bidding 按求购价（买入即挂求购，卖出即卖给求购）
+const (
+	PriceTypeSell    = "sell"
+	PriceTypeBidding = "bidding"
+)
+
+// RouteLeg 路线中的一步：在哪个平台以什么方式成交
+type RouteLeg struct {
+	Platform     string  `json:"platform"` // 平台标识，如 uu
+	PlatformName string  `json:"platform_name"`
+	PriceType    string  `json:"price_type"` // sell / bidding
+	Price        float64 `json:"price" currency:"amount"`
+	Count        int64   `json:"count"` // 对应的在售数或求购数
+}
+
+// ArbitrageRoute 某饰品扣除手续费后最优的搬砖路线
+type ArbitrageRoute struct {
+	MarketHashName string   `json:"market_hash_name"`
+	Name           string   `json:"name"`
+	ImageUrl       string   `json:"image_url"`
+	TypeName       string   `json:"type_name"`
+	Buy            RouteLeg `json:"buy"`
+	Sell           RouteLeg `json:"sell"`
+	PriceDiff      float64  `json:"price_diff" currency:"amount"`   // 卖出价 - 买入价
+	NetProceeds    float64  `json:"net_proceeds" currency:"amount"` // 卖出平台扣除手续费后的到手金额
+	NetProfit      float64  `json:"net_profit" currency:"amount"`   // 到手金额 - 买入价
+	NetProfitRate  float64  `json:"net_profit_rate"`                // 净利润 / 买入价
+	RouteCount     int      `json:"route_count"`                    // 满足条件的路线数量
+}
+
+// RouteQuery 路线查询条件
+type RouteQuery struct {
+	UserId    string
+	ProfileId uint // 设置方案ID，为 0 使用默认方案
+	PageSize  int
+	PageNum   int
+	IsDesc    bool
+	SortField string // net_profit / net_profit_rate
+	Search    string
+	Category  string
+	Platforms []string // 参与的平台标识，为空为全部平台
+	BuyTypes  []string // 参与的买入方式，为空为 sell 和 bidding
+	SellTypes []string // 参与的卖出方式，为空为 sell 和 bidding
+
+	MinNetProfit     *float64
+	MinNetProfitRate *float64
+	Watchlist        bool
+	ExcludeAnomaly   bool   // 跳过报价被标记为异常的平台
+	MaxAge           *int64 // 行情最大有效时长（秒），为空使用各平台配置
+	ExcludeStale     bool   // 跳过行情过期的平台
+}
+
+// routeRowsTTL 路线计算使用的平台行情缓存时长，与行情更新周期相当
+const routeRowsTTL = time.Minute
+
+// 各平台行情缓存，key: 平台代码 -> market_hash_name
+var routeRows struct {
+	mu       sync.Mutex
+	loadedAt time.Time
+	rows     map[string]map[string]*PlatformRow
+}
+
+// loadRouteRows 读取所有平台有价格的行情，一分钟内复用
+func loadRouteRows() map[string]map[string]*PlatformRow {
+	routeRows.mu.Lock()
+	defer routeRows.mu.Unlock()
+	if routeRows.rows != nil && time.Since(routeRows.loadedAt) < routeRowsTTL {
+		return routeRows.rows
+	}
+	result := make(map[string]map[string]*PlatformRow, len(Platforms()))
+	for _, p := range Platforms() {
+		var rows []PlatformRow
+		err := config.DB.Table(p.Table).Where("sell_price > 0 OR bidding_price > 0").Find(&rows).Error
+		if err != nil {
+			config.Log.Errorf("Load %s rows for route error: %v", p.Code, err)
+			continue
+		}
+		m := make(map[string]*PlatformRow, len(rows))
+		for i := range rows {
+			m[rows[i].MarketHashName] = &rows[i]
+		}
+		result[p.Code] = m
+	}
+	routeRows.rows = result
+	routeRows.loadedAt = time.Now()
+	return result
+}
+
+// legPrice 某平台按指定方式成交的价格和数量，不可用时返回 false
+func legPrice(p *PlatformDef, row *PlatformRow, priceType string) (float64, int64, bool) {
+	if priceType == PriceTypeBidding {
+		if !p.SupportsBidding || row.BiddingPrice <= 0 || row.BiddingCount <= 0 {
+			return 0, 0, false
+		}
+		return row.BiddingPrice, row.BiddingCount, true
+	}
+	if row.SellPrice <= 0 || row.SellCount <= 0 {
+		return 0, 0, false
+	}
+	return row.SellPrice, row.SellCount, true
+}
+
+// routeFilterNames 按搜索、类别、自选得到允许的饰品集合，都未指定时返回 nil 表示不限制
+func routeFilterNames(q *RouteQuery) (map[string]bool, error) {
+	if q.Search == "" && q.Category == "" && !q.Watchlist {
+		return nil, nil
+	}
+	db := config.DB.Table("base_goods").Select("base_goods.market_hash_name")
+	if q.Search != "" {
+		db = db.Where("base_goods.name LIKE ?", "%"+q.Search+"%")
+	}
+	if q.Category != "" {
+		db = db.Joins("JOIN u_base_info ON base_goods.market_hash_name = u_base_info.hash_name").
+			Where("u_base_info.type_name IN ?", strings.Split(q.Category, ","))
+	}
+	if q.Watchlist {
+		db = db.Where(watchlistFilter("base_goods"), q.UserId)
+	}
+	var names []string
+	if err := db.Pluck("base_goods.market_hash_name", &names).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(names))
+	for _, name := range names {
+		result[name] = true
+	}
+	return result, nil
+}
+
+// FindArbitrageRoutes 对每个饰品评估所有平台组合和买卖方式，返回扣除手续费后净利润率最高的路线
+// 用户设置：买入价在 [MinSellPrice, MaxSellPrice) 之间，卖出平台在售数 > MinSellNum，差价 > MinDiff
+func FindArbitrageRoutes(q *RouteQuery) ([]*ArbitrageRoute, int64, int) {
+	settings, code := GetUserSetting(q.UserId)
+	if q.ProfileId > 0 {
+		settings, code = GetUserSettingProfile(q.UserId, q.ProfileId)
+		if code != utils.SUCCESS {
+			return nil, 0, code
+		}
+	}
+
+	platforms := Platforms()
+	if len(q.Platforms) > 0 {
+		platforms = nil
+		for _, key := range q.Platforms {
+			if p, ok := GetPlatformByKey(key); ok {
+				platforms = append(platforms, p)
+			}
+		}
+	}
+	buyTypes, sellTypes := q.BuyTypes, q.SellTypes
+	if len(buyTypes) == 0 {
+		buyTypes = []string{PriceTypeSell, PriceTypeBidding}
+	}
+	if len(sellTypes) == 0 {
+		sellTypes = []string{PriceTypeSell, PriceTypeBidding}
+	}
+
+	allowed, err := routeFilterNames(q)
+	if err != nil {
+		config.Log.Errorf("Filter route goods error: %v", err)
+		return nil, 0, utils.ErrCodeGetGoods
+	}
+
+	fees := make(map[string]*PlatformFee, len(platforms))
+	cutoffs := make(map[string]int64, len(platforms))
+	for _, p := range platforms {
+		fees[p.Code] = p.Fee()
+		if q.ExcludeStale {
+			cutoffs[p.Code] = staleCutoff(p, q.MaxAge)
+		}
+	}
+
+	rows := loadRouteRows()
+	best := make(map[string]*ArbitrageRoute)
+	for _, source := range platforms {
+		for name, sourceRow := range rows[source.Code] {
+			if allowed != nil && !allowed[name] {
+				continue
+			}
+			if (q.ExcludeAnomaly && sourceRow.Anomaly != "") || sourceRow.UpdateTime < cutoffs[source.Code] {
+				continue
+			}
+			for _, buyType := range buyTypes {
+				buyPrice, buyCount, ok := legPrice(source, sourceRow, buyType)
+				if !ok || buyPrice < settings.MinSellPrice || buyPrice >= settings.MaxSellPrice {
+					continue
+				}
+				for _, target := range platforms {
+					if target.Code == source.Code {
+						continue
+					}
+					targetRow := rows[target.Code][name]
+					if targetRow == nil || targetRow.SellCount <= int64(settings.MinSellNum) ||
+						(q.ExcludeAnomaly && targetRow.Anomaly != "") || targetRow.UpdateTime < cutoffs[target.Code] {
+						continue
+					}
+					for _, sellType := range sellTypes {
+						sellPrice, sellCount, ok := legPrice(target, targetRow, sellType)
+						if !ok || sellPrice-buyPrice <= settings.MinDiff {
+							continue
+						}
+						proceeds := fees[target.Code].NetProceeds(sellPrice)
+						netProfit := proceeds - buyPrice
+						netProfitRate := netProfit / buyPrice
+						if q.MinNetProfit != nil && netProfit < *q.MinNetProfit {
+							continue
+						}
+						if q.MinNetProfitRate != nil && netProfitRate < *q.MinNetProfitRate {
+							continue
+						}
+
+						route := best[name]
+						if route == nil {
+							route = &ArbitrageRoute{MarketHashName: name, NetProfitRate: math.Inf(-1)}
+							best[name] = route
+						}
+						route.RouteCount++
+						if netProfitRate <= route.NetProfitRate {
+							continue
+						}
+						route.Buy = RouteLeg{Platform: source.Key, PlatformName: source.Name, PriceType: buyType, Price: buyPrice, Count: buyCount}
+						route.Sell = RouteLeg{Platform: target.Key, PlatformName: target.Name, PriceType: sellType, Price: sellPrice, Count: sellCount}
+						route.PriceDiff = math.Round((sellPrice-buyPrice)*100) / 100
+						route.NetProceeds = math.Round(proceeds*100) / 100
+						route.NetProfit = math.Round(netProfit*100) / 100
+						route.NetProfitRate = netProfitRate
+					}
+				}
+			}
+		}
+	}
+
+	routes := make([]*ArbitrageRoute, 0, len(best))
+	for _, r := range best {
+		r.NetProfitRate = math.Round(r.NetProfitRate*10000) / 10000
+		routes = append(routes, r)
+	}
+	sortRoutes(routes, q.SortField, q.IsDesc)
+
+	total := int64(len(routes))
+	start := min(max((q.PageNum-1)*q.PageSize, 0), len(routes))
+	end := min(max(start+q.PageSize, start), len(routes))
+	page := routes[start:end]
+	fillRouteGoodsInfo(page)
+	return page, total, code
+}
+
+// sortRoutes 按净利润或净利润率排序，相同时按名称保证分页稳定
+func sortRoutes(routes []*ArbitrageRoute, field string, desc bool) {
+	value := func(r *ArbitrageRoute) float64 { return r.NetProfitRate }
+	if field == "net_profit" {
+		value = func(r *ArbitrageRoute) float64 { return r.NetProfit }
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		vi, vj := value(routes[i]), value(routes[j])
+		if vi != vj {
+			if desc {
+				return vi > vj
+			}
+			return vi < vj
+		}
+		return routes[i].MarketHashName < routes[j].MarketHashName
+	})
+}
+
+// fillRouteGoodsInfo 补充当前页饰品的名称、图标和类别
+func fillRouteGoodsInfo(routes []*ArbitrageRoute) {
+	if len(routes) == 0 {
+		return
+	}
+	hashNames := make([]string, 0, len(routes))
+	for _, r := range routes {
+		hashNames = append(hashNames, r.MarketHashName)
+	}
+	var infos []struct {
+		MarketHashName string
+		Name           string
+		IconUrl        string
+		TypeName       string
+	}
+	config.DB.Table("base_goods").
+		Select("base_goods.market_hash_name, base_goods.name, base_goods.icon_url, u_base_info.type_name").
+		Joins("LEFT JOIN u_base_info ON base_goods.market_hash_name = u_base_info.hash_name").
+		Where("base_goods.market_hash_name IN ?", hashNames).
+		Scan(&infos)
+	infoMap := make(map[string]int, len(infos))
+	for i := range infos {
+		infoMap[infos[i].MarketHashName] = i
+	}
+	for _, r := range routes {
+		if i, ok := infoMap[r.MarketHashName]; ok {
+			r.Name = infos[i].Name
+			r.ImageUrl = infos[i].IconUrl
+			r.TypeName = infos[i].TypeName
+		}
+	}
+}
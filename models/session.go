@@ -2,15 +2,23 @@ package models
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 	"uu/config"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 const tokenVersionKeyPrefix = "user_token_version:"
 
+const streamTicketKeyPrefix = "stream_ticket:"
+
+// StreamTicketTTL 推送连接票据的有效期
+const StreamTicketTTL = 30 * time.Second
+
 // 客户端类型常量
 const (
 	ClientTypeWeb         = "web"
@@ -57,3 +65,29 @@ func InvalidateAllTokenVersions(ctx context.Context, userID uuid.UUID) error {
 	miniprogramKey := fmt.Sprintf("%s%s:%s", tokenVersionKeyPrefix, ClientTypeMiniprogram, userID.String())
 	return config.RDB.Del(ctx, webKey, miniprogramKey).Err()
 }
+
+// CreateStreamTicket 生成一次性的推送连接票据，票据对应当前登录的 JWT
+// 浏览器 EventSource 只能通过 URL 传参，用短期票据代替 JWT，避免 JWT 出现在访问日志中
+func CreateStreamTicket(ctx context.Context, token string) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(b)
+	return ticket, config.RDB.Set(ctx, streamTicketKeyPrefix+ticket, token, StreamTicketTTL).Err()
+}
+
+// ConsumeStreamTicket 取出票据对应的 JWT 并删除票据，票据不存在、已过期或已使用时返回空
+func ConsumeStreamTicket(ctx context.Context, ticket string) string {
+	key := streamTicketKeyPrefix + ticket
+	var get *redis.StringCmd
+	_, err := config.RDB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+	return get.Val()
+}
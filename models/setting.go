@@ -1,7 +1,10 @@
 package models
 
 import (
+	"context"
+	"errors"
 	"gorm.io/gorm"
+	"time"
 	"uu/config"
 	"uu/utils"
 )
@@ -52,38 +55,99 @@ import (
 //	return nil
 //}
 
+const (
+	// DefaultProfileName 默认设置方案名称，原有的单一设置迁移为该方案
+	DefaultProfileName = "默认"
+	// MaxSettingProfiles 每个用户最多的设置方案数量
+	MaxSettingProfiles = 20
+)
+
+// Settings 用户的搬砖设置方案，每个用户可以有多个方案，其中一个为默认方案
 type Settings struct {
 	gorm.Model
-	UserId       string  `json:"user_id" gorm:"type:char(36);uniqueIndex:idx_user_setting"`
+	UserId       string  `json:"user_id" gorm:"type:char(36);index"`
+	Name         string  `json:"name" gorm:"type:varchar(50)"`
+	IsDefault    bool    `json:"is_default"`
 	MinSellNum   int     `json:"min_sell_num" gorm:"default:100"`
-	MinDiff      float64 `json:"min_diff" gorm:"default:0.8"`
 	MaxSellPrice float64 `json:"max_sell_price" gorm:"default:10000"`
+	MinDiff      float64 `json:"min_diff" gorm:"default:0.8"`
 	MinSellPrice float64 `json:"min_sell_price" gorm:"default:0"`
+
+	MinProfitRate  float64 `json:"min_profit_rate"`                         // 最小利润率，0 不限制
+	Categories     string  `json:"categories" gorm:"type:varchar(255)"`     // 饰品类别，逗号分隔，为空不限制
+	SourcePlatform string  `json:"source_platform" gorm:"type:varchar(20)"` // 来源平台标识，为空使用请求参数
+	TargetPlatform string  `json:"target_platform" gorm:"type:varchar(20)"` // 目标平台标识，为空使用请求参数
+	BuyType        string  `json:"buy_type" gorm:"type:varchar(10)"`        // sell / bidding，为空使用请求参数
+	SellType       string  `json:"sell_type" gorm:"type:varchar(10)"`       // sell / bidding，为空使用请求参数
+	MinLiquidity   float64 `json:"min_liquidity"`                           // 目标平台最小估算日成交量，0 不限制
+
+	SteamRatioThreshold float64 `json:"steam_ratio_threshold"`                   // 挂刀比例阈值（Steam 到手余额 / 买入价），0 不限制
+	DisplayCurrency     string  `json:"display_currency" gorm:"type:varchar(3)"` // 接口返回价格的展示币种，为空为人民币
 }
 
 type SettingsResponse struct {
-	UserId       string  `json:"user_id"`
-	MinSellNum   int     `json:"min_sell_num"`
-	MinDiff      float64 `json:"min_diff"`
-	MaxSellPrice float64 `json:"max_sell_price"`
-	MinSellPrice float64 `json:"min_sell_price"`
+	ID             uint    `json:"id"`
+	UserId         string  `json:"user_id"`
+	Name           string  `json:"name"`
+	IsDefault      bool    `json:"is_default"`
+	MinSellNum     int     `json:"min_sell_num"`
+	MinDiff        float64 `json:"min_diff"`
+	MaxSellPrice   float64 `json:"max_sell_price"`
+	MinSellPrice   float64 `json:"min_sell_price"`
+	MinProfitRate  float64 `json:"min_profit_rate"`
+	Categories     string  `json:"categories"`
+	SourcePlatform string  `json:"source_platform"`
+	TargetPlatform string  `json:"target_platform"`
+	BuyType        string  `json:"buy_type"`
+	SellType       string  `json:"sell_type"`
+	MinLiquidity   float64 `json:"min_liquidity"`
+
+	SteamRatioThreshold float64 `json:"steam_ratio_threshold"`
+	DisplayCurrency     string  `json:"display_currency"`
+}
+
+// 展示币种缓存，默认方案修改时清除
+const (
+	displayCurrencyKeyPrefix = "display_currency:"
+	displayCurrencyCacheTTL  = 10 * time.Minute
+)
+
+// 设置方案可修改的字段
+var settingProfileFields = []string{"name", "min_sell_num", "min_diff", "max_sell_price", "min_sell_price",
+	"min_profit_rate", "categories", "source_platform", "target_platform", "buy_type", "sell_type", "min_liquidity", "steam_ratio_threshold", "display_currency"}
+
+// MigrateSettingsProfiles 将每个用户原有的单一设置迁移为默认方案
+func MigrateSettingsProfiles(db *gorm.DB) error {
+	// 旧版本 user_id 为唯一索引，一个用户只能有一条设置
+	if db.Migrator().HasIndex(&Settings{}, "idx_user_setting") {
+		if err := db.Migrator().DropIndex(&Settings{}, "idx_user_setting"); err != nil {
+			return err
+		}
+	}
+	return db.Model(&Settings{}).
+		Where("name = '' OR name IS NULL").
+		Updates(map[string]interface{}{"name": DefaultProfileName, "is_default": true}).Error
 }
 
 func CreateDefaultSetting(id string) int {
 	var setting Settings
 	setting.UserId = id
+	setting.Name = DefaultProfileName
+	setting.IsDefault = true
 	err := config.DB.Create(&setting).Error
 	if err != nil {
 		config.Log.Errorf("create default setting error: %v", err)
 		return utils.ErrCodeCreateDefaultSetting
 	}
+	clearDisplayCurrency(id)
 	return utils.SUCCESS
 }
 
+// GetUserSetting 获取用户的默认设置方案
 func GetUserSetting(id string) (*SettingsResponse, int) {
 	var setting SettingsResponse
 	var code int
-	err := config.DB.Model(&Settings{}).Where("user_id = ?", id).First(&setting).Error
+	err := config.DB.Model(&Settings{}).Where("user_id = ?", id).Order("is_default DESC, id ASC").First(&setting).Error
 	if err != nil {
 		config.Log.Errorf("get settings user: %s err: %s", id, err)
 		code = utils.ErrCodeGetSettings
@@ -93,8 +157,79 @@ func GetUserSetting(id string) (*SettingsResponse, int) {
 	return &setting, code
 }
 
+// GetUserDisplayCurrency 获取用户默认设置方案中的展示币种，未设置时为人民币
+func GetUserDisplayCurrency(id string) string {
+	ctx := context.Background()
+	key := displayCurrencyKeyPrefix + id
+	if currency, err := config.RDB.Get(ctx, key).Result(); err == nil && currency != "" {
+		return currency
+	}
+	currency := BaseCurrency
+	if setting, code := GetUserSetting(id); code == utils.SUCCESS {
+		currency = NormalizeCurrency(setting.DisplayCurrency)
+	}
+	config.RDB.Set(ctx, key, currency, displayCurrencyCacheTTL)
+	return currency
+}
+
+// clearDisplayCurrency 清除用户展示币种缓存
+func clearDisplayCurrency(id string) {
+	config.RDB.Del(context.Background(), displayCurrencyKeyPrefix+id)
+}
+
+// GetUserSettingProfile 获取用户指定的设置方案
+func GetUserSettingProfile(userId string, profileId uint) (*SettingsResponse, int) {
+	var setting SettingsResponse
+	err := config.DB.Model(&Settings{}).Where("id = ? AND user_id = ?", profileId, userId).First(&setting).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &setting, utils.ErrCodeSettingProfileNotFound
+		}
+		config.Log.Errorf("get setting profile %d user: %s err: %s", profileId, userId, err)
+		return &setting, utils.ErrCodeGetSettings
+	}
+	return &setting, utils.SUCCESS
+}
+
+// GetUserSettingProfiles 获取用户的所有设置方案，默认方案在前
+func GetUserSettingProfiles(userId string) ([]SettingsResponse, int) {
+	var profiles []SettingsResponse
+	err := config.DB.Model(&Settings{}).Where("user_id = ?", userId).Order("is_default DESC, id ASC").Find(&profiles).Error
+	if err != nil {
+		config.Log.Errorf("get setting profiles user: %s err: %s", userId, err)
+		return profiles, utils.ErrCodeGetSettings
+	}
+	return profiles, utils.SUCCESS
+}
+
+// CreateSettingProfile 新建设置方案，用户的第一个方案自动成为默认方案
+func CreateSettingProfile(userId string, setting *Settings) int {
+	var count int64
+	if err := config.DB.Model(&Settings{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		config.Log.Errorf("count setting profiles error: %v", err)
+		return utils.ErrCodeCreateSettingProfile
+	}
+	if count >= MaxSettingProfiles {
+		return utils.ErrCodeSettingProfileLimit
+	}
+	setting.ID = 0
+	setting.UserId = userId
+	setting.IsDefault = count == 0
+	if err := config.DB.Create(setting).Error; err != nil {
+		config.Log.Errorf("create setting profile error: %v", err)
+		return utils.ErrCodeCreateSettingProfile
+	}
+	clearDisplayCurrency(userId)
+	return utils.SUCCESS
+}
+
+// UpdateSetting 更新用户的默认设置方案（兼容只有单一设置的旧接口）
 func UpdateSetting(id string, setting Settings) int {
-	err := config.DB.Model(&Settings{}).Where("user_id = ?", id).Select("min_sell_num", "min_diff", "max_sell_price", "min_sell_price").Updates(&setting).Error
+	current, code := GetUserSetting(id)
+	if code != utils.SUCCESS {
+		return utils.ErrCodeUpdateSetting
+	}
+	err := config.DB.Model(&Settings{}).Where("id = ?", current.ID).Select("min_sell_num", "min_diff", "max_sell_price", "min_sell_price").Updates(&setting).Error
 	if err != nil {
 		config.Log.Errorf("update setting err: %s", err)
 		return utils.ErrCodeUpdateSetting
@@ -102,13 +237,65 @@ func UpdateSetting(id string, setting Settings) int {
 	return utils.SUCCESS
 }
 
+// UpdateSettingProfile 更新用户的某个设置方案
+func UpdateSettingProfile(userId string, setting Settings) int {
+	result := config.DB.Model(&Settings{}).Where("id = ? AND user_id = ?", setting.ID, userId).Select(settingProfileFields).Updates(&setting)
+	if result.Error != nil {
+		config.Log.Errorf("update setting profile err: %s", result.Error)
+		return utils.ErrCodeUpdateSetting
+	}
+	clearDisplayCurrency(userId)
+	if result.RowsAffected == 0 {
+		if _, code := GetUserSettingProfile(userId, setting.ID); code != utils.SUCCESS {
+			return code
+		}
+	}
+	return utils.SUCCESS
+}
+
+// DeleteSettingProfile 删除设置方案，默认方案不能删除
+func DeleteSettingProfile(userId string, profileId uint) int {
+	profile, code := GetUserSettingProfile(userId, profileId)
+	if code != utils.SUCCESS {
+		return code
+	}
+	if profile.IsDefault {
+		return utils.ErrCodeDeleteDefaultProfile
+	}
+	if err := config.DB.Where("id = ? AND user_id = ?", profileId, userId).Delete(&Settings{}).Error; err != nil {
+		config.Log.Errorf("delete setting profile err: %s", err)
+		return utils.ErrCodeDeleteSettingProfile
+	}
+	return utils.SUCCESS
+}
+
+// SetDefaultSettingProfile 将指定方案设为默认方案
+func SetDefaultSettingProfile(userId string, profileId uint) int {
+	if _, code := GetUserSettingProfile(userId, profileId); code != utils.SUCCESS {
+		return code
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Settings{}).Where("user_id = ? AND id <> ?", userId, profileId).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Settings{}).Where("id = ?", profileId).Update("is_default", true).Error
+	})
+	if err != nil {
+		config.Log.Errorf("set default setting profile err: %s", err)
+		return utils.ErrCodeUpdateSetting
+	}
+	clearDisplayCurrency(userId)
+	return utils.SUCCESS
+}
+
 // GetAdminSetting 获取管理员的设置（用于公开首页）
 func GetAdminSetting() (*SettingsResponse, int) {
 	var setting SettingsResponse
-	// 查找管理员用户的setting
+	// 查找管理员用户的默认setting
 	err := config.DB.Model(&Settings{}).
 		Joins("JOIN user ON settings.user_id = user.id").
 		Where("user.role = ?", RoleAdmin).
+		Order("settings.is_default DESC, settings.id ASC").
 		First(&setting).Error
 	if err != nil {
 		config.Log.Errorf("get admin settings err: %s", err)
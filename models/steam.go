@@ -1,11 +1,6 @@
 package models
 
 import (
-	"strings"
-	"time"
-
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"uu/config"
 )
 
@@ -21,45 +16,8 @@ type Steam struct {
 	UpdateTime     int64   `json:"updateTime"`
 	TurnOver       int64   `json:"turn_over"`
 	Link           string  `json:"link"`
-}
-
-func BatchGetSteamGoods(hashNames []string) map[string]*Steam {
-	var steams []Steam
-	result := make(map[string]*Steam)
-	config.DB.Where("market_hash_name in ?", hashNames).Find(&steams)
-	for i := range steams {
-		result[steams[i].MarketHashName] = &steams[i]
-	}
-	return result
-}
-
-func BatchUpdateSteamGoods(steam []*Steam) {
-	maxRetries := 3
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		err = config.DB.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Clauses(clause.OnConflict{
-				Columns: []clause.Column{{Name: "market_hash_name"}},
-				//DoUpdates: clause.AssignmentColumns([]string{"sell_price", "sell_count", "bidding_price", "bidding_count", "update_time", "turn_over", "link"}),
-				DoUpdates: clause.AssignmentColumns([]string{"link"}),
-			}).CreateInBatches(steam, 50).Error; err != nil {
-				return err
-			}
-			return nil
-		})
-		if err == nil {
-			return
-		}
-		if strings.Contains(err.Error(), "Deadlock") || strings.Contains(err.Error(), "SAVEPOINT") {
-			config.Log.Warnf("Update Steam Goods deadlock, retrying (%d/%d)...", i+1, maxRetries)
-			time.Sleep(time.Millisecond * time.Duration(100*(i+1)))
-			continue
-		}
-		break
-	}
-	if err != nil {
-		config.Log.Errorf("Update Steam Goods fail: %v", err)
-	}
+	Currency       string  `json:"currency" gorm:"type:varchar(3);default:CNY"` // 原始报价币种，价格已换算为人民币
+	Anomaly        string  `json:"anomaly" gorm:"type:varchar(20)"`             // 异常报价原因，为空表示正常
 }
 
 // GetSteamsWithoutItemNameId 获取所有没有 item_nameid 的商品
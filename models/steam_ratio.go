@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"uu/config"
+	"uu/utils"
+
+	"gorm.io/gorm"
+)
+
+// SteamRatioItem 挂刀数据：在其他平台买入、在 Steam 出售换取余额
+// 比例 = Steam 扣除手续费后到手余额 / 买入价，越高越划算
+type SteamRatioItem struct {
+	MarketHashName    string  `json:"market_hash_name"`
+	Name              string  `json:"name"`
+	ImageUrl          string  `json:"image_url"`
+	TypeName          string  `json:"type_name"`
+	BuyPrice          float64 `json:"buy_price" currency:"amount"` // 来源平台在售价
+	BuyCount          int64   `json:"buy_count"`                   // 来源平台在售数
+	SteamSellPrice    float64 `json:"steam_sell_price" currency:"amount"`
+	SteamSellCount    int64   `json:"steam_sell_count"`
+	SteamBiddingPrice float64 `json:"steam_bidding_price" currency:"amount"`
+	SteamBiddingCount int64   `json:"steam_bidding_count"`
+	SteamVolume       float64 `json:"steam_volume"`                  // Steam 估算日成交量
+	InstantNet        float64 `json:"instant_net" currency:"amount"` // 卖给 Steam 求购的到手余额
+	InstantRatio      float64 `json:"instant_ratio"`                 // 求购比例 = instant_net / buy_price
+	ListingNet        float64 `json:"listing_net" currency:"amount"` // 按 Steam 最低在售价上架的到手余额
+	ListingRatio      float64 `json:"listing_ratio"`                 // 上架比例 = listing_net / buy_price
+	SteamUpdateTime   int64   `json:"steam_update_time"`
+	SourceUpdateTime  int64   `json:"source_update_time"`
+}
+
+// SteamRatioQuery 挂刀查询条件
+type SteamRatioQuery struct {
+	UserId    string
+	PageSize  int
+	PageNum   int
+	IsDesc    bool
+	SortField string // instant_ratio / listing_ratio
+	Source    string // 买入平台标识，默认悠悠
+	Search    string
+	Category  string
+	Watchlist bool
+
+	MinRatio       *float64 // 排序所用比例的最小值，为空时使用用户设置的挂刀比例阈值
+	MinSteamVolume *float64 // Steam 最小估算日成交量
+	MinSteamPrice  *float64 // Steam 在售价下限
+	MaxSteamPrice  *float64 // Steam 在售价上限
+}
+
+// GetSteamRatio 按 Steam 余额比例排序的挂刀列表
+func GetSteamRatio(q *SteamRatioQuery) (*[]SteamRatioItem, int64, int) {
+	var items []SteamRatioItem
+	var total int64
+
+	steamDef, ok := GetPlatformByCode("STEAM")
+	if !ok {
+		return &items, 0, utils.ErrCodeGetGoods
+	}
+	// 买入平台不能是 Steam 本身，默认悠悠
+	sourceDef, ok := GetPlatformByKey(q.Source)
+	if !ok || sourceDef.Code == steamDef.Code {
+		sourceDef = Platforms()[0]
+	}
+	sourceTable, steamTable := sourceDef.Table, steamDef.Table
+
+	sortField := q.SortField
+	if sortField != "listing_ratio" {
+		sortField = "instant_ratio"
+	}
+	order := sortField
+	if q.IsDesc {
+		order += " DESC"
+	}
+
+	minRatio := q.MinRatio
+	if minRatio == nil {
+		if settings, code := GetUserSetting(q.UserId); code == utils.SUCCESS && settings.SteamRatioThreshold > 0 {
+			minRatio = &settings.SteamRatioThreshold
+		}
+	}
+
+	fee := steamDef.Fee()
+	buyPrice := sourceTable + ".sell_price"
+	instantNet := fee.NetProceedsSQL(steamTable + ".bidding_price")
+	listingNet := fee.NetProceedsSQL(steamTable + ".sell_price")
+	ratioExpr := map[string]string{
+		"instant_ratio": fmt.Sprintf("%s / %s", instantNet, buyPrice),
+		"listing_ratio": fmt.Sprintf("%s / %s", listingNet, buyPrice),
+	}
+
+	selectSQL := fmt.Sprintf(`
+		%s.market_hash_name as market_hash_name,
+		base_goods.name as name,
+		base_goods.icon_url as image_url,
+		u_base_info.type_name as type_name,
+		%s as buy_price,
+		%s.sell_count as buy_count,
+		%s.sell_price as steam_sell_price,
+		%s.sell_count as steam_sell_count,
+		%s.bidding_price as steam_bidding_price,
+		%s.bidding_count as steam_bidding_count,
+		COALESCE(liquidity.sales_per_day, 0) as steam_volume,
+		ROUND(%s, 2) as instant_net,
+		ROUND(%s, 4) as instant_ratio,
+		ROUND(%s, 2) as listing_net,
+		ROUND(%s, 4) as listing_ratio,
+		%s.update_time as steam_update_time,
+		%s.update_time as source_update_time`,
+		steamTable,
+		buyPrice,
+		sourceTable,
+		steamTable, steamTable, steamTable, steamTable,
+		instantNet, ratioExpr["instant_ratio"],
+		listingNet, ratioExpr["listing_ratio"],
+		steamTable, sourceTable)
+
+	// 按求购比例排序时要求 Steam 有求购
+	whereSQL := fmt.Sprintf("%s > 0 AND %s.sell_price > 0", buyPrice, steamTable)
+	if sortField == "instant_ratio" {
+		whereSQL += fmt.Sprintf(" AND %s.bidding_price > 0", steamTable)
+	}
+
+	build := func() *gorm.DB {
+		db := config.DB.Table(steamTable).
+			Joins(fmt.Sprintf("JOIN %s ON %s.market_hash_name = %s.market_hash_name", sourceTable, steamTable, sourceTable)).
+			Joins(fmt.Sprintf("JOIN base_goods ON %s.market_hash_name = base_goods.market_hash_name", steamTable)).
+			Joins(fmt.Sprintf("LEFT JOIN u_base_info ON %s.market_hash_name = u_base_info.hash_name", steamTable)).
+			Joins(liquidityJoin(steamTable, steamDef.Code)).
+			Where(whereSQL)
+		if q.Category != "" {
+			db = db.Where("u_base_info.type_name IN ?", strings.Split(q.Category, ","))
+		}
+		if q.Search != "" {
+			db = db.Where("base_goods.name LIKE ?", "%"+q.Search+"%")
+		}
+		if q.Watchlist {
+			db = db.Where(watchlistFilter(steamTable), q.UserId)
+		}
+		if minRatio != nil {
+			db = db.Where(ratioExpr[sortField]+" >= ?", *minRatio)
+		}
+		if q.MinSteamVolume != nil {
+			db = db.Where("COALESCE(liquidity.sales_per_day, 0) >= ?", *q.MinSteamVolume)
+		}
+		if q.MinSteamPrice != nil {
+			db = db.Where(steamTable+".sell_price >= ?", *q.MinSteamPrice)
+		}
+		if q.MaxSteamPrice != nil {
+			db = db.Where(steamTable+".sell_price <= ?", *q.MaxSteamPrice)
+		}
+		return db
+	}
+
+	if err := build().Count(&total).Error; err != nil {
+		config.Log.Errorf("Get steam ratio total fail: %v", err)
+		return &items, 0, utils.ErrCodeGetGoodsTotal
+	}
+	err := build().
+		Select(selectSQL).
+		Order(order).
+		Limit(q.PageSize).
+		Offset((q.PageNum - 1) * q.PageSize).
+		Scan(&items).Error
+	if err != nil {
+		config.Log.Errorf("Get steam ratio data fail: %v", err)
+		return &items, 0, utils.ErrCodeGetGoods
+	}
+	return &items, total, utils.SUCCESS
+}
@@ -19,21 +19,23 @@ const (
 
 // GetSystemConfig 获取系统配置
 func GetSystemConfig(key string) (string, bool) {
-	var cfg SystemConfig
-	err := config.DB.Where("`key` = ?", key).First(&cfg).Error
-	if err != nil {
+	var cfgs []SystemConfig
+	err := config.DB.Where("`key` = ?", key).Limit(1).Find(&cfgs).Error
+	if err != nil || len(cfgs) == 0 {
 		return "", false
 	}
-	return cfg.Value, true
+	return cfgs[0].Value, true
 }
 
 // SetSystemConfig 设置系统配置
 func SetSystemConfig(key, value, desc string) error {
-	var cfg SystemConfig
-	err := config.DB.Where("`key` = ?", key).First(&cfg).Error
-	if err != nil {
+	var cfgs []SystemConfig
+	if err := config.DB.Where("`key` = ?", key).Limit(1).Find(&cfgs).Error; err != nil {
+		return err
+	}
+	if len(cfgs) == 0 {
 		// 不存在则创建
-		cfg = SystemConfig{
+		cfg := SystemConfig{
 			Key:   key,
 			Value: value,
 			Desc:  desc,
@@ -41,6 +43,7 @@ func SetSystemConfig(key, value, desc string) error {
 		return config.DB.Create(&cfg).Error
 	}
 	// 存在则更新
+	cfg := cfgs[0]
 	cfg.Value = value
 	if desc != "" {
 		cfg.Desc = desc
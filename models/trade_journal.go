@@ -0,0 +1,480 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"github.com/google/uuid"
+)
+
+// MaxTradesPerImport 单次 CSV 导入的最大条数
+const MaxTradesPerImport = 2000
+
+// TradeJournal 已完成的搬砖交易记录，价格为单件人民币价格
+// Snapshot* 为买入时刻监控展示的行情，用于对比监控给出的预期收益和实际收益
+type TradeJournal struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uuid.UUID `json:"-" gorm:"type:char(36);index:idx_trade_user_time,priority:1;not null"`
+	MarketHashName string    `json:"market_hash_name" gorm:"type:varchar(255);not null"`
+	Quantity       int64     `json:"quantity" gorm:"not null;default:1"`
+	BuyPlatform    string    `json:"buy_platform" gorm:"type:varchar(20)"` // 平台代码
+	BuyPrice       float64   `json:"buy_price" currency:"amount"`
+	BuyTime        time.Time `json:"buy_time" gorm:"index:idx_trade_user_time,priority:2"`
+	SellPlatform   string    `json:"sell_platform" gorm:"type:varchar(20)"` // 平台代码
+	SellPrice      float64   `json:"sell_price" currency:"amount"`
+	SellTime       time.Time `json:"sell_time"`
+	Fee            float64   `json:"fee" currency:"amount"`    // 总手续费，未填写时按卖出平台手续费计算
+	Profit         float64   `json:"profit" currency:"amount"` // 卖出总额 - 手续费 - 买入总额
+	Note           string    `json:"note" gorm:"type:varchar(255)"`
+
+	SnapshotTime      *time.Time `json:"snapshot_time"`                         // 关联的行情时间，无行情时为空
+	SnapshotBuyPrice  float64    `json:"snapshot_buy_price" currency:"amount"`  // 买入平台当时在售价
+	SnapshotSellPrice float64    `json:"snapshot_sell_price" currency:"amount"` // 卖出平台当时在售价
+	SnapshotProfit    float64    `json:"snapshot_profit" currency:"amount"`     // 当时展示的单件预期净利润（扣除手续费）
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TradeGroupStats 分组统计
+type TradeGroupStats struct {
+	Key    string  `json:"key"`
+	Trades int     `json:"trades"`
+	Cost   float64 `json:"cost" currency:"amount"`
+	Profit float64 `json:"profit" currency:"amount"`
+	ROI    float64 `json:"roi"`
+}
+
+// TradeStats 用户交易统计
+type TradeStats struct {
+	Trades         int                `json:"trades"`
+	Cost           float64            `json:"cost" currency:"amount"`
+	Profit         float64            `json:"profit" currency:"amount"`
+	ROI            float64            `json:"roi"`                               // 总利润 / 总成本
+	WinRate        float64            `json:"win_rate"`                          // 盈利笔数 / 总笔数
+	AvgHoldingDays float64            `json:"avg_holding_days"`                  // 平均持有天数
+	SnapshotProfit float64            `json:"snapshot_profit" currency:"amount"` // 有关联行情的交易的预期利润合计
+	MatchedProfit  float64            `json:"matched_profit" currency:"amount"`  // 有关联行情的交易的实际利润合计
+	MatchedTrades  int                `json:"matched_trades"`                    // 有关联行情的交易笔数
+	ByCategory     []*TradeGroupStats `json:"by_category"`
+	ByPlatformPair []*TradeGroupStats `json:"by_platform_pair"` // key 如 uu->buff
+}
+
+// TradeCSVHeader CSV 导入导出的列，时间格式 2006-01-02 15:04:05 或 2006-01-02，平台为标识或代码
+var TradeCSVHeader = []string{
+	"market_hash_name", "quantity", "buy_platform", "buy_price", "buy_time",
+	"sell_platform", "sell_price", "sell_time", "fee", "note",
+}
+
+// snapshotLookback 关联行情时向前查找的最长时间
+const snapshotLookback = 24 * time.Hour
+
+// pricePoint 某一时刻的在售价
+type pricePoint struct {
+	price float64
+	at    time.Time
+}
+
+// tradePrices 关联交易行情用的价格，一次查询加载一批交易涉及的饰品、平台和时间范围
+// key: market_hash_name + "|" + 平台代码，值按时间升序
+type tradePrices struct {
+	snapshots map[string][]pricePoint
+	history   map[string][]pricePoint
+}
+
+func tradePriceKey(marketHashName, platform string) string {
+	return marketHashName + "|" + platform
+}
+
+// loadTradePrices 加载交易买入时刻前 snapshotLookback 内的日内快照和每日记录
+func loadTradePrices(trades []*TradeJournal) *tradePrices {
+	result := &tradePrices{snapshots: map[string][]pricePoint{}, history: map[string][]pricePoint{}}
+	if len(trades) == 0 {
+		return result
+	}
+	// 日内快照只保留最近几天，只为买入时间在保留范围内的交易加载
+	snapshotFrom := time.Now().AddDate(0, 0, -PriceSnapshotRetentionDays).Add(-snapshotLookback)
+	nameSet, platformSet := map[string]bool{}, map[string]bool{}
+	snapshotNames := map[string]bool{}
+	// 买入时刻前 snapshotLookback 内只有买入当天的每日记录，按 (饰品, 日期) 精确加载
+	dayKeys := map[string]bool{}
+	var days [][]interface{}
+	var from, to time.Time
+	for _, t := range trades {
+		nameSet[t.MarketHashName] = true
+		platformSet[t.BuyPlatform] = true
+		platformSet[t.SellPlatform] = true
+		if day := t.BuyTime.Format("2006-01-02"); !dayKeys[t.MarketHashName+"|"+day] {
+			dayKeys[t.MarketHashName+"|"+day] = true
+			days = append(days, []interface{}{t.MarketHashName, day})
+		}
+		if !t.BuyTime.After(snapshotFrom) {
+			continue
+		}
+		snapshotNames[t.MarketHashName] = true
+		if from.IsZero() || t.BuyTime.Before(from) {
+			from = t.BuyTime
+		}
+		if t.BuyTime.After(to) {
+			to = t.BuyTime
+		}
+	}
+	platforms := make([]string, 0, len(platformSet))
+	for p := range platformSet {
+		platforms = append(platforms, p)
+	}
+
+	if len(snapshotNames) > 0 {
+		names := make([]string, 0, len(snapshotNames))
+		for name := range snapshotNames {
+			names = append(names, name)
+		}
+		var snaps []PriceSnapshot
+		err := config.DB.Select("market_hash_name, platform, sell_price, snapshot_time").
+			Where("market_hash_name IN ? AND platform IN ? AND snapshot_time <= ? AND snapshot_time > ?",
+				names, platforms, to, from.Add(-snapshotLookback)).
+			Order("snapshot_time").Find(&snaps).Error
+		if err != nil {
+			config.Log.Errorf("Get snapshots for trades error: %v", err)
+		}
+		for _, p := range snaps {
+			key := tradePriceKey(p.MarketHashName, p.Platform)
+			result.snapshots[key] = append(result.snapshots[key], pricePoint{price: p.SellPrice, at: p.SnapshotTime})
+		}
+	}
+
+	var history []PriceHistory
+	err := config.DB.Select("market_hash_name, platform, sell_price, record_date").
+		Where("(market_hash_name, record_date) IN ? AND platform IN ? AND sell_price > 0", days, platforms).
+		Order("record_date").Find(&history).Error
+	if err != nil {
+		config.Log.Errorf("Get price history for trades error: %v", err)
+	}
+	for _, h := range history {
+		key := tradePriceKey(h.MarketHashName, h.Platform)
+		result.history[key] = append(result.history[key], pricePoint{price: h.SellPrice, at: h.RecordDate})
+	}
+	return result
+}
+
+// at 某饰品在某平台于 t 时刻的在售价：优先取日内快照，快照已压缩时取当日及之前的每日记录
+func (p *tradePrices) at(marketHashName, platform string, t time.Time) (float64, time.Time, bool) {
+	key := tradePriceKey(marketHashName, platform)
+	if snap, ok := lastPointBefore(p.snapshots[key], t); ok && snap.price > 0 {
+		return snap.price, snap.at, true
+	}
+	if h, ok := lastPointBefore(p.history[key], t); ok {
+		return h.price, h.at, true
+	}
+	return 0, time.Time{}, false
+}
+
+// lastPointBefore 按时间升序的价格中 t 及之前 snapshotLookback 内的最后一个
+func lastPointBefore(points []pricePoint, t time.Time) (pricePoint, bool) {
+	i := sort.Search(len(points), func(i int) bool { return points[i].at.After(t) })
+	if i == 0 || !points[i-1].at.After(t.Add(-snapshotLookback)) {
+		return pricePoint{}, false
+	}
+	return points[i-1], true
+}
+
+// prepareTrade 补全手续费、利润，并关联买入时刻的行情
+// platformFees 为 GetPlatformFeeMap 的结果，批量处理时只加载一次
+func prepareTrade(t *TradeJournal, fee *float64, platformFees map[string]*PlatformFee, prices *tradePrices) {
+	qty := float64(t.Quantity)
+	sellFee, hasSellFee := platformFees[t.SellPlatform]
+	if fee != nil {
+		t.Fee = *fee
+	} else if hasSellFee {
+		t.Fee = (t.SellPrice - sellFee.NetProceeds(t.SellPrice)) * qty
+	}
+	t.Fee = math.Round(t.Fee*100) / 100
+	t.Profit = math.Round((t.SellPrice*qty-t.Fee-t.BuyPrice*qty)*100) / 100
+
+	t.SnapshotTime = nil
+	t.SnapshotBuyPrice, t.SnapshotSellPrice, t.SnapshotProfit = 0, 0, 0
+	buyPrice, buyAt, ok := prices.at(t.MarketHashName, t.BuyPlatform, t.BuyTime)
+	if !ok {
+		return
+	}
+	sellPrice, _, ok := prices.at(t.MarketHashName, t.SellPlatform, t.BuyTime)
+	if !ok {
+		return
+	}
+	if !hasSellFee {
+		return
+	}
+	t.SnapshotTime = &buyAt
+	t.SnapshotBuyPrice = buyPrice
+	t.SnapshotSellPrice = sellPrice
+	t.SnapshotProfit = math.Round((sellFee.NetProceeds(sellPrice)-buyPrice)*100) / 100
+}
+
+// CreateTrade 记录交易，fee 为空时按卖出平台手续费计算
+func CreateTrade(t *TradeJournal, fee *float64) int {
+	prepareTrade(t, fee, GetPlatformFeeMap(), loadTradePrices([]*TradeJournal{t}))
+	if err := config.DB.Create(t).Error; err != nil {
+		config.Log.Errorf("Create trade error: %v", err)
+		return utils.ErrCodeCreateTrade
+	}
+	return utils.SUCCESS
+}
+
+// UpdateTrade 修改交易记录，重新计算利润和关联行情
+func UpdateTrade(userID uuid.UUID, id uint, t *TradeJournal, fee *float64) int {
+	var existing TradeJournal
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&existing).Error; err != nil {
+		return utils.ErrCodeTradeNotFound
+	}
+	t.ID, t.UserID, t.CreatedAt = existing.ID, existing.UserID, existing.CreatedAt
+	prepareTrade(t, fee, GetPlatformFeeMap(), loadTradePrices([]*TradeJournal{t}))
+	if err := config.DB.Save(t).Error; err != nil {
+		config.Log.Errorf("Update trade error: %v", err)
+		return utils.ErrCodeUpdateTrade
+	}
+	return utils.SUCCESS
+}
+
+// DeleteTrade 删除交易记录
+func DeleteTrade(userID uuid.UUID, id uint) int {
+	res := config.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&TradeJournal{})
+	if res.Error != nil {
+		config.Log.Errorf("Delete trade error: %v", res.Error)
+		return utils.ErrCodeUpdateTrade
+	}
+	if res.RowsAffected == 0 {
+		return utils.ErrCodeTradeNotFound
+	}
+	return utils.SUCCESS
+}
+
+// GetTrades 分页获取交易记录，按买入时间倒序；pageSize 为 0 时返回全部
+func GetTrades(userID uuid.UUID, pageSize, pageNum int) ([]TradeJournal, int64, int) {
+	var trades []TradeJournal
+	var total int64
+	db := config.DB.Model(&TradeJournal{}).Where("user_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		config.Log.Errorf("Get trades total error: %v", err)
+		return nil, 0, utils.ErrCodeGetTrades
+	}
+	db = db.Order("buy_time DESC, id DESC")
+	if pageSize > 0 {
+		db = db.Limit(pageSize).Offset((pageNum - 1) * pageSize)
+	}
+	if err := db.Find(&trades).Error; err != nil {
+		config.Log.Errorf("Get trades error: %v", err)
+		return nil, 0, utils.ErrCodeGetTrades
+	}
+	return trades, total, utils.SUCCESS
+}
+
+// ImportTrades 批量导入交易记录，平台手续费和所有交易的关联行情一次加载
+func ImportTrades(userID uuid.UUID, trades []*TradeJournal, fees []*float64) (int, int) {
+	platformFees := GetPlatformFeeMap()
+	prices := loadTradePrices(trades)
+	for i, t := range trades {
+		t.UserID = userID
+		prepareTrade(t, fees[i], platformFees, prices)
+	}
+	if err := config.DB.CreateInBatches(trades, 200).Error; err != nil {
+		config.Log.Errorf("Import trades error: %v", err)
+		return 0, utils.ErrCodeImportTrades
+	}
+	return len(trades), utils.SUCCESS
+}
+
+// GetTradeStats 用户交易统计：ROI、平均持有天数、胜率，按类别和平台组合分组的利润
+func GetTradeStats(userID uuid.UUID) (*TradeStats, int) {
+	trades, _, code := GetTrades(userID, 0, 0)
+	if code != utils.SUCCESS {
+		return nil, code
+	}
+
+	stats := &TradeStats{ByCategory: []*TradeGroupStats{}, ByPlatformPair: []*TradeGroupStats{}}
+	if len(trades) == 0 {
+		return stats, utils.SUCCESS
+	}
+
+	hashNames := make([]string, 0, len(trades))
+	for _, t := range trades {
+		hashNames = append(hashNames, t.MarketHashName)
+	}
+	var categories []UBaseInfo
+	config.DB.Select("hash_name, type_name").Where("hash_name IN ?", hashNames).Find(&categories)
+	categoryMap := make(map[string]string, len(categories))
+	for _, c := range categories {
+		categoryMap[c.HashName] = c.TypeName
+	}
+
+	byCategory := make(map[string]*TradeGroupStats)
+	byPair := make(map[string]*TradeGroupStats)
+	add := func(groups map[string]*TradeGroupStats, key string, cost, profit float64) {
+		g := groups[key]
+		if g == nil {
+			g = &TradeGroupStats{Key: key}
+			groups[key] = g
+		}
+		g.Trades++
+		g.Cost += cost
+		g.Profit += profit
+	}
+
+	var wins int
+	var holdingDays float64
+	for _, t := range trades {
+		cost := t.BuyPrice * float64(t.Quantity)
+		stats.Trades++
+		stats.Cost += cost
+		stats.Profit += t.Profit
+		if t.Profit > 0 {
+			wins++
+		}
+		if t.SellTime.After(t.BuyTime) {
+			holdingDays += t.SellTime.Sub(t.BuyTime).Hours() / 24
+		}
+		if t.SnapshotTime != nil {
+			stats.MatchedTrades++
+			stats.SnapshotProfit += t.SnapshotProfit * float64(t.Quantity)
+			stats.MatchedProfit += t.Profit
+		}
+
+		category := categoryMap[t.MarketHashName]
+		if category == "" {
+			category = InferTypeFromHashName(t.MarketHashName)
+		}
+		add(byCategory, category, cost, t.Profit)
+		add(byPair, platformKey(t.BuyPlatform)+"->"+platformKey(t.SellPlatform), cost, t.Profit)
+	}
+
+	stats.Cost = math.Round(stats.Cost*100) / 100
+	stats.Profit = math.Round(stats.Profit*100) / 100
+	stats.ROI = ratio(stats.Profit, stats.Cost)
+	stats.WinRate = ratio(float64(wins), float64(stats.Trades))
+	stats.AvgHoldingDays = math.Round(holdingDays/float64(stats.Trades)*100) / 100
+	stats.SnapshotProfit = math.Round(stats.SnapshotProfit*100) / 100
+	stats.MatchedProfit = math.Round(stats.MatchedProfit*100) / 100
+	stats.ByCategory = sortedGroups(byCategory)
+	stats.ByPlatformPair = sortedGroups(byPair)
+	return stats, utils.SUCCESS
+}
+
+// ratio 保留 4 位小数的比值，分母为 0 时为 0
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return math.Round(a/b*10000) / 10000
+}
+
+// sortedGroups 计算各分组 ROI 并按利润倒序
+func sortedGroups(groups map[string]*TradeGroupStats) []*TradeGroupStats {
+	result := make([]*TradeGroupStats, 0, len(groups))
+	for _, g := range groups {
+		g.Cost = math.Round(g.Cost*100) / 100
+		g.Profit = math.Round(g.Profit*100) / 100
+		g.ROI = ratio(g.Profit, g.Cost)
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Profit != result[j].Profit {
+			return result[i].Profit > result[j].Profit
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// platformKey 平台代码转换为平台标识，未知平台原样返回
+func platformKey(code string) string {
+	if p, ok := GetPlatformByCode(code); ok {
+		return p.Key
+	}
+	return code
+}
+
+// resolvePlatform 按平台标识或代码查找平台
+func resolvePlatform(s string) (*PlatformDef, bool) {
+	if p, ok := GetPlatformByKey(strings.ToLower(s)); ok {
+		return p, true
+	}
+	return GetPlatformByCode(strings.ToUpper(s))
+}
+
+// parseTradeTime 解析 2006-01-02 15:04:05 或 2006-01-02 格式时间
+func parseTradeTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// ParseTrade 由各字段构造交易记录并校验，fee 为空字符串表示按平台手续费计算
+func ParseTrade(marketHashName, quantity, buyPlatform, buyPrice, buyTime, sellPlatform, sellPrice, sellTime, fee, note string) (*TradeJournal, *float64, error) {
+	t := &TradeJournal{MarketHashName: strings.TrimSpace(marketHashName), Note: strings.TrimSpace(note), Quantity: 1}
+	if t.MarketHashName == "" || len(t.MarketHashName) > 255 || len(t.Note) > 255 {
+		return nil, nil, errors.New("invalid market_hash_name or note")
+	}
+	if strings.TrimSpace(quantity) != "" {
+		q, err := strconv.ParseInt(strings.TrimSpace(quantity), 10, 64)
+		if err != nil || q <= 0 {
+			return nil, nil, fmt.Errorf("invalid quantity %q", quantity)
+		}
+		t.Quantity = q
+	}
+	buyDef, ok := resolvePlatform(buyPlatform)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown buy platform %q", buyPlatform)
+	}
+	sellDef, ok := resolvePlatform(sellPlatform)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown sell platform %q", sellPlatform)
+	}
+	t.BuyPlatform, t.SellPlatform = buyDef.Code, sellDef.Code
+
+	var err error
+	if t.BuyPrice, err = strconv.ParseFloat(strings.TrimSpace(buyPrice), 64); err != nil || t.BuyPrice <= 0 {
+		return nil, nil, fmt.Errorf("invalid buy price %q", buyPrice)
+	}
+	if t.SellPrice, err = strconv.ParseFloat(strings.TrimSpace(sellPrice), 64); err != nil || t.SellPrice <= 0 {
+		return nil, nil, fmt.Errorf("invalid sell price %q", sellPrice)
+	}
+	if t.BuyTime, err = parseTradeTime(buyTime); err != nil {
+		return nil, nil, fmt.Errorf("invalid buy time %q", buyTime)
+	}
+	if t.SellTime, err = parseTradeTime(sellTime); err != nil || t.SellTime.Before(t.BuyTime) {
+		return nil, nil, fmt.Errorf("invalid sell time %q", sellTime)
+	}
+
+	var feeValue *float64
+	if strings.TrimSpace(fee) != "" {
+		f, err := strconv.ParseFloat(strings.TrimSpace(fee), 64)
+		if err != nil || f < 0 {
+			return nil, nil, fmt.Errorf("invalid fee %q", fee)
+		}
+		feeValue = &f
+	}
+	return t, feeValue, nil
+}
+
+// CSVRecord 按 TradeCSVHeader 的列顺序导出
+func (t *TradeJournal) CSVRecord() []string {
+	return []string{
+		t.MarketHashName,
+		strconv.FormatInt(t.Quantity, 10),
+		platformKey(t.BuyPlatform),
+		strconv.FormatFloat(t.BuyPrice, 'f', 2, 64),
+		t.BuyTime.Format("2006-01-02 15:04:05"),
+		platformKey(t.SellPlatform),
+		strconv.FormatFloat(t.SellPrice, 'f', 2, 64),
+		t.SellTime.Format("2006-01-02 15:04:05"),
+		strconv.FormatFloat(t.Fee, 'f', 2, 64),
+		t.Note,
+	}
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"strings"
-	"time"
 
 	"uu/config"
 
@@ -204,13 +203,6 @@ func BatchAddUUItem(uu []*UItem) {
 	}
 }
 
-func BatchAddUUInventory(uu []*UItemsInfo) {
-	err := config.DB.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(uu, 100).Error
-	if err != nil {
-		config.Log.Errorf("barch insert uu inventory fail: %s", err)
-	}
-}
-
 // -------------------------------------------------------v2------------------------------------------------------------
 // data from steamDT
 
@@ -226,46 +218,8 @@ type U struct {
 	BeforeCount    int64   `json:"beforeCount"`
 	TurnOver       int64   `json:"turn_over"`
 	Link           string  `json:"link"`
-}
-
-func BatchGetUUGoods(hashNames []string) map[string]*U {
-	var uList []U
-	err := config.DB.Where("market_hash_name in ?", hashNames).Find(&uList).Error
-	if err != nil {
-		config.Log.Errorf("Batch get uu goods error: %v", err)
-	}
-	result := make(map[string]*U)
-	for i := range uList {
-		result[uList[i].MarketHashName] = &uList[i]
-	}
-	return result
-}
-
-func BatchUpdateUUGoods(uu []*U) {
-	maxRetries := 3
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		err = config.DB.Transaction(func(tx *gorm.DB) error {
-			// 减小批量大小，降低锁冲突概率
-			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(uu, 50).Error; err != nil {
-				return err
-			}
-			return nil
-		})
-		if err == nil {
-			return
-		}
-		// 如果是死锁错误，等待后重试
-		if strings.Contains(err.Error(), "Deadlock") || strings.Contains(err.Error(), "SAVEPOINT") {
-			config.Log.Warnf("Update UU Goods deadlock, retrying (%d/%d)...", i+1, maxRetries)
-			time.Sleep(time.Millisecond * time.Duration(100*(i+1))) // 递增等待
-			continue
-		}
-		break // 其他错误不重试
-	}
-	if err != nil {
-		config.Log.Errorf("Update UU Goods fail: %v", err)
-	}
+	Currency       string  `json:"currency" gorm:"type:varchar(3);default:CNY"` // 原始报价币种，价格已换算为人民币
+	Anomaly        string  `json:"anomaly" gorm:"type:varchar(20)"`             // 异常报价原因，为空表示正常
 }
 
 func BatchQueryHashIcon() ([]UBaseInfo, error) {
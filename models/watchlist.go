@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+	"uu/config"
+	"uu/utils"
+
+	"github.com/google/uuid"
+)
+
+// MaxWatchlistSize 每个用户最多可自选的饰品数量
+const MaxWatchlistSize = 200
+
+// Watchlist 用户自选饰品
+type Watchlist struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uuid.UUID `json:"user_id" gorm:"type:char(36);uniqueIndex:idx_user_watch;not null"`
+	MarketHashName string    `json:"market_hash_name" gorm:"type:varchar(255);uniqueIndex:idx_user_watch;not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:datetime;default:CURRENT_TIMESTAMP"`
+}
+
+// WatchlistItem 自选列表项：各平台当前在售信息 + 涨跌幅
+type WatchlistItem struct {
+	MarketHashName string            `json:"market_hash_name"`
+	Name           string            `json:"name"`
+	ImageUrl       string            `json:"image_url"`
+	AddedAt        time.Time         `json:"added_at"`
+	PlatformList   []*Platform       `json:"platform_list"`
+	PriceChange    []PriceChangeItem `json:"price_change"` // 悠悠平台 1d/7d/30d 涨跌幅
+}
+
+// watchlistFilter 生成"只显示自选饰品"的条件，参数为用户ID
+func watchlistFilter(table string) string {
+	return fmt.Sprintf("%s.market_hash_name IN (SELECT market_hash_name FROM watchlist WHERE user_id = ?)", table)
+}
+
+// AddToWatchlist 添加自选饰品
+func AddToWatchlist(userID uuid.UUID, marketHashName string) int {
+	var count int64
+	config.DB.Model(&BaseGoods{}).Where("market_hash_name = ?", marketHashName).Count(&count)
+	if count == 0 {
+		return utils.ErrCodeWatchlistGoodsNotFound
+	}
+
+	config.DB.Model(&Watchlist{}).Where("user_id = ? AND market_hash_name = ?", userID, marketHashName).Count(&count)
+	if count > 0 {
+		return utils.SUCCESS
+	}
+
+	config.DB.Model(&Watchlist{}).Where("user_id = ?", userID).Count(&count)
+	if count >= MaxWatchlistSize {
+		return utils.ErrCodeWatchlistLimitExceeded
+	}
+
+	item := &Watchlist{UserID: userID, MarketHashName: marketHashName}
+	if err := config.DB.Create(item).Error; err != nil {
+		config.Log.Errorf("Add to watchlist error: %v", err)
+		return utils.ErrCodeUpdateWatchlist
+	}
+	return utils.SUCCESS
+}
+
+// RemoveFromWatchlist 移除自选饰品
+func RemoveFromWatchlist(userID uuid.UUID, marketHashName string) int {
+	err := config.DB.Where("user_id = ? AND market_hash_name = ?", userID, marketHashName).Delete(&Watchlist{}).Error
+	if err != nil {
+		config.Log.Errorf("Remove from watchlist error: %v", err)
+		return utils.ErrCodeUpdateWatchlist
+	}
+	return utils.SUCCESS
+}
+
+// GetWatchlist 获取用户自选列表，包含各平台快照和涨跌幅
+func GetWatchlist(userID uuid.UUID) ([]WatchlistItem, int) {
+	var rows []struct {
+		MarketHashName string
+		Name           string
+		IconUrl        string
+		CreatedAt      time.Time
+	}
+	err := config.DB.Table("watchlist").
+		Select("watchlist.market_hash_name, base_goods.name, base_goods.icon_url, watchlist.created_at").
+		Joins("LEFT JOIN base_goods ON watchlist.market_hash_name = base_goods.market_hash_name").
+		Where("watchlist.user_id = ?", userID).
+		Order("watchlist.created_at DESC").
+		Scan(&rows).Error
+	if err != nil {
+		config.Log.Errorf("Get watchlist error: %v", err)
+		return nil, utils.ErrCodeGetWatchlist
+	}
+
+	hashNames := make([]string, 0, len(rows))
+	for _, r := range rows {
+		hashNames = append(hashNames, r.MarketHashName)
+	}
+	platformList := GetPlatformListBatch(hashNames)
+	priceChange := batchCalculatePriceChange(hashNames)
+
+	result := make([]WatchlistItem, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, WatchlistItem{
+			MarketHashName: r.MarketHashName,
+			Name:           r.Name,
+			ImageUrl:       r.IconUrl,
+			AddedAt:        r.CreatedAt,
+			PlatformList:   platformList[r.MarketHashName],
+			PriceChange:    priceChange[r.MarketHashName],
+		})
+	}
+	return result, utils.SUCCESS
+}
+
+// batchCalculatePriceChange 批量计算悠悠平台 1d/7d/30d 涨跌幅，口径与 calculatePriceChange 一致
+func batchCalculatePriceChange(hashNames []string) map[string][]PriceChangeItem {
+	result := make(map[string][]PriceChangeItem)
+	if len(hashNames) == 0 {
+		return result
+	}
+
+	today := getLocalToday()
+	// 多取几天，保证30天前没有记录时能取到更早的一条
+	var histories []PriceHistory
+	err := config.DB.Select("market_hash_name, sell_price, record_date").
+		Where("market_hash_name IN ? AND platform = ? AND record_date >= ?", hashNames, "YOUPIN", today.AddDate(0, 0, -37)).
+		Order("market_hash_name ASC, record_date ASC").
+		Find(&histories).Error
+	if err != nil {
+		config.Log.Errorf("Batch get price history error: %v", err)
+		return result
+	}
+
+	grouped := make(map[string][]PriceHistory)
+	for _, h := range histories {
+		grouped[h.MarketHashName] = append(grouped[h.MarketHashName], h)
+	}
+
+	periods := []struct {
+		label string
+		date  time.Time
+	}{
+		{"今日", today.AddDate(0, 0, -1)},
+		{"本周", today.AddDate(0, 0, -7)},
+		{"本月", today.AddDate(0, 0, -30)},
+	}
+
+	for name, list := range grouped {
+		current := list[len(list)-1].SellPrice
+		items := make([]PriceChangeItem, 0, len(periods))
+		for _, p := range periods {
+			item := PriceChangeItem{Label: p.label}
+			// 取对比日期当天或之前最近的一条
+			var old float64
+			for _, h := range list {
+				if h.RecordDate.After(p.date) {
+					break
+				}
+				old = h.SellPrice
+			}
+			if old > 0 {
+				item.PriceDiff = math.Round((current-old)*100) / 100
+				item.ChangeRate = math.Round((current-old)/old*10000) / 100
+				item.IsUp = current >= old
+			}
+			items = append(items, item)
+		}
+		result[name] = items
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/google/uuid"
+)
+
+// 同时执行的回测任务数，回测需要扫描大量历史数据
+var backtestSlots = make(chan struct{}, 2)
+
+// BacktestRequest 回测参数，平台为标识，为空时使用设置方案中的平台，再为空为悠悠 -> BUFF
+type BacktestRequest struct {
+	ProfileID uint
+	Source    string
+	Target    string
+	StartDate time.Time
+	EndDate   time.Time
+	HoldDays  int
+}
+
+// SubmitBacktest 创建回测任务并在后台执行，每个用户同时只能有一个未完成的任务
+func SubmitBacktest(userID uuid.UUID, req *BacktestRequest) (*models.BacktestJob, int) {
+	if models.CountActiveBacktestJobs(userID) > 0 {
+		return nil, utils.ErrCodeBacktestRunning
+	}
+
+	settings, code := models.GetUserSetting(userID.String())
+	if req.ProfileID > 0 {
+		settings, code = models.GetUserSettingProfile(userID.String(), req.ProfileID)
+	}
+	if code != utils.SUCCESS {
+		return nil, code
+	}
+
+	sourceKey, targetKey := req.Source, req.Target
+	if sourceKey == "" {
+		sourceKey = settings.SourcePlatform
+	}
+	if targetKey == "" {
+		targetKey = settings.TargetPlatform
+	}
+	if sourceKey == "" {
+		sourceKey = "uu"
+	}
+	if targetKey == "" {
+		targetKey = "buff"
+	}
+	source, ok := models.GetPlatformByKey(sourceKey)
+	target, ok2 := models.GetPlatformByKey(targetKey)
+	if !ok || !ok2 || source.Code == target.Code {
+		return nil, utils.ErrCodeInvalidParams
+	}
+
+	settingsJSON, _ := json.Marshal(settings)
+	job := &models.BacktestJob{
+		UserID:    userID,
+		ProfileID: settings.ID,
+		Source:    source.Code,
+		Target:    target.Code,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		HoldDays:  req.HoldDays,
+		Settings:  string(settingsJSON),
+	}
+	if code := models.CreateBacktestJob(job); code != utils.SUCCESS {
+		return nil, code
+	}
+
+	SafeGo(func() { runBacktestJob(job.ID, settings, source, target, req) })
+	return job, utils.SUCCESS
+}
+
+// runBacktestJob 等待执行名额后执行回测并保存结果
+func runBacktestJob(id uint, settings *models.SettingsResponse, source, target *models.PlatformDef, req *BacktestRequest) {
+	backtestSlots <- struct{}{}
+	defer func() { <-backtestSlots }()
+
+	models.MarkBacktestRunning(id)
+	begin := time.Now()
+	result, err := runBacktestSafely(settings, source, target, req)
+	models.FinishBacktestJob(id, result, err)
+	if err != nil {
+		config.Log.Errorf("Backtest job %d failed: %v", id, err)
+		return
+	}
+	config.Log.Infof("Backtest job %d finished in %v, %d opportunities", id, time.Since(begin), result.Opportunities)
+}
+
+// runBacktestSafely 执行回测，panic 时转换为错误，保证任务状态能被更新
+func runBacktestSafely(settings *models.SettingsResponse, source, target *models.PlatformDef, req *BacktestRequest) (result *models.BacktestResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("backtest panic: %v", r)
+		}
+	}()
+	return models.RunBacktest(settings, source, target, req.StartDate, req.EndDate, req.HoldDays)
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 	"uu/config"
 	"uu/models"
 	"uu/utils"
@@ -68,7 +70,7 @@ func GetBuffHeaders() map[string]string {
 	}
 }
 
-func GetBuffItems(pageSize, pageNum string) ([]*models.BuffItem, int, error) {
+func GetBuffItems(ctx context.Context, pageSize, pageNum string) ([]*models.BuffItem, int, error) {
 	header := GetBuffHeaders()
 	var buffResponse BuffResponse
 	var opt = utils.RequestOptions{
@@ -81,7 +83,7 @@ func GetBuffItems(pageSize, pageNum string) ([]*models.BuffItem, int, error) {
 		Headers: header,
 		Result:  &buffResponse,
 	}
-	res, err := BuffClient.DoRequest("GET", "api/market/goods", opt)
+	res, err := BuffClient.DoRequest(ctx, "GET", "api/market/goods", opt)
 	if err != nil || res.StatusCode() != 200 {
 		config.Log.Errorf("request buff api error : %s", err)
 	}
@@ -102,7 +104,7 @@ func VerifyBuffToken() {
 		Headers: header,
 		Result:  &buffResponse,
 	}
-	res, err := BuffClient.DoRequest("GET", "api/market/goods", opt)
+	res, err := BuffClient.DoRequest(context.Background(), "GET", "api/market/goods", opt)
 	if err != nil || res.StatusCode() != 200 || buffResponse.Code != "OK" {
 		config.Log.Warn("buff token expired")
 		buffToken.Expired = "yes"
@@ -115,7 +117,7 @@ func VerifyBuffToken() {
 	}
 }
 
-func GetBuffInventory() []*models.BuffInventory {
+func GetBuffInventory(ctx context.Context) []*models.BuffInventory {
 	header := GetBuffHeaders()
 	var buffResponse BuffInventoryResponse
 	var opt = utils.RequestOptions{
@@ -128,9 +130,36 @@ func GetBuffInventory() []*models.BuffInventory {
 		Headers: header,
 		Result:  &buffResponse,
 	}
-	res, err := BuffClient.DoRequest("GET", "api/market/steam_inventory", opt)
+	res, err := BuffClient.DoRequest(ctx, "GET", "api/market/steam_inventory", opt)
 	if err != nil || res.StatusCode() != 200 {
 		config.Log.Errorf("request buff inventory api error : %s", err)
 	}
 	return buffResponse.Data.Items
 }
+
+// buffSnapshotProvider BUFF 市场全量扫描（GetBuffItems）得到的报价，由 BUFF 全量更新任务刷新
+var buffSnapshotProvider = newQuoteSnapshot("buff", 90*time.Minute)
+
+// buffItemsToQuotes 将 BUFF 市场列表转换为报价
+func buffItemsToQuotes(items []*models.BuffItem) []*PriceQuote {
+	now := time.Now().Unix()
+	quotes := make([]*PriceQuote, 0, len(items))
+	for _, item := range items {
+		price, _ := strconv.ParseFloat(item.Price, 64)
+		if item.HashName == "" || price <= 0 {
+			continue
+		}
+		buyPrice, _ := strconv.ParseFloat(item.BuyPrice, 64)
+		quotes = append(quotes, &PriceQuote{
+			MarketHashName: item.HashName,
+			Platform:       "BUFF",
+			PlatformItemId: strconv.FormatInt(item.ID, 10),
+			SellPrice:      price,
+			SellCount:      item.Count,
+			BiddingPrice:   buyPrice,
+			BiddingCount:   item.BuyCount,
+			UpdateTime:     now,
+		})
+	}
+	return quotes
+}
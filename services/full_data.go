@@ -2,12 +2,14 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"uu/config"
 	"uu/models"
+	"uu/utils"
 
 	"golang.org/x/time/rate"
 )
@@ -21,20 +23,25 @@ var buffLimiter = rate.NewLimiter(rate.Every(2000*time.Millisecond), 1)
 var RequestDelay = time.Second * 10
 var newLimiter = rate.NewLimiter(rate.Every(310*time.Second), 1)
 
-func UpdateAllUUItems() {
-	_, total, _ := GetUUItems(20, 1)
+// UpdateAllUUItems 逐页扫描悠悠市场，ctx 取消时写完当前页后返回
+func UpdateAllUUItems(ctx context.Context) error {
+	_, total, _ := GetUUItems(ctx, 20, 1)
 	totalPages := total/UUMaxPageSize + 1
 	for page := 1; page <= totalPages+1; page++ {
-		if err := uuLimiter.Wait(context.Background()); err != nil {
-			config.Log.Errorf("wait limiter error: %v", err)
-			return
+		if err := uuLimiter.Wait(ctx); err != nil {
+			return fmt.Errorf("wait uu limiter: %w", err)
 		}
 
-		items, _, err := GetUUItems(UUMaxPageSize, page)
+		items, _, err := GetUUItems(ctx, UUMaxPageSize, page)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if isRateLimitError(err) {
 				// 动态退避：遇到429时增加延迟
-				handleRateLimitError()
+				if err := handleRateLimitError(ctx, "uu"); err != nil {
+					return err
+				}
 				page-- // 重试当前页
 				continue
 			}
@@ -42,8 +49,10 @@ func UpdateAllUUItems() {
 			continue
 		}
 		models.BatchAddUUItem(items)
+		uuSnapshotProvider.put(uuItemsToQuotes(items))
 		//config.Log.Infof("Full Update uu item pageName: %d, success", page)
 	}
+	return nil
 }
 
 // 判断是否为速率限制错误
@@ -52,25 +61,31 @@ func isRateLimitError(err error) bool {
 		strings.Contains(err.Error(), "too many requests")
 }
 
-func handleRateLimitError() {
-	config.Log.Warnf("limits sleep %v", RequestDelay)
-	time.Sleep(RequestDelay)
+func handleRateLimitError(ctx context.Context, source string) error {
+	utils.UpstreamRateLimitedTotal.WithLabelValues(source).Inc()
+	config.Log.Warnf("%s limits sleep %v", source, RequestDelay)
+	return sleepContext(ctx, RequestDelay)
 }
 
-func UpdateAllBuffItems() {
-	_, total, _ := GetBuffItems("20", "1")
+// UpdateAllBuffItems 逐页扫描 BUFF 市场，ctx 取消时写完当前页后返回
+func UpdateAllBuffItems(ctx context.Context) error {
+	_, total, _ := GetBuffItems(ctx, "20", "1")
 	size, _ := strconv.Atoi(BuffMaxPageSize)
 	pageNum := total/size + 1
 	for i := 1; i <= pageNum+1; i++ {
-		if err := buffLimiter.Wait(context.Background()); err != nil {
-			config.Log.Errorf("wait buff limiter error: %v", err)
-			return
+		if err := buffLimiter.Wait(ctx); err != nil {
+			return fmt.Errorf("wait buff limiter: %w", err)
 		}
-		items, _, err := GetBuffItems(BuffMaxPageSize, strconv.Itoa(i))
+		items, _, err := GetBuffItems(ctx, BuffMaxPageSize, strconv.Itoa(i))
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if isRateLimitError(err) {
 				// 动态退避：遇到429时增加延迟
-				handleRateLimitError()
+				if err := handleRateLimitError(ctx, "buff"); err != nil {
+					return err
+				}
 				i-- // 重试当前页
 				continue
 			}
@@ -79,10 +94,13 @@ func UpdateAllBuffItems() {
 		}
 		config.Log.Infof("Full Update buff item pageName: %d, success", i)
 		models.BatchAddBuffItem(items)
+		buffSnapshotProvider.put(buffItemsToQuotes(items))
 	}
+	return nil
 }
 
-func UpdateUUGoods() {
+// UpdateUUGoods 更新饰品图标，返回处理的饰品数
+func UpdateUUGoods(ctx context.Context) (int, error) {
 	hashNames := models.QueryAllUUHashName()
 	n := len(hashNames) / 200
 	remainder := len(hashNames) % 200
@@ -90,16 +108,16 @@ func UpdateUUGoods() {
 		n++
 	}
 	for i := 0; i < n; i++ {
-		if err := newLimiter.Wait(context.Background()); err != nil {
-			config.Log.Errorf("wait limiter error: %v", err)
-			return
+		if err := newLimiter.Wait(ctx); err != nil {
+			return i * 200, fmt.Errorf("wait limiter: %w", err)
 		}
 		start := i * 200
-		end := start + 200
-		goods := GetUUGoods(hashNames[start:end])
+		end := min(start+200, len(hashNames))
+		goods := GetUUGoods(ctx, hashNames[start:end])
 		models.BatchUpdateIcon(goods)
 	}
 	models.UpdateBaseGoodsIcon()
+	return len(hashNames), nil
 }
 
 //func UpdateFullData() int {
@@ -117,29 +135,30 @@ func UpdateUUGoods() {
 //	return utils.SUCCESS
 //}
 
-func UpdateInventory() {
-	uu := GetUUInventory()
-	models.BatchAddUUInventory(uu)
-	buff := GetBuffInventory()
-	models.BatchAddBuffInventory(buff)
-}
-
-func UpdateUUFullData() {
+func UpdateUUFullData(ctx context.Context) error {
 	if !taskUU.TryLock() {
 		config.Log.Info("uu full update running")
+		return nil
 	}
 	defer taskUU.Unlock()
 	config.Log.Info("Start uu full update")
-	UpdateAllUUItems()
+	if err := UpdateAllUUItems(ctx); err != nil {
+		return err
+	}
 	config.Log.Info("uu full update completed")
+	return nil
 }
 
-func UpdateBuffFullData() {
+func UpdateBuffFullData(ctx context.Context) error {
 	if !taskBuff.TryLock() {
 		config.Log.Info("buff full update running")
+		return nil
 	}
 	defer taskBuff.Unlock()
 	config.Log.Info("Start buff full update")
-	UpdateAllBuffItems()
+	if err := UpdateAllBuffItems(ctx); err != nil {
+		return err
+	}
 	config.Log.Info("buff full update completed")
+	return nil
 }
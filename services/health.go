@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/models"
+)
+
+// 检查结果
+const (
+	HealthOK   = "ok"
+	HealthWarn = "warn" // 不影响就绪，需要关注
+	HealthFail = "fail"
+)
+
+// 就绪检查参数
+const (
+	healthCheckTimeout = 3 * time.Second
+	maxPlatformDataAge = 15 * time.Minute // 行情超过该时长未成功更新视为停止工作
+)
+
+// startTime 进程启动时间
+var startTime = time.Now()
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`   // ok / warn / fail
+	Critical bool   `json:"critical"` // 失败时实例不可用
+	Latency  int64  `json:"latency"`  // 检查耗时（毫秒）
+	Message  string `json:"message,omitempty"`
+	Age      *int64 `json:"age,omitempty"` // 行情距最近一次成功更新的秒数
+}
+
+// ReadinessReport 就绪检查结果，关键检查全部通过时 Ready 为 true
+type ReadinessReport struct {
+	Ready  bool           `json:"ready"`
+	Uptime int64          `json:"uptime"` // 进程运行秒数
+	Checks []*HealthCheck `json:"checks"`
+}
+
+// Uptime 进程运行秒数
+func Uptime() int64 {
+	return int64(time.Since(startTime).Seconds())
+}
+
+// CheckReadiness 并行检查 MySQL、Redis、行情更新和悠悠/BUFF token
+// 行情和 token 只影响数据质量，结果为 warn，不影响就绪
+func CheckReadiness(ctx context.Context) *ReadinessReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := []*HealthCheck{
+		{Name: "mysql", Critical: true},
+		{Name: "redis", Critical: true},
+		{Name: "platform_data"},
+		{Name: "uu_token"},
+		{Name: "buff_token"},
+	}
+	funcs := []func(context.Context, *HealthCheck){checkMysql, checkRedis, checkPlatformData, checkUUToken, checkBuffToken}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(check *HealthCheck, fn func(context.Context, *HealthCheck)) {
+			defer wg.Done()
+			start := time.Now()
+			fn(ctx, check)
+			check.Latency = time.Since(start).Milliseconds()
+		}(checks[i], funcs[i])
+	}
+	wg.Wait()
+
+	report := &ReadinessReport{Ready: true, Uptime: Uptime(), Checks: checks}
+	for _, check := range checks {
+		if check.Critical && check.Status == HealthFail {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func checkMysql(ctx context.Context, check *HealthCheck) {
+	db, err := config.DB.DB()
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	setCheckError(check, err)
+}
+
+func checkRedis(ctx context.Context, check *HealthCheck) {
+	setCheckError(check, config.RDB.Ping(ctx).Err())
+}
+
+func checkPlatformData(_ context.Context, check *HealthCheck) {
+	run, err := models.GetLastSuccessJobRun(JobPlatformData)
+	if err != nil {
+		check.Status, check.Message = HealthWarn, err.Error()
+		return
+	}
+	if run == nil || run.EndTime == nil {
+		check.Status, check.Message = HealthWarn, "no successful run yet"
+		return
+	}
+	age := int64(time.Since(*run.EndTime).Seconds())
+	check.Age = &age
+	check.Status = HealthOK
+	if time.Duration(age)*time.Second > maxPlatformDataAge {
+		check.Status = HealthWarn
+		check.Message = fmt.Sprintf("last successful update %s ago", time.Duration(age)*time.Second)
+	}
+}
+
+func checkUUToken(_ context.Context, check *HealthCheck) {
+	var token models.UUToken
+	token.GetUUExpired()
+	setTokenStatus(check, token.Expired)
+}
+
+func checkBuffToken(_ context.Context, check *HealthCheck) {
+	var token models.BuffToken
+	token.GetBuffExpired()
+	setTokenStatus(check, token.Expired)
+}
+
+// setTokenStatus 按 token 校验任务记录的 expired 标记设置结果
+func setTokenStatus(check *HealthCheck, expired string) {
+	switch expired {
+	case "no":
+		check.Status = HealthOK
+	case "yes":
+		check.Status, check.Message = HealthWarn, "token expired"
+	default:
+		check.Status, check.Message = HealthWarn, "token not verified"
+	}
+}
+
+func setCheckError(check *HealthCheck, err error) {
+	if err != nil {
+		check.Status, check.Message = HealthFail, err.Error()
+		return
+	}
+	check.Status = HealthOK
+}
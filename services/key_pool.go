@@ -0,0 +1,287 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+)
+
+// key 结果与退避参数
+const (
+	keyBackoffBase     = 30 * time.Second
+	keyBackoffMax      = 30 * time.Minute
+	keyMaxConsecutive  = 10  // 连续失败达到该次数自动禁用
+	keyScoreDecay      = 0.8 // 健康分滑动系数，越小越看重最近的结果
+	keyErrorMessageLen = 255
+)
+
+// keyResult 一次请求对 key 健康的影响
+type keyResult int
+
+const (
+	keySuccess      keyResult = iota
+	keyFailed                 // 请求失败，指数退避，连续失败过多自动禁用
+	keyRateLimited            // 4005，当前窗口额度作废并退避
+	keyUnauthorized           // 401/403，key 无效，立即禁用
+	keyReleased               // 未实际消耗（如服务退出取消请求），不计入健康
+)
+
+// KeyPool SteamDT API key 池，按额度窗口限流，按请求结果退避或禁用
+// 每次取可用 key 中最久未使用的一个，一轮扫描的批次均匀分摊到所有健康的 key
+type KeyPool struct {
+	mu   sync.Mutex
+	keys []*models.APIKey
+}
+
+// APIKeyStatus 后台展示的 key 状态，key 只显示首尾几位
+type APIKeyStatus struct {
+	*models.APIKey
+	MaskedKey string `json:"key"`
+	Available bool   `json:"available"` // 当前可以被选中
+}
+
+var keyPool = &KeyPool{}
+
+// InitKeyPool 从数据库加载 key
+func InitKeyPool() {
+	keys, err := models.GetAPIKeys()
+	if err != nil {
+		config.Log.Errorf("Load api keys error: %v", err)
+		return
+	}
+	keyPool.mu.Lock()
+	keyPool.keys = keys
+	keyPool.mu.Unlock()
+	config.Log.Infof("Load %d api keys", len(keys))
+}
+
+// acquire 取一个可用的 key 并计入当前窗口用量，没有可用 key 时返回 nil
+func (p *KeyPool) acquire() *models.APIKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var best *models.APIKey
+	for _, k := range p.keys {
+		if !keyAvailable(k, now) {
+			continue
+		}
+		if best == nil || lessRecentlyUsed(k, best) {
+			best = k
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	if windowExpired(best, now) {
+		best.WindowStart = &now
+		best.Used = 0
+	}
+	best.Used++
+	best.LastUsed = &now
+	p.save(best)
+	return best
+}
+
+// report 记录 key 的请求结果，更新健康分、退避和禁用状态
+func (p *KeyPool) report(key *models.APIKey, result keyResult, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	switch result {
+	case keyReleased:
+		if key.Used > 0 {
+			key.Used--
+		}
+		p.save(key)
+		return
+	case keySuccess:
+		key.SuccessCount++
+		key.Consecutive = 0
+		key.BackoffUntil = nil
+		key.LastError = ""
+		key.Score = key.Score*keyScoreDecay + 100*(1-keyScoreDecay)
+		p.save(key)
+		return
+	}
+
+	key.Consecutive++
+	key.Score = key.Score * keyScoreDecay
+	if err != nil {
+		key.LastError = truncate(err.Error(), keyErrorMessageLen)
+	}
+	backoff := keyBackoffBase * time.Duration(math.Pow(2, float64(key.Consecutive-1)))
+	if backoff <= 0 || backoff > keyBackoffMax {
+		backoff = keyBackoffMax
+	}
+
+	switch result {
+	case keyRateLimited:
+		key.RateLimitCount++
+		// 额度已用完，至少等到当前窗口结束
+		key.Used = key.Quota
+		if key.WindowStart != nil {
+			if wait := key.WindowStart.Add(time.Duration(key.Window) * time.Second).Sub(now); wait > backoff {
+				backoff = wait
+			}
+		}
+	case keyUnauthorized:
+		key.FailCount++
+		disableKey(key, "unauthorized")
+	default:
+		key.FailCount++
+		if key.Consecutive >= keyMaxConsecutive {
+			disableKey(key, "too many consecutive failures")
+		}
+	}
+	until := now.Add(backoff)
+	key.BackoffUntil = &until
+	if key.Status == models.APIKeyDisabled {
+		config.Log.Warnf("Api key %s disabled: %s", key.MaskedKey(), key.DisabledReason)
+	}
+	p.save(key)
+}
+
+// save 持久化 key 状态，调用方需持有锁
+func (p *KeyPool) save(key *models.APIKey) {
+	if err := models.SaveAPIKeyState(key); err != nil {
+		config.Log.Errorf("Save api key %d state error: %v", key.ID, err)
+	}
+}
+
+// List 所有 key 的状态
+func (p *KeyPool) List() []*APIKeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	result := make([]*APIKeyStatus, 0, len(p.keys))
+	for _, k := range p.keys {
+		copied := *k
+		result = append(result, &APIKeyStatus{APIKey: &copied, MaskedKey: k.MaskedKey(), Available: keyAvailable(k, now)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Add 新增 key，quota / window 不大于 0 时使用默认额度
+func (p *KeyPool) Add(key, remark string, quota, window int) int {
+	if quota <= 0 {
+		quota = models.DefaultKeyQuota
+	}
+	if window <= 0 {
+		window = models.DefaultKeyWindow
+	}
+	k := &models.APIKey{Key: key, Remark: remark, Status: models.APIKeyActive, Quota: quota, Window: window, Score: 100}
+	if code := models.CreateAPIKey(k); code != utils.SUCCESS {
+		return code
+	}
+	p.mu.Lock()
+	p.keys = append(p.keys, k)
+	p.mu.Unlock()
+	return utils.SUCCESS
+}
+
+// Remove 删除 key
+func (p *KeyPool) Remove(id uint) int {
+	if code := models.DeleteAPIKey(id); code != utils.SUCCESS {
+		return code
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, k := range p.keys {
+		if k.ID == id {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			break
+		}
+	}
+	return utils.SUCCESS
+}
+
+// SetEnabled 手动禁用或启用 key，启用时清除退避和连续失败次数
+func (p *KeyPool) SetEnabled(id uint, enabled bool) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.ID != id {
+			continue
+		}
+		if enabled {
+			k.Status = models.APIKeyActive
+			k.DisabledReason = ""
+			k.Consecutive = 0
+			k.BackoffUntil = nil
+		} else {
+			disableKey(k, "disabled by admin")
+		}
+		if err := models.SaveAPIKeyState(k); err != nil {
+			config.Log.Errorf("Save api key %d state error: %v", id, err)
+			return utils.ErrCodeUpdateAPIKey
+		}
+		return utils.SUCCESS
+	}
+	return utils.ErrCodeAPIKeyNotFound
+}
+
+// keyAvailable key 已启用、不在退避中且当前窗口还有额度
+func keyAvailable(k *models.APIKey, now time.Time) bool {
+	if k.Status == models.APIKeyDisabled {
+		return false
+	}
+	if k.BackoffUntil != nil && now.Before(*k.BackoffUntil) {
+		return false
+	}
+	return windowExpired(k, now) || k.Used < k.Quota
+}
+
+func windowExpired(k *models.APIKey, now time.Time) bool {
+	return k.WindowStart == nil || !now.Before(k.WindowStart.Add(time.Duration(k.Window)*time.Second))
+}
+
+// lessRecentlyUsed a 比 b 更久未使用，相同时健康分高的优先
+func lessRecentlyUsed(a, b *models.APIKey) bool {
+	if a.LastUsed == nil || b.LastUsed == nil {
+		if a.LastUsed == nil && b.LastUsed == nil {
+			return a.Score > b.Score
+		}
+		return a.LastUsed == nil
+	}
+	if !a.LastUsed.Equal(*b.LastUsed) {
+		return a.LastUsed.Before(*b.LastUsed)
+	}
+	return a.Score > b.Score
+}
+
+func disableKey(k *models.APIKey, reason string) {
+	k.Status = models.APIKeyDisabled
+	k.DisabledReason = reason
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// ListAPIKeys 所有 key 的状态
+func ListAPIKeys() []*APIKeyStatus {
+	return keyPool.List()
+}
+
+// AddAPIKey 新增 key
+func AddAPIKey(key, remark string, quota, window int) int {
+	return keyPool.Add(key, remark, quota, window)
+}
+
+// RemoveAPIKey 删除 key
+func RemoveAPIKey(id uint) int {
+	return keyPool.Remove(id)
+}
+
+// SetAPIKeyEnabled 禁用或启用 key
+func SetAPIKeyEnabled(id uint, enabled bool) int {
+	return keyPool.SetEnabled(id, enabled)
+}
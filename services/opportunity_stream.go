@@ -0,0 +1,115 @@
+package services
+
+import (
+	"sync"
+	"uu/models"
+)
+
+// MaxStreamsPerUser 每个用户同时保持的机会推送连接数上限
+const MaxStreamsPerUser = 3
+
+// opportunityHub 管理搬砖机会推送的订阅者，每轮行情更新后通知所有订阅者重新计算
+type opportunityHub struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]string // 通知通道 -> 用户ID
+	closing     chan struct{}            // 服务退出时关闭，所有推送连接随之结束
+	closeOnce   sync.Once
+}
+
+var opportunities = &opportunityHub{
+	subscribers: make(map[chan struct{}]string),
+	closing:     make(chan struct{}),
+}
+
+// OpportunityStreamsClosing 服务退出时关闭的通道，推送连接收到后应结束
+func OpportunityStreamsClosing() <-chan struct{} {
+	return opportunities.closing
+}
+
+// CloseOpportunityStreams 结束所有机会推送连接，在 http.Server 的 RegisterOnShutdown 中调用
+// 长连接不会自行变为空闲，不结束的话 Shutdown 会一直等到超时
+func CloseOpportunityStreams() {
+	opportunities.closeOnce.Do(func() { close(opportunities.closing) })
+}
+
+// SubscribeOpportunities 订阅行情更新通知，超过连接数上限时返回 false
+// 返回的 cancel 必须在连接结束时调用
+func SubscribeOpportunities(userId string) (<-chan struct{}, func(), bool) {
+	opportunities.mu.Lock()
+	defer opportunities.mu.Unlock()
+
+	var count int
+	for _, id := range opportunities.subscribers {
+		if id == userId {
+			count++
+		}
+	}
+	if count >= MaxStreamsPerUser {
+		return nil, nil, false
+	}
+
+	// 缓冲为 1：订阅者处理较慢时多轮更新合并为一次
+	ch := make(chan struct{}, 1)
+	opportunities.subscribers[ch] = userId
+	cancel := func() {
+		opportunities.mu.Lock()
+		delete(opportunities.subscribers, ch)
+		opportunities.mu.Unlock()
+	}
+	return ch, cancel, true
+}
+
+// publishOpportunityUpdate 通知所有订阅者行情已更新，不阻塞
+func publishOpportunityUpdate() {
+	opportunities.mu.Lock()
+	defer opportunities.mu.Unlock()
+	for ch := range opportunities.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// OpportunityChange 价格发生变化的搬砖机会
+type OpportunityChange struct {
+	Before models.Goods `json:"before"`
+	After  models.Goods `json:"after"`
+}
+
+// OpportunityDiff 两轮之间搬砖机会的变化
+type OpportunityDiff struct {
+	Added   []models.Goods      `json:"added"`
+	Removed []string            `json:"removed"` // market_hash_name
+	Changed []OpportunityChange `json:"changed"`
+}
+
+// Empty 是否没有任何变化
+func (d *OpportunityDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffOpportunities 比较两轮的搬砖机会，key: market_hash_name
+func DiffOpportunities(prev, cur map[string]models.Goods) *OpportunityDiff {
+	diff := &OpportunityDiff{
+		Added:   []models.Goods{},
+		Removed: []string{},
+		Changed: []OpportunityChange{},
+	}
+	for name, g := range cur {
+		old, ok := prev[name]
+		if !ok {
+			diff.Added = append(diff.Added, g)
+			continue
+		}
+		if old.SourcePrice != g.SourcePrice || old.TargetPrice != g.TargetPrice || old.NetProfit != g.NetProfit {
+			diff.Changed = append(diff.Changed, OpportunityChange{Before: old, After: g})
+		}
+	}
+	for name := range prev {
+		if _, ok := cur[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	return diff
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
@@ -83,7 +84,7 @@ func VerifySign(params map[string]string, sign, apiKey string) bool {
 // totalFee: 支付金额（元）
 // body: 商品描述
 // attach: 附加数据，回调时原样返回
-func CreateNativePay(outTradeNo string, totalFee float64, body, attach string) (*NativePayResponse, error) {
+func CreateNativePay(ctx context.Context, outTradeNo string, totalFee float64, body, attach string) (*NativePayResponse, error) {
 	paymentConfig := config.CONFIG.Payment
 	if paymentConfig == nil {
 		return nil, fmt.Errorf("payment config not found")
@@ -118,7 +119,7 @@ func CreateNativePay(outTradeNo string, totalFee float64, body, attach string) (
 	config.Log.Infof("NativePay request: out_trade_no=%s, total_fee=%.2f, body=%s", outTradeNo, totalFee, body)
 
 	var result NativePayResponse
-	resp, err := payClient.DoRequest("POST", NativePayPath, utils.RequestOptions{
+	resp, err := payClient.DoRequest(ctx, "POST", NativePayPath, utils.RequestOptions{
 		FormData: requestParams,
 		Result:   &result,
 	})
@@ -139,7 +140,7 @@ func CreateNativePay(outTradeNo string, totalFee float64, body, attach string) (
 // body: 商品描述
 // openId: 用户openid
 // attach: 附加数据，回调时原样返回
-func CreateMinAppPay(outTradeNo string, totalFee float64, body, openId, attach string) (*MinAppPayResponse, error) {
+func CreateMinAppPay(ctx context.Context, outTradeNo string, totalFee float64, body, openId, attach string) (*MinAppPayResponse, error) {
 	paymentConfig := config.CONFIG.Payment
 	if paymentConfig == nil {
 		return nil, fmt.Errorf("payment config not found")
@@ -182,7 +183,7 @@ func CreateMinAppPay(outTradeNo string, totalFee float64, body, openId, attach s
 	config.Log.Infof("MinAppPay request: out_trade_no=%s, total_fee=%.2f, body=%s, openId=%s, app_id=%s", outTradeNo, totalFee, body, openId, wechatConfig.AppID)
 
 	var result MinAppPayResponse
-	resp, err := payClient.DoRequest("POST", MinAppPayPath, utils.RequestOptions{
+	resp, err := payClient.DoRequest(ctx, "POST", MinAppPayPath, utils.RequestOptions{
 		FormData: requestParams,
 		Result:   &result,
 	})
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+	"uu/models"
+	"uu/utils"
+
+	"github.com/google/uuid"
+)
+
+// ImportInventory 从悠悠或 BUFF 库存导入持仓
+// 库存接口使用系统配置的平台账号，不是用户自己的库存；买入价无从得知，留空（0）由用户补填，买入日期为导入当天
+func ImportInventory(ctx context.Context, userID uuid.UUID, source string) (int, int) {
+	today := time.Now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+
+	var items []*models.Holding
+	switch source {
+	case models.HoldingSourceUU:
+		for _, item := range GetUUInventory(ctx) {
+			if item == nil || item.MarketHashName == "" {
+				continue
+			}
+			quantity := item.AssetMergeCount
+			if quantity <= 0 {
+				quantity = 1
+			}
+			items = append(items, &models.Holding{
+				MarketHashName: item.MarketHashName,
+				Quantity:       quantity,
+				BuyPlatform:    "YOUPIN",
+				BuyDate:        today,
+			})
+		}
+	case models.HoldingSourceBuff:
+		// BUFF 折叠库存按饰品合并，同名饰品累加数量
+		merged := make(map[string]*models.Holding)
+		for _, item := range GetBuffInventory(ctx) {
+			if item == nil || item.MarketHashName == "" {
+				continue
+			}
+			if h, ok := merged[item.MarketHashName]; ok {
+				h.Quantity++
+				continue
+			}
+			h := &models.Holding{
+				MarketHashName: item.MarketHashName,
+				Quantity:       1,
+				BuyPlatform:    "BUFF",
+				BuyDate:        today,
+			}
+			merged[item.MarketHashName] = h
+			items = append(items, h)
+		}
+	default:
+		return 0, utils.ErrCodeInvalidParams
+	}
+	if len(items) == 0 {
+		return 0, utils.ErrCodeImportInventory
+	}
+	return models.ImportHoldings(userID, source, items)
+}
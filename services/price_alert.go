@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+)
+
+// alertEvaluating 是否有一轮预警评估正在进行，避免两轮同时触发同一预警
+var alertEvaluating atomic.Bool
+
+// EvaluatePriceAlerts 评估所有启用的价格预警，在每轮 UpdateAllPlatformData 之后于后台执行
+// ctx 取消后不再处理剩余的预警；上一轮尚未结束时直接跳过
+func EvaluatePriceAlerts(ctx context.Context) {
+	if !alertEvaluating.CompareAndSwap(false, true) {
+		return
+	}
+	defer alertEvaluating.Store(false)
+
+	alerts, err := models.GetEnabledPriceAlerts()
+	if err != nil {
+		config.Log.Errorf("Get enabled price alerts error: %v", err)
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	// 按平台收集需要查询的饰品，每个平台只查一次
+	platformNames := make(map[string][]string)
+	for i := range alerts {
+		if alerts[i].InCooldown(now) {
+			continue
+		}
+		for _, p := range alertPlatforms(&alerts[i]) {
+			platformNames[p] = append(platformNames[p], alerts[i].MarketHashName)
+		}
+	}
+	if len(platformNames) == 0 {
+		return
+	}
+
+	prices := make(map[string]map[string]float64)
+	for platform, hashNames := range platformNames {
+		if ctx.Err() != nil {
+			return
+		}
+		prices[platform] = models.GetPlatformSellPrices(platform, hashNames)
+	}
+
+	var triggered int
+	for i := range alerts {
+		if ctx.Err() != nil {
+			break
+		}
+		alert := &alerts[i]
+		if alert.InCooldown(now) {
+			continue
+		}
+		value, ok := evaluateAlert(alert, prices)
+		if !ok {
+			continue
+		}
+		deliverAlert(ctx, alert, value)
+		triggered++
+	}
+	if triggered > 0 {
+		config.Log.Infof("Price alerts triggered: %d", triggered)
+	}
+}
+
+// alertPlatforms 预警需要读取价格的平台
+func alertPlatforms(alert *models.PriceAlert) []string {
+	if alert.AlertType == models.AlertTypeSpreadAbove {
+		return []string{alert.SourcePlatform, alert.TargetPlatform}
+	}
+	return []string{alert.Platform}
+}
+
+// evaluateAlert 判断预警是否满足触发条件，返回触发时的价格或价差百分比
+func evaluateAlert(alert *models.PriceAlert, prices map[string]map[string]float64) (float64, bool) {
+	switch alert.AlertType {
+	case models.AlertTypePriceBelow:
+		price := prices[alert.Platform][alert.MarketHashName]
+		return price, price > 0 && price < alert.Threshold
+	case models.AlertTypePriceAbove:
+		price := prices[alert.Platform][alert.MarketHashName]
+		return price, price > 0 && price > alert.Threshold
+	case models.AlertTypeSpreadAbove:
+		source := prices[alert.SourcePlatform][alert.MarketHashName]
+		target := prices[alert.TargetPlatform][alert.MarketHashName]
+		if source <= 0 || target <= 0 {
+			return 0, false
+		}
+		spread := math.Round((target-source)/source*10000) / 100
+		return spread, spread > alert.Threshold
+	}
+	return 0, false
+}
+
+// alertMessage 生成预警通知的标题和内容
+func alertMessage(alert *models.PriceAlert, value float64) (string, string) {
+	switch alert.AlertType {
+	case models.AlertTypePriceBelow:
+		return "价格预警：" + alert.MarketHashName,
+			fmt.Sprintf("%s 在 %s 的在售价已降至 ¥%.2f，低于您设置的 ¥%.2f",
+				alert.MarketHashName, platformName(alert.Platform), value, alert.Threshold)
+	case models.AlertTypePriceAbove:
+		return "价格预警：" + alert.MarketHashName,
+			fmt.Sprintf("%s 在 %s 的在售价已升至 ¥%.2f，高于您设置的 ¥%.2f",
+				alert.MarketHashName, platformName(alert.Platform), value, alert.Threshold)
+	default:
+		return "价差预警：" + alert.MarketHashName,
+			fmt.Sprintf("%s 从 %s 到 %s 的价差已达 %.2f%%，超过您设置的 %.2f%%",
+				alert.MarketHashName, platformName(alert.SourcePlatform),
+				platformName(alert.TargetPlatform), value, alert.Threshold)
+	}
+}
+
+// deliverAlert 发送站内通知并记录触发历史，开启邮件通知时在后台发送邮件
+func deliverAlert(ctx context.Context, alert *models.PriceAlert, value float64) {
+	title, content := alertMessage(alert, value)
+
+	if _, code := models.CreateUserNotification(alert.UserID, title, content); code != utils.SUCCESS {
+		config.Log.Errorf("Deliver price alert %d notification failed", alert.ID)
+	}
+
+	trigger := &models.AlertTrigger{
+		AlertID:        alert.ID,
+		UserID:         alert.UserID,
+		MarketHashName: alert.MarketHashName,
+		AlertType:      alert.AlertType,
+		Value:          value,
+		Threshold:      alert.Threshold,
+		Message:        content,
+	}
+	if err := models.RecordAlertTrigger(alert, trigger); err != nil {
+		config.Log.Errorf("Record price alert %d trigger error: %v", alert.ID, err)
+		return
+	}
+
+	if alert.EmailEnabled && config.CONFIG.Email != nil {
+		alertID, userID := alert.ID, alert.UserID.String()
+		SafeGo(func() { sendAlertEmail(ctx, alertID, trigger.ID, userID, title, content) })
+	}
+}
+
+// sendAlertEmail 发送预警邮件，成功后标记触发记录；ctx 已取消时不再发送
+func sendAlertEmail(ctx context.Context, alertID, triggerID uint, userID, title, content string) {
+	if ctx.Err() != nil {
+		return
+	}
+	user, code := models.GetUserById(userID)
+	if code != utils.SUCCESS || user.Email == "" {
+		return
+	}
+	if config.CONFIG.Email.SendPriceAlert(user.Email, title, content) != utils.SUCCESS {
+		config.Log.Warnf("Send price alert %d email to %s failed", alertID, user.Email)
+		return
+	}
+	if err := models.MarkAlertEmailSent(triggerID); err != nil {
+		config.Log.Errorf("Mark price alert %d email sent error: %v", alertID, err)
+	}
+}
+
+// platformName 平台显示名称
+func platformName(key string) string {
+	if p, ok := models.GetPlatformByKey(key); ok {
+		return p.Name
+	}
+	return key
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"uu/config"
+)
+
+// PriceQuote 某饰品在某个平台的一条报价，与数据源无关
+type PriceQuote struct {
+	MarketHashName string
+	Platform       string // 平台代码：YOUPIN, BUFF, C5, STEAM
+	PlatformItemId string
+	SellPrice      float64
+	SellCount      int64
+	BiddingPrice   float64
+	BiddingCount   int64
+	UpdateTime     int64
+	Currency       string // 报价币种，为空表示平台默认币种
+}
+
+// PriceProvider 行情数据源，按批次获取一组饰品的报价
+type PriceProvider interface {
+	// Name 数据源名称，与配置 priceProviders.enabled 中的名称对应
+	Name() string
+	// BatchSize 单次 FetchQuotes 最多传入的饰品数量，<= 0 表示不限制
+	BatchSize() int
+	// FetchQuotes 获取一批饰品在各平台的报价，一个饰品可以返回多个平台
+	FetchQuotes(ctx context.Context, hashNames []string) ([]*PriceQuote, error)
+}
+
+// errProviderExhausted 数据源额度耗尽（如没有可用的 API key），本轮不再继续请求
+var errProviderExhausted = errors.New("price provider exhausted")
+
+// 所有已实现的数据源
+var priceProviders = map[string]PriceProvider{
+	"steamdt": steamDTProvider{},
+	"uu":      uuSnapshotProvider,
+	"buff":    buffSnapshotProvider,
+}
+
+// isProviderEnabled 数据源是否在配置中启用
+func isProviderEnabled(name string) bool {
+	for _, p := range enabledProviders() {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledProviders 按配置顺序返回启用的数据源，未配置时只使用 SteamDT
+func enabledProviders() []PriceProvider {
+	names := []string{"steamdt"}
+	if config.CONFIG.PriceProviders != nil && len(config.CONFIG.PriceProviders.Enabled) > 0 {
+		names = config.CONFIG.PriceProviders.Enabled
+	}
+	var providers []PriceProvider
+	for _, name := range names {
+		p, ok := priceProviders[name]
+		if !ok {
+			config.Log.Warnf("Unknown price provider: %s", name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// MergeQuotes 合并多个数据源的报价：同一饰品同一平台保留更新时间最新的一条
+// 返回 market_hash_name -> platform -> quote
+func MergeQuotes(quotes []*PriceQuote) map[string]map[string]*PriceQuote {
+	merged := make(map[string]map[string]*PriceQuote)
+	for _, q := range quotes {
+		if q == nil || q.MarketHashName == "" || q.Platform == "" {
+			continue
+		}
+		platforms := merged[q.MarketHashName]
+		if platforms == nil {
+			platforms = make(map[string]*PriceQuote)
+			merged[q.MarketHashName] = platforms
+		}
+		if old, exists := platforms[q.Platform]; exists && old.UpdateTime >= q.UpdateTime {
+			continue
+		}
+		platforms[q.Platform] = q
+	}
+	return merged
+}
+
+// quoteSnapshot 全量扫描得到的报价快照，供只能按页拉取的数据源按名称查询
+type quoteSnapshot struct {
+	name      string
+	batchSize int
+	maxAge    time.Duration // 超过该时长的报价视为过期，不再返回
+	mu        sync.RWMutex
+	quotes    map[string]*PriceQuote
+}
+
+func newQuoteSnapshot(name string, maxAge time.Duration) *quoteSnapshot {
+	return &quoteSnapshot{
+		name:   name,
+		maxAge: maxAge,
+		quotes: make(map[string]*PriceQuote),
+	}
+}
+
+func (s *quoteSnapshot) Name() string {
+	return s.name
+}
+
+func (s *quoteSnapshot) BatchSize() int {
+	return s.batchSize
+}
+
+// FetchQuotes 从快照中读取报价，快照中没有或已过期的饰品不返回
+func (s *quoteSnapshot) FetchQuotes(_ context.Context, hashNames []string) ([]*PriceQuote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	minTime := time.Now().Add(-s.maxAge).Unix()
+	quotes := make([]*PriceQuote, 0, len(hashNames))
+	for _, name := range hashNames {
+		if q, ok := s.quotes[name]; ok && q.UpdateTime >= minTime {
+			quotes = append(quotes, q)
+		}
+	}
+	return quotes, nil
+}
+
+// put 写入一页扫描结果
+func (s *quoteSnapshot) put(quotes []*PriceQuote) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range quotes {
+		s.quotes[q.MarketHashName] = q
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeProvider 按预设报价返回的数据源，记录每次请求的饰品
+type fakeProvider struct {
+	name   string
+	size   int
+	quotes map[string][]*PriceQuote
+	calls  [][]string
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) BatchSize() int { return p.size }
+
+func (p *fakeProvider) FetchQuotes(_ context.Context, hashNames []string) ([]*PriceQuote, error) {
+	p.calls = append(p.calls, hashNames)
+	var quotes []*PriceQuote
+	for _, name := range hashNames {
+		quotes = append(quotes, p.quotes[name]...)
+	}
+	return quotes, nil
+}
+
+func TestMergeQuotesOverlappingProviders(t *testing.T) {
+	steamdt := &fakeProvider{name: "steamdt", size: 1, quotes: map[string][]*PriceQuote{
+		"AK-47 | Redline (Field-Tested)": {
+			{MarketHashName: "AK-47 | Redline (Field-Tested)", Platform: "YOUPIN", SellPrice: 100, UpdateTime: 10},
+			{MarketHashName: "AK-47 | Redline (Field-Tested)", Platform: "BUFF", SellPrice: 101, UpdateTime: 10},
+		},
+		"AWP | Asiimov (Field-Tested)": {
+			{MarketHashName: "AWP | Asiimov (Field-Tested)", Platform: "BUFF", SellPrice: 500, UpdateTime: 30},
+		},
+	}}
+	buff := &fakeProvider{name: "buff", quotes: map[string][]*PriceQuote{
+		"AK-47 | Redline (Field-Tested)": {
+			{MarketHashName: "AK-47 | Redline (Field-Tested)", Platform: "BUFF", SellPrice: 99, UpdateTime: 20},
+		},
+		"AWP | Asiimov (Field-Tested)": {
+			{MarketHashName: "AWP | Asiimov (Field-Tested)", Platform: "BUFF", SellPrice: 480, UpdateTime: 5},
+		},
+	}}
+	names := []string{"AK-47 | Redline (Field-Tested)", "AWP | Asiimov (Field-Tested)"}
+
+	quotes, err := fetchBatchQuotes(context.Background(), []PriceProvider{steamdt, buff}, names)
+	if err != nil {
+		t.Fatalf("fetchBatchQuotes error: %v", err)
+	}
+	if len(steamdt.calls) != 2 || len(buff.calls) != 1 {
+		t.Fatalf("provider calls = %d/%d, want 2/1 by batch size", len(steamdt.calls), len(buff.calls))
+	}
+
+	merged := MergeQuotes(quotes)
+	ak := merged["AK-47 | Redline (Field-Tested)"]
+	if len(ak) != 2 {
+		t.Fatalf("AK platforms = %d, want 2", len(ak))
+	}
+	if ak["YOUPIN"].SellPrice != 100 {
+		t.Errorf("YOUPIN from single provider = %v, want 100", ak["YOUPIN"].SellPrice)
+	}
+	if ak["BUFF"].SellPrice != 99 {
+		t.Errorf("BUFF should come from newer buff quote, got %v", ak["BUFF"].SellPrice)
+	}
+	if got := merged["AWP | Asiimov (Field-Tested)"]["BUFF"].SellPrice; got != 500 {
+		t.Errorf("AWP BUFF should keep newer steamdt quote, got %v", got)
+	}
+}
+
+func TestMergeQuotesSameUpdateTimeKeepsFirst(t *testing.T) {
+	merged := MergeQuotes([]*PriceQuote{
+		{MarketHashName: "a", Platform: "C5", SellPrice: 1, UpdateTime: 10},
+		{MarketHashName: "a", Platform: "C5", SellPrice: 2, UpdateTime: 10},
+	})
+	if got := merged["a"]["C5"].SellPrice; got != 1 {
+		t.Errorf("SellPrice = %v, want first quote 1", got)
+	}
+}
+
+func TestMergeQuotesEmptyBatches(t *testing.T) {
+	empty := &fakeProvider{name: "empty", size: 100}
+	quotes, err := fetchBatchQuotes(context.Background(), []PriceProvider{empty}, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("fetchBatchQuotes error: %v", err)
+	}
+	if merged := MergeQuotes(quotes); len(merged) != 0 {
+		t.Errorf("merged = %v, want empty", merged)
+	}
+	if merged := MergeQuotes(nil); len(merged) != 0 {
+		t.Errorf("MergeQuotes(nil) = %v, want empty", merged)
+	}
+
+	// 缺少名称或平台的报价以及 nil 报价被忽略
+	merged := MergeQuotes([]*PriceQuote{nil, {Platform: "BUFF"}, {MarketHashName: "a"}})
+	if !reflect.DeepEqual(merged, map[string]map[string]*PriceQuote{}) {
+		t.Errorf("merged = %v, want empty", merged)
+	}
+}
+
+func TestFetchBatchQuotesExhausted(t *testing.T) {
+	exhausted := &exhaustedProvider{}
+	_, err := fetchBatchQuotes(context.Background(), []PriceProvider{exhausted}, []string{"a"})
+	if !errors.Is(err, errProviderExhausted) {
+		t.Errorf("err = %v, want errProviderExhausted", err)
+	}
+}
+
+type exhaustedProvider struct{}
+
+func (exhaustedProvider) Name() string { return "exhausted" }
+
+func (exhaustedProvider) BatchSize() int { return 0 }
+
+func (exhaustedProvider) FetchQuotes(context.Context, []string) ([]*PriceQuote, error) {
+	return nil, errProviderExhausted
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+)
+
+// defaultSweepBatchSize 启用的数据源都不限制批次大小时使用的批次大小
+const defaultSweepBatchSize = 100
+
+// SweepProgress 全量扫描进度
+type SweepProgress struct {
+	Current       *models.PriceSweep `json:"current"`        // 进行中的扫描，没有时为空
+	Percent       float64            `json:"percent"`        // 完成百分比
+	Pending       int                `json:"pending"`        // 未完成的批次数
+	Elapsed       int64              `json:"elapsed"`        // 已用时（秒）
+	Remaining     *int64             `json:"remaining"`      // 按当前速度估算的剩余时间（秒）
+	LastCompleted *models.PriceSweep `json:"last_completed"` // 上一轮完成的扫描
+	Interval      int64              `json:"interval"`       // 上一轮扫描耗时（秒），即每个饰品的刷新间隔
+}
+
+// UpdateAllPlatformData 继续当前的全量扫描，逐批从启用的数据源获取报价并写入平台表，返回本次写入报价的饰品数
+// 每批先暂存报价（fetched）再写入（merged），进度保存在数据库，服务重启或 key 耗尽后从未完成的批次继续
+// 一轮扫描完成后，下次运行按最新的饰品列表开始新一轮，每个饰品每轮刷新一次
+func UpdateAllPlatformData(ctx context.Context) (int, error) {
+	providers := enabledProviders()
+	sweep, err := currentSweep(providers)
+	if err != nil {
+		return 0, err
+	}
+
+	items := 0
+	// 上次运行在写入前中断的批次，直接使用暂存的报价
+	fetched, err := models.GetFetchedBatches(sweep.ID)
+	if err != nil {
+		return 0, fmt.Errorf("get fetched batches: %w", err)
+	}
+	for _, batch := range fetched {
+		var quotes []*PriceQuote
+		if err := json.Unmarshal([]byte(batch.Quotes), &quotes); err != nil {
+			config.Log.Errorf("Unmarshal sweep batch %d quotes error: %v", batch.ID, err)
+		}
+		n, err := mergeSweepBatch(sweep, batch, quotes)
+		if err != nil {
+			return items, err
+		}
+		items += n
+	}
+
+	// 热门和关注的饰品到期时，与全量扫描按 priorityBudgetShare 分配批次
+	priorityBatches, sweepBatches := 0, 0
+	for ctx.Err() == nil {
+		if float64(priorityBatches) < float64(priorityBatches+sweepBatches+1)*priorityBudgetShare {
+			if names := nextPriorityBatch(sweep.BatchSize); len(names) > 0 {
+				priorityBatches++
+				n, err := refreshPriorityBatch(ctx, providers, names)
+				if errors.Is(err, errProviderExhausted) {
+					config.Log.Warnf("Priority refresh paused: %v", err)
+					break
+				}
+				items += n
+				continue
+			}
+		}
+		sweepBatches++
+
+		batch, err := models.NextSweepBatch(sweep.ID)
+		if err != nil {
+			return items, fmt.Errorf("get next sweep batch: %w", err)
+		}
+		if batch == nil {
+			if err := models.CompleteSweep(sweep); err != nil {
+				return items, fmt.Errorf("complete sweep %d: %w", sweep.ID, err)
+			}
+			config.Log.Infof("Price sweep %d completed: %d items, %d failed batches", sweep.ID, sweep.Items, sweep.Failed)
+			break
+		}
+
+		quotes, err := fetchBatchQuotes(ctx, providers, batch.Names())
+		if errors.Is(err, errProviderExhausted) {
+			config.Log.Warnf("Price sweep %d paused at batch %d/%d: %v", sweep.ID, batch.Idx, sweep.Batches, err)
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			config.Log.Errorf("Price sweep %d batch %d attempt %d error: %v", sweep.ID, batch.Idx, batch.Attempts+1, err)
+			if e := models.FailSweepBatch(sweep, batch, err); e != nil {
+				return items, fmt.Errorf("save sweep batch %d: %w", batch.Idx, e)
+			}
+			continue
+		}
+
+		data, _ := json.Marshal(quotes)
+		if err := models.MarkBatchFetched(batch, string(data)); err != nil {
+			return items, fmt.Errorf("save sweep batch %d: %w", batch.Idx, err)
+		}
+		n, err := mergeSweepBatch(sweep, batch, quotes)
+		if err != nil {
+			return items, err
+		}
+		items += n
+		utils.PriceSweepProgress.Set(sweepRatio(sweep))
+	}
+	utils.PriceSweepProgress.Set(sweepRatio(sweep))
+
+	if items > 0 && ctx.Err() == nil {
+		// 通知搬砖机会推送的订阅者重新计算
+		publishOpportunityUpdate()
+
+		// 价格更新完成后在后台评估用户价格预警，不占用扫描任务的执行时间
+		SafeGo(func() { EvaluatePriceAlerts(ctx) })
+	}
+	return items, ctx.Err()
+}
+
+// currentSweep 返回进行中的扫描，没有时按当前饰品列表创建新一轮
+func currentSweep(providers []PriceProvider) (*models.PriceSweep, error) {
+	sweep, err := models.GetRunningSweep()
+	if err != nil {
+		return nil, fmt.Errorf("get running sweep: %w", err)
+	}
+	if sweep != nil {
+		return sweep, nil
+	}
+	hashNames, err := models.GetHashNames()
+	if err != nil {
+		return nil, fmt.Errorf("get hash names: %w", err)
+	}
+	sweep, err = models.CreateSweep(hashNames, sweepBatchSize(providers))
+	if err != nil {
+		return nil, fmt.Errorf("create sweep: %w", err)
+	}
+	config.Log.Infof("Price sweep %d started: %d items in %d batches", sweep.ID, sweep.Total, sweep.Batches)
+	return sweep, nil
+}
+
+// sweepBatchSize 启用的数据源中最小的批次大小，保证一个批次只需请求每个数据源一次
+func sweepBatchSize(providers []PriceProvider) int {
+	size := 0
+	for _, p := range providers {
+		if n := p.BatchSize(); n > 0 && (size == 0 || n < size) {
+			size = n
+		}
+	}
+	if size == 0 {
+		size = defaultSweepBatchSize
+	}
+	return size
+}
+
+// fetchBatchQuotes 从所有数据源获取一个批次的报价，任一数据源失败时整批重试
+// 数据源额度耗尽时返回 errProviderExhausted，批次保持 pending 等待下次运行
+func fetchBatchQuotes(ctx context.Context, providers []PriceProvider, hashNames []string) ([]*PriceQuote, error) {
+	var quotes []*PriceQuote
+	for _, p := range providers {
+		size := p.BatchSize()
+		if size <= 0 {
+			size = len(hashNames)
+		}
+		for start := 0; start < len(hashNames); start += size {
+			batch, err := p.FetchQuotes(ctx, hashNames[start:min(start+size, len(hashNames))])
+			if errors.Is(err, errProviderExhausted) {
+				utils.ProviderBatchesTotal.WithLabelValues(p.Name(), "exhausted").Inc()
+				return nil, fmt.Errorf("%s: %w", p.Name(), err)
+			}
+			if err != nil {
+				utils.ProviderBatchesTotal.WithLabelValues(p.Name(), "error").Inc()
+				return nil, fmt.Errorf("%s: %w", p.Name(), err)
+			}
+			utils.ProviderBatchesTotal.WithLabelValues(p.Name(), "success").Inc()
+			quotes = append(quotes, batch...)
+		}
+	}
+	return quotes, nil
+}
+
+// refreshPriorityBatch 在全量扫描之外刷新一批到期的热门饰品，失败的饰品保持到期状态，下次再挑选
+func refreshPriorityBatch(ctx context.Context, providers []PriceProvider, hashNames []string) (int, error) {
+	quotes, err := fetchBatchQuotes(ctx, providers, hashNames)
+	if err != nil {
+		if !errors.Is(err, errProviderExhausted) && ctx.Err() == nil {
+			config.Log.Errorf("Priority refresh of %d items error: %v", len(hashNames), err)
+		}
+		return 0, err
+	}
+	merged := MergeQuotes(quotes)
+	if len(merged) > 0 {
+		applyQuotes(merged, hashNames)
+	}
+	markSteamDTRefreshed(hashNames)
+	return len(merged), nil
+}
+
+// mergeSweepBatch 合并批次报价写入平台表并标记为 merged，返回有报价的饰品数
+func mergeSweepBatch(sweep *models.PriceSweep, batch *models.PriceSweepBatch, quotes []*PriceQuote) (int, error) {
+	merged := MergeQuotes(quotes)
+	if len(merged) > 0 {
+		applyQuotes(merged, batch.Names())
+	}
+	if err := models.MarkBatchMerged(sweep, batch, len(merged)); err != nil {
+		return 0, fmt.Errorf("save sweep batch %d: %w", batch.Idx, err)
+	}
+	markSteamDTRefreshed(batch.Names())
+	return len(merged), nil
+}
+
+// sweepRatio 已结束（写入或重试用尽）的批次占比
+func sweepRatio(sweep *models.PriceSweep) float64 {
+	if sweep.Batches == 0 {
+		return 1
+	}
+	return float64(sweep.Merged+sweep.Failed) / float64(sweep.Batches)
+}
+
+// GetSweepProgress 当前扫描的进度和上一轮扫描的耗时
+func GetSweepProgress() (*SweepProgress, error) {
+	current, err := models.GetRunningSweep()
+	if err != nil {
+		return nil, err
+	}
+	last, err := models.GetLastCompletedSweep()
+	if err != nil {
+		return nil, err
+	}
+	progress := &SweepProgress{Current: current, LastCompleted: last}
+	if last != nil && last.EndTime != nil {
+		progress.Interval = int64(last.EndTime.Sub(last.StartTime).Seconds())
+	}
+	if current == nil {
+		return progress, nil
+	}
+
+	ratio := sweepRatio(current)
+	done := current.Merged + current.Failed
+	progress.Percent = math.Round(ratio*10000) / 100
+	progress.Pending = current.Batches - done
+	elapsed := time.Since(current.StartTime)
+	progress.Elapsed = int64(elapsed.Seconds())
+	if done > 0 {
+		remaining := int64((elapsed / time.Duration(done) * time.Duration(progress.Pending)).Seconds())
+		progress.Remaining = &remaining
+	}
+	return progress, nil
+}
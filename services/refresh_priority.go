@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/models"
+)
+
+// refreshIntervals 各档位的目标刷新间隔，为 0 表示该档位不单独安排刷新
+type refreshIntervals map[string]time.Duration
+
+// SteamDT：冷门饰品只随全量扫描刷新，热门和关注的饰品在扫描之外额外刷新
+var steamDTRefreshIntervals = refreshIntervals{
+	models.RefreshHot:  5 * time.Minute,
+	models.RefreshWarm: 20 * time.Minute,
+}
+
+// Steam 市场逐个请求，额度更少，间隔更长
+var steamRefreshIntervals = refreshIntervals{
+	models.RefreshHot:  15 * time.Minute,
+	models.RefreshWarm: 2 * time.Hour,
+	models.RefreshCold: 12 * time.Hour,
+}
+
+// 请求额度分配
+const (
+	priorityBudgetShare = 0.5 // SteamDT 批次中用于优先刷新的比例，其余用于全量扫描
+	overdueBudgetShare  = 0.2 // 每次挑选时留给逾期最久饰品的比例，避免低分饰品一直排不上
+	steamRefreshPerRun  = 300 // Steam 价格任务每次运行最多请求的饰品数
+)
+
+// refreshState 刷新优先级缓存和 SteamDT 各饰品最近一次获取报价的时间
+type refreshState struct {
+	mu          sync.RWMutex
+	priorities  map[string]*models.RefreshPriority
+	lastSteamDT map[string]time.Time
+}
+
+var refresh = &refreshState{lastSteamDT: make(map[string]time.Time)}
+
+// refreshCandidate 待挑选的饰品及其上次刷新时间
+type refreshCandidate struct {
+	name string
+	last time.Time
+}
+
+// UpdateRefreshPriorities 重新计算刷新优先级并更新缓存，返回饰品数
+func UpdateRefreshPriorities(ctx context.Context) (int, error) {
+	n, err := models.RebuildRefreshPriorities()
+	if err != nil {
+		return 0, err
+	}
+	loadRefreshPriorities()
+	return n, nil
+}
+
+func loadRefreshPriorities() {
+	priorities, err := models.GetAllRefreshPriorities()
+	if err != nil {
+		config.Log.Errorf("Load refresh priority error: %v", err)
+		return
+	}
+	refresh.mu.Lock()
+	refresh.priorities = priorities
+	refresh.mu.Unlock()
+}
+
+// markSteamDTRefreshed 记录饰品已从 SteamDT 获取报价
+func markSteamDTRefreshed(hashNames []string) {
+	now := time.Now()
+	refresh.mu.Lock()
+	defer refresh.mu.Unlock()
+	for _, name := range hashNames {
+		refresh.lastSteamDT[name] = now
+	}
+}
+
+// nextPriorityBatch 挑选到期需要额外刷新的热门/关注饰品，最多 limit 个
+func nextPriorityBatch(limit int) []string {
+	refresh.mu.RLock()
+	var candidates []refreshCandidate
+	for name, p := range refresh.priorities {
+		if steamDTRefreshIntervals[p.Tier] > 0 {
+			candidates = append(candidates, refreshCandidate{name: name, last: refresh.lastSteamDT[name]})
+		}
+	}
+	refresh.mu.RUnlock()
+	return pickDueRefresh(candidates, steamDTRefreshIntervals, limit, time.Now())
+}
+
+// pickDueRefresh 从到期（距上次刷新超过档位间隔）的饰品中挑选最多 limit 个
+// 大部分额度按优先级分数从高到低分配，其余留给逾期时间与间隔之比最大的饰品
+func pickDueRefresh(candidates []refreshCandidate, intervals refreshIntervals, limit int, now time.Time) []string {
+	type dueItem struct {
+		name    string
+		score   float64
+		overdue float64
+	}
+	refresh.mu.RLock()
+	var due []dueItem
+	for _, c := range candidates {
+		tier, score := models.RefreshCold, 0.0
+		if p, ok := refresh.priorities[c.name]; ok {
+			tier, score = p.Tier, p.Score
+		}
+		interval := intervals[tier]
+		if interval <= 0 {
+			continue
+		}
+		if elapsed := now.Sub(c.last); elapsed >= interval {
+			due = append(due, dueItem{name: c.name, score: score, overdue: float64(elapsed) / float64(interval)})
+		}
+	}
+	refresh.mu.RUnlock()
+
+	if len(due) <= limit {
+		sort.Slice(due, func(i, j int) bool { return due[i].score > due[j].score })
+		names := make([]string, len(due))
+		for i, d := range due {
+			names[i] = d.name
+		}
+		return names
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].score != due[j].score {
+			return due[i].score > due[j].score
+		}
+		return due[i].overdue > due[j].overdue
+	})
+	byScore := limit - int(float64(limit)*overdueBudgetShare)
+	names := make([]string, 0, limit)
+	for _, d := range due[:byScore] {
+		names = append(names, d.name)
+	}
+	rest := due[byScore:]
+	sort.Slice(rest, func(i, j int) bool { return rest[i].overdue > rest[j].overdue })
+	for _, d := range rest[:limit-byScore] {
+		names = append(names, d.name)
+	}
+	return names
+}
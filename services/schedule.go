@@ -1,16 +1,40 @@
 package services
 
 import (
-	"sync"
+	"context"
 	"time"
+	"uu/models"
 )
 
-func StartBuffFullUpdateScheduler() {
-	go UpdateBuffFullData()
-	ticker := time.NewTicker(30 * time.Minute)
-	defer ticker.Stop()
-	for range ticker.C {
-		go UpdateBuffFullData()
+// JobPlatformData 行情更新任务名，就绪检查根据它最近一次成功的时间判断行情是否停止更新
+const JobPlatformData = "platform_data"
+
+// InitJobs 注册所有定时任务
+func InitJobs() {
+	RegisterJob("steam_item_nameids", "补全 Steam item_nameid", Every(120*time.Hour), true, UpdateSteamItemNameIds)
+	// 每轮执行完后休息 1 分钟，避免过于频繁
+	RegisterJob("steam_prices", "从 Steam 市场逐个更新价格", Every(time.Minute), true, UpdateSteamPricesFromMarket)
+	RegisterJob("base_goods", "更新饰品基础信息", Every(24*time.Hour), false, UpdateBaseGoodsToDb)
+	RegisterJob(JobPlatformData, "从数据源获取各平台报价", Every(100*time.Second), false, UpdateAllPlatformData)
+	RegisterJob("refresh_priority", "计算饰品刷新优先级", Every(30*time.Minute), true, UpdateRefreshPriorities)
+	RegisterJob("icons", "更新饰品图标", Every(13*time.Hour), true, UpdateUUGoods)
+	// 每天凌晨2点记录一次价格历史
+	RegisterJob("daily_price_history", "记录每日价格历史并重算风险", MustParseCron("0 2 * * *"), false,
+		func(ctx context.Context) (int, error) { return RecordDailyPriceHistory() })
+	// 每小时根据日内快照重新估算流动性
+	RegisterJob("liquidity", "根据日内快照估算流动性", Every(time.Hour), true,
+		func(ctx context.Context) (int, error) { return models.RebuildLiquidity() })
+	RegisterJob("job_run_cleanup", "清理过期的任务运行记录", MustParseCron("30 3 * * *"), false,
+		func(ctx context.Context) (int, error) { return models.CleanOldJobRuns(models.JobRunRetainDays) })
+
+	// 启用了悠悠/BUFF 数据源时，定期全量扫描刷新报价快照
+	if isProviderEnabled("uu") {
+		RegisterJob("uu_full_update", "悠悠全量扫描", Every(8*time.Minute), true,
+			func(ctx context.Context) (int, error) { return 0, UpdateUUFullData(ctx) })
+	}
+	if isProviderEnabled("buff") {
+		RegisterJob("buff_full_update", "BUFF 全量扫描", Every(30*time.Minute), true,
+			func(ctx context.Context) (int, error) { return 0, UpdateBuffFullData(ctx) })
 	}
 }
 
@@ -24,97 +48,3 @@ func StartVerifyToken() {
 		}()
 	}
 }
-
-func StartUUFullUpdateScheduler() {
-	go UpdateUUFullData()
-	ticker := time.NewTicker(8 * time.Minute)
-	defer ticker.Stop()
-	for range ticker.C {
-		go UpdateUUFullData()
-	}
-}
-
-func UpdateBaseGoodsScheduler() {
-	ticker := time.NewTicker(24 * time.Hour)
-	defer ticker.Stop()
-	for range ticker.C {
-		SafeGo(UpdateBaseGoodsToDb)
-	}
-}
-
-func UpdateIconScheduler() {
-	SafeGo(UpdateUUGoods)
-	ticker := time.NewTicker(13 * time.Hour)
-	defer ticker.Stop()
-	for range ticker.C {
-		SafeGo(UpdateUUGoods)
-	}
-
-}
-
-func UpdateAllGoodsScheduler() {
-	ticker := time.NewTicker(100 * time.Second)
-	defer ticker.Stop()
-	var running bool
-	var mutex sync.Mutex
-
-	for range ticker.C {
-		mutex.Lock()
-		if running {
-			mutex.Unlock()
-			continue
-		}
-		running = true
-		mutex.Unlock()
-		go func() {
-			defer func() {
-				mutex.Lock()
-				running = false
-				mutex.Unlock()
-			}()
-			SafeGo(UpdateAllPlatformData)
-		}()
-	}
-}
-
-// StartDailyPriceHistoryScheduler 每天凌晨2点记录一次价格历史
-func StartDailyPriceHistoryScheduler() {
-	// 计算距离下一个凌晨2点的时间
-	now := time.Now()
-	next := time.Date(now.Year(), now.Month(), now.Day(), 2, 0, 0, 0, now.Location())
-	if next.Before(now) {
-		next = next.Add(24 * time.Hour)
-	}
-
-	// 先等待到第一次执行时间
-	waitDuration := next.Sub(now)
-	time.Sleep(waitDuration)
-
-	// 执行第一次记录
-	SafeGo(RecordDailyPriceHistory)
-
-	// 然后每24小时执行一次
-	ticker := time.NewTicker(24 * time.Hour)
-	defer ticker.Stop()
-	for range ticker.C {
-		SafeGo(RecordDailyPriceHistory)
-	}
-}
-
-func UpdateSteamItemNameIdsScheduler() {
-	SafeGo(UpdateSteamItemNameIds)
-	ticker := time.NewTicker(120 * time.Hour)
-	defer ticker.Stop()
-	for range ticker.C {
-		SafeGo(UpdateSteamItemNameIds)
-	}
-}
-
-// UpdateSteamPricesScheduler 持续更新 Steam 价格，执行完一次后继续执行
-func UpdateSteamPricesScheduler() {
-	for {
-		UpdateSteamPricesFromMarket()
-		// 每轮执行完后休息 1 分钟，避免过于频繁
-		time.Sleep(1 * time.Minute)
-	}
-}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"uu/config"
+	"uu/models"
+	"uu/utils"
+)
+
+// JobFunc 定时任务，返回处理的条目数
+type JobFunc func(ctx context.Context) (int, error)
+
+// Schedule 计算下一次执行时间
+type Schedule interface {
+	Next(from time.Time) time.Time
+	String() string
+}
+
+// intervalSchedule 上一次执行结束后间隔固定时长再执行
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(from time.Time) time.Time {
+	return from.Add(time.Duration(s))
+}
+
+func (s intervalSchedule) String() string {
+	return "@every " + time.Duration(s).String()
+}
+
+// Every 固定间隔执行
+func Every(d time.Duration) Schedule {
+	return intervalSchedule(d)
+}
+
+// cronSchedule 五段 cron 表达式（分 时 日 月 周），按本地时区计算
+type cronSchedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64 // 各字段允许值的位集合
+}
+
+// cronFields 各字段取值范围
+var cronFields = [5][2]uint{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// ParseCron 解析五段 cron 表达式，每段支持 *、数字、a-b、*/n、a-b/n 及逗号分隔的列表
+// 与标准 cron 不同，同时限制日和周时需两者都满足
+func ParseCron(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron spec %q: expected 5 fields", spec)
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i][0], cronFields[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron spec %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+	return &cronSchedule{spec: spec, minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4]}, nil
+}
+
+// MustParseCron 解析 cron 表达式，格式错误时 panic，用于注册内置任务
+func MustParseCron(spec string) Schedule {
+	s, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseCronField(field string, min, max uint) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, uint(1)
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.ParseUint(item[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			rangePart, step = item[:i], uint(n)
+		}
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			n, err := strconv.ParseUint(bounds[0], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			lo, hi = uint(n), uint(n)
+			if len(bounds) == 2 {
+				n, err = strconv.ParseUint(bounds[1], 10, 8)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", item)
+				}
+				hi = uint(n)
+			} else if step > 1 {
+				hi = max // a/n 表示从 a 开始每隔 n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range in %q", item)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (s *cronSchedule) Next(from time.Time) time.Time {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	// 最多向后查找一年多，表达式永远无法满足（如 2 月 31 日）时返回零值
+	for limit := t.AddDate(1, 1, 0); t.Before(limit); {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.dom&(1<<uint(t.Day())) == 0 || s.dow&(1<<uint(t.Weekday())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) String() string {
+	return s.spec
+}
+
+// configKeyJobPausedPrefix 系统配置中任务暂停状态的键前缀，后接任务名，重启后保持
+const configKeyJobPausedPrefix = "job_paused:"
+
+// job 已注册的定时任务
+type job struct {
+	name       string
+	desc       string
+	schedule   Schedule
+	runOnStart bool // 启动后立即执行一次
+	fn         JobFunc
+
+	mu      sync.Mutex
+	running bool
+	paused  bool
+	nextRun time.Time
+	trigger chan struct{} // 手动触发
+}
+
+// JobStatus 任务状态
+type JobStatus struct {
+	Name     string         `json:"name"`
+	Desc     string         `json:"desc"`
+	Schedule string         `json:"schedule"`
+	Paused   bool           `json:"paused"`
+	Running  bool           `json:"running"`
+	NextRun  *time.Time     `json:"next_run"` // 暂停时为空
+	LastRun  *models.JobRun `json:"last_run"`
+}
+
+var scheduler struct {
+	mu       sync.Mutex
+	jobs     []*job
+	started  bool
+	stopping bool           // WaitJobs 已调用，不再开始新的执行
+	runs     sync.WaitGroup // 执行中的任务
+}
+
+// RegisterJob 注册定时任务，需在 StartScheduler 之前调用，同名任务覆盖
+func RegisterJob(name, desc string, schedule Schedule, runOnStart bool, fn JobFunc) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	j := &job{name: name, desc: desc, schedule: schedule, runOnStart: runOnStart, fn: fn, trigger: make(chan struct{}, 1)}
+	for i, existing := range scheduler.jobs {
+		if existing.name == name {
+			scheduler.jobs[i] = j
+			return
+		}
+	}
+	scheduler.jobs = append(scheduler.jobs, j)
+}
+
+// StartScheduler 启动所有已注册的任务，每个任务一个协程，同一任务不会重叠执行
+// ctx 取消后不再开始新的执行，执行中的任务通过 ctx 得知需要尽快结束，用 WaitJobs 等待
+func StartScheduler(ctx context.Context) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.started {
+		return
+	}
+	scheduler.started = true
+	models.FailInterruptedJobRuns()
+	for _, j := range scheduler.jobs {
+		if v, ok := models.GetSystemConfig(configKeyJobPausedPrefix + j.name); ok {
+			j.paused, _ = strconv.ParseBool(v)
+		}
+		go j.loop(ctx)
+	}
+	config.Log.Infof("Scheduler started with %d jobs", len(scheduler.jobs))
+}
+
+func (j *job) loop(ctx context.Context) {
+	next := time.Now()
+	if !j.runOnStart {
+		next = j.schedule.Next(next)
+	}
+	for {
+		j.mu.Lock()
+		j.nextRun = next
+		j.mu.Unlock()
+
+		trigger := models.JobTriggerSchedule
+		var timer *time.Timer
+		var timeout <-chan time.Time // 没有下一次执行时间时为 nil，只能手动触发
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-timeout:
+		case <-j.trigger:
+			trigger = models.JobTriggerManual
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		j.mu.Lock()
+		paused := j.paused
+		j.mu.Unlock()
+		if trigger == models.JobTriggerManual || !paused {
+			j.run(ctx, trigger)
+		}
+		next = j.schedule.Next(time.Now())
+	}
+}
+
+// run 执行一次任务并记录运行结果，panic 视为失败
+func (j *job) run(ctx context.Context, trigger string) {
+	scheduler.mu.Lock()
+	if scheduler.stopping {
+		scheduler.mu.Unlock()
+		return
+	}
+	scheduler.runs.Add(1)
+	scheduler.mu.Unlock()
+	defer scheduler.runs.Done()
+	j.mu.Lock()
+	j.running = true
+	j.mu.Unlock()
+	utils.JobRunning.WithLabelValues(j.name).Set(1)
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+		utils.JobRunning.WithLabelValues(j.name).Set(0)
+	}()
+
+	run := models.StartJobRun(j.name, trigger)
+	var items int
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		items, err = j.fn(ctx)
+	}()
+	models.FinishJobRun(run, items, err)
+	utils.JobRunsTotal.WithLabelValues(j.name, run.Status).Inc()
+	utils.JobDuration.WithLabelValues(j.name).Observe(float64(run.Duration) / 1000)
+	utils.JobItems.WithLabelValues(j.name).Set(float64(items))
+	if err != nil {
+		config.Log.Errorf("Job %s failed after %dms: %v", j.name, run.Duration, err)
+		return
+	}
+	config.Log.Infof("Job %s finished in %dms, items: %d", j.name, run.Duration, items)
+}
+
+// WaitJobs 停止开始新的执行并等待执行中的任务结束，ctx 超时返回错误
+func WaitJobs(ctx context.Context) error {
+	scheduler.mu.Lock()
+	scheduler.stopping = true
+	scheduler.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *job) status(lastRuns map[string]*models.JobRun) *JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := &JobStatus{
+		Name:     j.name,
+		Desc:     j.desc,
+		Schedule: j.schedule.String(),
+		Paused:   j.paused,
+		Running:  j.running,
+		LastRun:  lastRuns[j.name],
+	}
+	if !j.paused && !j.running && !j.nextRun.IsZero() {
+		next := j.nextRun
+		s.NextRun = &next
+	}
+	return s
+}
+
+func findJob(name string) *job {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	for _, j := range scheduler.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// ListJobs 所有任务的状态，按名称排序
+func ListJobs() []*JobStatus {
+	scheduler.mu.Lock()
+	jobs := append([]*job(nil), scheduler.jobs...)
+	scheduler.mu.Unlock()
+
+	lastRuns := models.GetLastJobRuns()
+	result := make([]*JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		result = append(result, j.status(lastRuns))
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Name < result[b].Name })
+	return result
+}
+
+// SetJobPaused 暂停或恢复任务的计划执行，暂停中的任务仍可手动触发
+func SetJobPaused(name string, paused bool) int {
+	j := findJob(name)
+	if j == nil {
+		return utils.ErrCodeJobNotFound
+	}
+	err := models.SetSystemConfig(configKeyJobPausedPrefix+name, strconv.FormatBool(paused), "定时任务 "+name+" 是否暂停")
+	if err != nil {
+		config.Log.Errorf("Save job %s paused error: %v", name, err)
+		return utils.ErrCodeUpdateJob
+	}
+	j.mu.Lock()
+	j.paused = paused
+	j.mu.Unlock()
+	config.Log.Infof("Job %s paused: %v", name, paused)
+	return utils.SUCCESS
+}
+
+// TriggerJob 立即执行一次任务，正在执行时返回错误
+func TriggerJob(name string) int {
+	j := findJob(name)
+	if j == nil {
+		return utils.ErrCodeJobNotFound
+	}
+	j.mu.Lock()
+	running := j.running
+	j.mu.Unlock()
+	if running {
+		return utils.ErrCodeJobRunning
+	}
+	select {
+	case j.trigger <- struct{}{}:
+		return utils.SUCCESS
+	default:
+		// 已有未处理的手动触发
+		return utils.ErrCodeJobRunning
+	}
+}
+
+// sleepContext 等待 d，ctx 取消时提前返回 ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// cronBits 由允许值生成字段位集合
+func cronBits(values ...uint) uint64 {
+	var bits uint64
+	for _, v := range values {
+		bits |= 1 << v
+	}
+	return bits
+}
+
+func cronRange(lo, hi, step uint) uint64 {
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << v
+	}
+	return bits
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec                          string
+		minute, hour, dom, month, dow uint64
+	}{
+		{"* * * * *", cronRange(0, 59, 1), cronRange(0, 23, 1), cronRange(1, 31, 1), cronRange(1, 12, 1), cronRange(0, 6, 1)},
+		{"30 4 * * *", cronBits(30), cronBits(4), cronRange(1, 31, 1), cronRange(1, 12, 1), cronRange(0, 6, 1)},
+		{"0 9-17 * * 1-5", cronBits(0), cronRange(9, 17, 1), cronRange(1, 31, 1), cronRange(1, 12, 1), cronRange(1, 5, 1)},
+		{"*/15 */6 * * *", cronBits(0, 15, 30, 45), cronBits(0, 6, 12, 18), cronRange(1, 31, 1), cronRange(1, 12, 1), cronRange(0, 6, 1)},
+		{"5/20 3/8 * * *", cronBits(5, 25, 45), cronBits(3, 11, 19), cronRange(1, 31, 1), cronRange(1, 12, 1), cronRange(0, 6, 1)},
+		{"0 0 1,15 1-12/3 0-6/2", cronBits(0), cronBits(0), cronBits(1, 15), cronBits(1, 4, 7, 10), cronBits(0, 2, 4, 6)},
+		{"0,10-12,50 0 1 1 0", cronBits(0, 10, 11, 12, 50), cronBits(0), cronBits(1), cronBits(1), cronBits(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron() error: %v", err)
+			}
+			c := s.(*cronSchedule)
+			got := [5]uint64{c.minute, c.hour, c.dom, c.month, c.dow}
+			want := [5]uint64{tt.minute, tt.hour, tt.dom, tt.month, tt.dow}
+			if got != want {
+				t.Errorf("fields = %b, want %b", got, want)
+			}
+			if s.String() != tt.spec {
+				t.Errorf("String() = %q, want %q", s.String(), tt.spec)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 7",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"-1 * * * *",
+		"1,,2 * * * *",
+	}
+	for _, spec := range specs {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) expected error", spec)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"same hour", "30 * * * *", at(2026, 1, 1, 10, 15, 0), at(2026, 1, 1, 10, 30, 0)},
+		{"next hour", "30 * * * *", at(2026, 1, 1, 10, 45, 0), at(2026, 1, 1, 11, 30, 0)},
+		{"strictly after from", "*/15 * * * *", at(2026, 1, 1, 10, 15, 0), at(2026, 1, 1, 10, 30, 0)},
+		{"seconds truncated", "*/15 * * * *", at(2026, 1, 1, 10, 14, 59), at(2026, 1, 1, 10, 15, 0)},
+		{"day rollover", "0 3 * * *", at(2026, 1, 1, 4, 0, 0), at(2026, 1, 2, 3, 0, 0)},
+		{"month rollover", "0 0 * * *", at(2026, 1, 31, 23, 59, 30), at(2026, 2, 1, 0, 0, 0)},
+		{"short month skipped", "0 0 31 * *", at(2026, 4, 1, 0, 0, 0), at(2026, 5, 31, 0, 0, 0)},
+		{"year rollover", "0 0 1 * *", at(2026, 12, 15, 8, 0, 0), at(2027, 1, 1, 0, 0, 0)},
+		{"weekday", "0 9 * * 1", at(2026, 1, 1, 10, 0, 0), at(2026, 1, 5, 9, 0, 0)},
+		{"day and weekday both required", "0 0 13 * 5", at(2026, 1, 1, 0, 0, 0), at(2026, 2, 13, 0, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2027, 3, 1, 0, 0, 0), at(2028, 2, 29, 0, 0, 0)},
+		{"never matches", "0 0 31 2 *", at(2026, 1, 1, 0, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MustParseCron(tt.spec).Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,11 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
-	"math/rand"
+	"net/http"
 	neturl "net/url"
 	"os"
 	"regexp"
@@ -59,214 +60,227 @@ func getHeaderFormatKey(key string) map[string]string {
 	}
 }
 
-func GetBaseGoods() ([]*models.BaseGoods, error) {
+func GetBaseGoods(ctx context.Context) ([]*models.BaseGoods, error) {
 	header := getHeader()
 	var base BaseGoodsResponse
 	var opts = utils.RequestOptions{
 		Headers: header,
 		Result:  &base,
 	}
-	rep, err := steamClient.DoRequest("GET", "open/cs2/v1/base", opts)
+	rep, err := steamClient.DoRequest(ctx, "GET", "open/cs2/v1/base", opts)
 	if err != nil || rep.StatusCode() != 200 {
 		config.Log.Errorf("Request steamDT base api error:%v, code: %v", err, rep.StatusCode())
 		return base.Data, err
 	}
 	if base.ErrorCode == 4005 {
+		utils.UpstreamRateLimitedTotal.WithLabelValues("steamdt").Inc()
 		config.Log.Warningf("Request api %s limit", "open/cs2/v1/base")
 		return base.Data, fmt.Errorf("request api %s limit", "open/cs2/v1/base")
 	}
 	return base.Data, err
 }
 
-func UpdateBaseGoodsToDb() {
-	goods, err := GetBaseGoods()
+func UpdateBaseGoodsToDb(ctx context.Context) (int, error) {
+	goods, err := GetBaseGoods(ctx)
 	if err != nil {
-		return
+		return 0, err
 	}
 	models.UpdateBaseGoods(goods)
+	return len(goods), nil
 }
 
-func BatchGetPrice() ([]*PriceList, error) {
-	var allPrice []*PriceList
-	hashNames, err := models.GetHashNames()
+// steamDTProvider SteamDT 批量价格接口，一次返回多个平台的报价
+type steamDTProvider struct{}
+
+func (steamDTProvider) Name() string {
+	return "steamdt"
+}
+
+func (steamDTProvider) BatchSize() int {
+	return 100
+}
+
+// FetchQuotes 从 key 池取 key 请求一批价格，key 被限流或无效时换下一个 key 重试本批
+// 没有可用 key 时返回 errProviderExhausted
+func (steamDTProvider) FetchQuotes(ctx context.Context, hashNames []string) ([]*PriceQuote, error) {
+	for {
+		key := keyPool.acquire()
+		if key == nil {
+			return nil, errProviderExhausted
+		}
+		rep, result, err := requestBatchPrice(ctx, key.Key, hashNames)
+		keyPool.report(key, result, err)
+		if result == keyRateLimited || result == keyUnauthorized {
+			config.Log.Warnf("Request api %s with key %s failed: %v", "open/cs2/v1/price/batch", key.MaskedKey(), err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return batchPriceQuotes(rep), nil
+	}
+}
+
+// requestBatchPrice 使用指定 key 请求批量价格，返回本次请求对 key 健康的影响
+func requestBatchPrice(ctx context.Context, key string, hashNames []string) (*BatchPriceResponse, keyResult, error) {
+	var rep BatchPriceResponse
+	opts := utils.RequestOptions{
+		Headers: getHeaderFormatKey(key),
+		Body: map[string][]string{
+			"marketHashNames": hashNames,
+		},
+		Result: &rep,
+	}
+	res, err := steamClient.DoRequest(ctx, "POST", "open/cs2/v1/price/batch", opts)
+	if ctx.Err() != nil {
+		return nil, keyReleased, ctx.Err()
+	}
+	if res != nil && (res.StatusCode() == http.StatusUnauthorized || res.StatusCode() == http.StatusForbidden) {
+		return nil, keyUnauthorized, err
+	}
 	if err != nil {
-		config.Log.Errorf("Get hash name error: %v", err)
-		return allPrice, err
+		config.Log.Errorf("Request open/cs2/v1/price/batch error: %v", err)
+		return nil, keyFailed, err
 	}
-	index := models.GetLastIndex()
-	n := len(hashNames) / 100
-	remainder := len(hashNames) % 100
-	if remainder > 0 {
-		n++
+	if rep.ErrorCode == 4005 {
+		utils.UpstreamRateLimitedTotal.WithLabelValues("steamdt").Inc()
+		config.Log.Info(rep.ErrorMsg)
+		return nil, keyRateLimited, fmt.Errorf("request api %s limit: %s", "open/cs2/v1/price/batch", rep.ErrorMsg)
 	}
+	return &rep, keySuccess, nil
+}
 
-	keys := models.GetActivateKey()
-	if len(keys) == 0 {
-		return allPrice, fmt.Errorf("no activate key")
+// batchPriceQuotes 将批量价格响应转换为报价
+func batchPriceQuotes(rep *BatchPriceResponse) []*PriceQuote {
+	var quotes []*PriceQuote
+	for _, item := range rep.Data {
+		for _, p := range item.DataList {
+			quotes = append(quotes, &PriceQuote{
+				MarketHashName: item.MarketHashName,
+				Platform:       p.Platform,
+				PlatformItemId: p.PlatformItemId,
+				SellPrice:      p.SellPrice,
+				SellCount:      p.SellCount,
+				BiddingPrice:   p.BiddingPrice,
+				BiddingCount:   p.BiddingCount,
+				UpdateTime:     p.UpdateTime,
+			})
+		}
 	}
+	return quotes
+}
 
-	for i := index; i < n; i++ {
-		var rep BatchPriceResponse
-		start := i * 100
-		end := start + 100
-		if end > len(hashNames) {
-			end = len(hashNames)
+// applyQuotes 将合并后的报价写入各平台表（计算成交量、生成链接），未注册的平台忽略
+// 价格、在售数或求购较上一轮有变化的饰品同时记录一条日内快照
+// 偏离近期历史和其他平台的报价会被标记为异常（anomaly），不记录快照
+func applyQuotes(merged map[string]map[string]*PriceQuote, hashNames []string) {
+	now := time.Now()
+	rates := models.GetFxRateMap()
+	var snapshots []*models.PriceSnapshot
+
+	// 先换算所有报价的在售价，供跨平台比较
+	sellPrices := make(map[string]map[string]float64, len(merged))
+	for hashName, platforms := range merged {
+		prices := make(map[string]float64, len(platforms))
+		for code, q := range platforms {
+			if p, ok := models.GetPlatformByCode(code); ok && !p.ExternalPrice {
+				prices[code] = models.ToBaseCurrency(q.SellPrice, quoteCurrency(p, q), rates)
+			}
 		}
-		key := keys[0]
-		if len(keys) > 1 {
-			keys = keys[1:]
-			if end >= len(hashNames) {
-				i = 0
+		sellPrices[hashName] = prices
+	}
+	detector := models.NewAnomalyDetector()
+	defer detector.Flush()
+
+	for _, p := range models.Platforms() {
+		existing := models.BatchGetPlatformRows(p, hashNames)
+		var rows []*models.PlatformRow
+		for hashName, platforms := range merged {
+			q, ok := platforms[p.Code]
+			if !ok {
 				continue
 			}
-		} else {
-			models.UpdateLastUsed(&key)
-			keys = models.GetActivateKey()
-			if len(keys) == 0 {
-				models.SetLastIndex(i)
-				return allPrice, fmt.Errorf("no activate key")
+			row := existing[hashName]
+			if row == nil {
+				row = &models.PlatformRow{}
 			}
+			row.MarketHashName = hashName
+			row.Link = p.BuildLink(q.PlatformItemId, hashName)
+			if !p.ExternalPrice {
+				// 非人民币报价先按汇率换算
+				currency := quoteCurrency(p, q)
+				sellPrice := models.ToBaseCurrency(q.SellPrice, currency, rates)
+				biddingPrice := models.ToBaseCurrency(q.BiddingPrice, currency, rates)
+				row.Anomaly = detector.Check(hashName, p.Code, sellPrice, biddingPrice, sellPrices[hashName], row.Anomaly)
+				if row.Anomaly == "" && sellPrice > 0 && (sellPrice != row.SellPrice || q.SellCount != row.SellCount ||
+					biddingPrice != row.BiddingPrice || q.BiddingCount != row.BiddingCount) {
+					snapshots = append(snapshots, &models.PriceSnapshot{
+						MarketHashName: hashName,
+						Platform:       p.Code,
+						SellPrice:      sellPrice,
+						SellCount:      q.SellCount,
+						BiddingPrice:   biddingPrice,
+						BiddingCount:   q.BiddingCount,
+						SnapshotTime:   now,
+					})
+				}
+				if q.UpdateTime-row.BeforeTime >= 43200 {
+					turnOver := int64(math.Abs(float64(q.SellCount - row.BeforeCount)))
+					row.BeforeTime = q.UpdateTime
+					row.BeforeCount = q.SellCount
+					row.TurnOver = turnOver
+				}
+				row.Id = q.PlatformItemId
+				row.SellPrice = sellPrice
+				row.SellCount = q.SellCount
+				row.BiddingPrice = biddingPrice
+				row.BiddingCount = q.BiddingCount
+				row.Currency = currency
+				row.UpdateTime = q.UpdateTime
+			}
+			rows = append(rows, row)
 		}
-		hashList := hashNames[start:end]
-		header := getHeaderFormatKey(key.Key)
-		opts := utils.RequestOptions{
-			Headers: header,
-			Body: map[string][]string{
-				"marketHashNames": hashList,
-			},
-			Result: &rep,
-		}
-		res, err := steamClient.DoRequest("POST", "open/cs2/v1/price/batch", opts)
-		if err != nil || res.StatusCode() != 200 {
-			config.Log.Errorf("Request open/cs2/v1/price/batch error: %v", err)
-		}
-		if rep.ErrorCode == 4005 {
-			config.Log.Warningf("Request api %s limit, key: %s", "open/cs2/v1/price/batch", key)
-			config.Log.Info(rep.ErrorMsg)
-		} else {
-			models.UpdateLastUsed(&key)
-		}
-		allPrice = append(allPrice, rep.Data...)
+		models.BatchUpdatePlatformRows(p, rows)
 	}
-
-	return allPrice, err
-}
-
-func UpdateAllPlatformData() {
-	var uList []*models.U
-	var buffList []*models.Buff
-	var c5List []*models.C5
-	var steamList []*models.Steam
-	all, err := BatchGetPrice()
-	if err != nil && len(all) == 0 {
-		return
+	if err := models.BatchCreatePriceSnapshots(snapshots); err != nil {
+		config.Log.Errorf("Record price snapshots error: %v", err)
 	}
+}
 
-	hashNames, _ := models.GetHashNames()
-	uMap := models.BatchGetUUGoods(hashNames)
-	buffMap := models.BatchGetBuffGoods(hashNames)
-	c5Map := models.BatchGetC5Goods(hashNames)
-	steamMap := models.BatchGetSteamGoods(hashNames)
-
-	for _, data := range all {
-		dataList := data.DataList
-		for i, _ := range dataList {
-			switch dataList[i].Platform {
-			case "YOUPIN":
-				u := uMap[data.MarketHashName]
-				if u == nil {
-					u = &models.U{}
-				}
-				if dataList[i].UpdateTime-u.BeforeTime >= 43200 {
-					turnOver := int64(math.Abs(float64(dataList[i].SellCount - u.BeforeCount)))
-					u.BeforeTime = dataList[i].UpdateTime
-					u.BeforeCount = dataList[i].SellCount
-					u.TurnOver = turnOver
-				}
-				u.Id = dataList[i].PlatformItemId
-				u.MarketHashName = data.MarketHashName
-				u.SellPrice = dataList[i].SellPrice
-				u.SellCount = dataList[i].SellCount
-				u.BiddingPrice = dataList[i].BiddingPrice
-				u.BiddingCount = dataList[i].BiddingCount
-				u.UpdateTime = dataList[i].UpdateTime
-				u.Link = fmt.Sprintf("https://www.youpin898.com/market/goods-list?listType=10&templateId=%s&gameId=730", dataList[i].PlatformItemId)
-				uList = append(uList, u)
-			case "BUFF":
-				buff := buffMap[data.MarketHashName]
-				if buff == nil {
-					buff = &models.Buff{}
-				}
-				if dataList[i].UpdateTime-buff.BeforeTime >= 43200 {
-					turnOver := int64(math.Abs(float64(dataList[i].SellCount - buff.BeforeCount)))
-					buff.BeforeTime = dataList[i].UpdateTime
-					buff.BeforeCount = dataList[i].SellCount
-					buff.TurnOver = turnOver
-				}
-				buff.Id = dataList[i].PlatformItemId
-				buff.MarketHashName = data.MarketHashName
-				buff.SellPrice = dataList[i].SellPrice
-				buff.SellCount = dataList[i].SellCount
-				buff.BiddingPrice = dataList[i].BiddingPrice
-				buff.BiddingCount = dataList[i].BiddingCount
-				buff.UpdateTime = dataList[i].UpdateTime
-				buff.Link = fmt.Sprintf("https://buff.163.com/goods/%s?from=market#tab=selling", dataList[i].PlatformItemId)
-				buffList = append(buffList, buff)
-			case "C5":
-				c5 := c5Map[data.MarketHashName]
-				if c5 == nil {
-					c5 = &models.C5{}
-				}
-				if dataList[i].UpdateTime-c5.BeforeTime >= 43200 {
-					turnOver := int64(math.Abs(float64(dataList[i].SellCount - c5.BeforeCount)))
-					c5.BeforeTime = dataList[i].UpdateTime
-					c5.BeforeCount = dataList[i].SellCount
-					c5.TurnOver = turnOver
-				}
-				c5.Id = dataList[i].PlatformItemId
-				c5.MarketHashName = data.MarketHashName
-				c5.SellPrice = dataList[i].SellPrice
-				c5.SellCount = dataList[i].SellCount
-				c5.BiddingPrice = dataList[i].BiddingPrice
-				c5.BiddingCount = dataList[i].BiddingCount
-				c5.UpdateTime = dataList[i].UpdateTime
-				c5.Link = fmt.Sprintf("https://www.c5game.com/csgo/%s/%s/sell", dataList[i].PlatformItemId, data.MarketHashName)
-				c5List = append(c5List, c5)
-			case "STEAM":
-				steam := steamMap[data.MarketHashName]
-				if steam == nil {
-					steam = &models.Steam{}
-				}
-				//if dataList[i].UpdateTime-steam.BeforeTime >= 43200 {
-				//	turnOver := int64(math.Abs(float64(dataList[i].SellCount - steam.BeforeCount)))
-				//	steam.BeforeCount = dataList[i].SellCount
-				//	steam.BeforeTime = dataList[i].UpdateTime
-				//	steam.TurnOver = turnOver
-				//}
-				steam.MarketHashName = data.MarketHashName
-				//steam.SellPrice = dataList[i].SellPrice
-				//steam.SellCount = dataList[i].SellCount
-				//steam.BiddingPrice = dataList[i].BiddingPrice
-				//steam.BiddingCount = dataList[i].BiddingCount
-				//steam.Id = dataList[i].PlatformItemId
-				//steam.UpdateTime = dataList[i].UpdateTime
-				steam.Link = fmt.Sprintf("https://steamcommunity.com/market/listings/730/%s", data.MarketHashName)
-				steamList = append(steamList, steam)
+// currentSellPrices 各饰品在数据源平台的当前在售价（人民币，不含被标记为异常的报价），供 Steam 报价跨平台比较
+func currentSellPrices(hashNames []string) map[string]map[string]float64 {
+	result := make(map[string]map[string]float64, len(hashNames))
+	for _, p := range models.Platforms() {
+		if p.ExternalPrice {
+			continue
+		}
+		for name, row := range models.BatchGetPlatformRows(p, hashNames) {
+			if row.SellPrice <= 0 || row.Anomaly != "" {
+				continue
+			}
+			if result[name] == nil {
+				result[name] = make(map[string]float64)
 			}
+			result[name][p.Code] = row.SellPrice
 		}
 	}
-	models.BatchUpdateUUGoods(uList)
-	models.BatchUpdateBuffGoods(buffList)
-	models.BatchUpdateC5Goods(c5List)
-	models.BatchUpdateSteamGoods(steamList)
+	return result
+}
+
+// quoteCurrency 报价币种，报价未指定时使用平台配置的币种
+func quoteCurrency(p *models.PlatformDef, q *PriceQuote) string {
+	if q.Currency != "" {
+		return models.NormalizeCurrency(q.Currency)
+	}
+	return p.Currency
 }
 
-// RecordDailyPriceHistory 每天记录一次价格历史
-func RecordDailyPriceHistory() {
+// RecordDailyPriceHistory 每天记录一次价格历史，返回记录条数
+func RecordDailyPriceHistory() (int, error) {
 	// 检查今天是否已记录
 	if models.CheckTodayRecordExists() {
 		config.Log.Info("Today's price history already recorded, skip.")
-		return
+		return 0, nil
 	}
 
 	// 获取本地时区的今天 00:00:00
@@ -277,76 +291,47 @@ func RecordDailyPriceHistory() {
 
 	hashNames, err := models.GetHashNames()
 	if err != nil {
-		config.Log.Errorf("Get hash names error: %v", err)
-		return
-	}
-
-	// 获取各平台当前数据
-	uMap := models.BatchGetUUGoods(hashNames)
-	buffMap := models.BatchGetBuffGoods(hashNames)
-	c5Map := models.BatchGetC5Goods(hashNames)
-	steamMap := models.BatchGetSteamGoods(hashNames)
-
-	for _, u := range uMap {
-		if u.SellPrice > 0 { // 只记录有价格的数据
-			histories = append(histories, &models.PriceHistory{
-				MarketHashName: u.MarketHashName,
-				Platform:       "YOUPIN",
-				SellPrice:      u.SellPrice,
-				SellCount:      u.SellCount,
-				RecordDate:     today,
-			})
-		}
-	}
-
-	for _, buff := range buffMap {
-		if buff.SellPrice > 0 {
-			histories = append(histories, &models.PriceHistory{
-				MarketHashName: buff.MarketHashName,
-				Platform:       "BUFF",
-				SellPrice:      buff.SellPrice,
-				SellCount:      buff.SellCount,
-				RecordDate:     today,
-			})
-		}
-	}
-
-	for _, c5 := range c5Map {
-		if c5.SellPrice > 0 {
-			histories = append(histories, &models.PriceHistory{
-				MarketHashName: c5.MarketHashName,
-				Platform:       "C5",
-				SellPrice:      c5.SellPrice,
-				SellCount:      c5.SellCount,
-				RecordDate:     today,
-			})
-		}
-	}
-
-	for _, steam := range steamMap {
-		if steam.SellPrice > 0 {
-			histories = append(histories, &models.PriceHistory{
-				MarketHashName: steam.MarketHashName,
-				Platform:       "STEAM",
-				SellPrice:      steam.SellPrice,
-				SellCount:      steam.SellCount,
-				RecordDate:     today,
-			})
+		return 0, fmt.Errorf("get hash names: %w", err)
+	}
+
+	// 获取各平台当前数据，只记录有价格且未被标记为异常的数据
+	for _, p := range models.Platforms() {
+		for _, row := range models.BatchGetPlatformRows(p, hashNames) {
+			if row.SellPrice > 0 && row.Anomaly == "" {
+				histories = append(histories, &models.PriceHistory{
+					MarketHashName: row.MarketHashName,
+					Platform:       p.Code,
+					SellPrice:      row.SellPrice,
+					SellCount:      row.SellCount,
+					RecordDate:     today,
+				})
+			}
 		}
 	}
 
 	// 批量保存
 	if err := models.BatchCreatePriceHistory(histories); err != nil {
-		config.Log.Errorf("Record daily price history error: %v", err)
-		return
+		return 0, fmt.Errorf("record daily price history: %w", err)
 	}
 	config.Log.Infof("Recorded %d price history entries for today", len(histories))
 
 	// 清理超过一年的旧数据
 	models.CleanOldHistory(366)
 
+	// 将过期的日内快照压缩为每日 OHLC
+	models.CompactPriceSnapshots(models.PriceSnapshotRetentionDays)
+
+	models.CleanOldAnomalyLogs(models.AnomalyLogRetainDays)
+
+	// 按今日价格记录用户组合估值
+	models.RecordPortfolioValuations(today)
+
+	// 根据最新历史数据重新计算持有期风险
+	models.RebuildPriceRisk()
+
 	// 清除涨幅缓存，让下次查询获取最新数据
 	models.ClearPriceIncreaseCache()
+	return len(histories), nil
 }
 
 // InitSteamItemNameIds 从 steam_id.json 文件初始化 Steam 表的 item_nameid
@@ -427,7 +412,7 @@ func InitSteamItemNameIds() {
 var steamCommunityClient = utils.CreateClient("https://steamcommunity-a.akamaihd.net")
 
 // FetchSteamItemNameId 从 Steam 商品详情页获取 item_nameid
-func FetchSteamItemNameId(marketHashName string) (string, error) {
+func FetchSteamItemNameId(ctx context.Context, marketHashName string) (string, error) {
 	// 直接构建路径，不依赖数据库的 link 字段
 	// URL 编码 marketHashName
 	encodedName := neturl.PathEscape(marketHashName)
@@ -441,9 +426,10 @@ func FetchSteamItemNameId(marketHashName string) (string, error) {
 			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
 			"Accept-Language": "en-US,en;q=0.5",
 		},
+		PathLabel: "market/listings/730/{name}",
 	}
 
-	resp, err := steamCommunityClient.DoRequest("GET", url, opts)
+	resp, err := steamCommunityClient.DoRequest(ctx, "GET", url, opts)
 	if err != nil {
 		return "", err
 	}
@@ -469,20 +455,20 @@ func FetchSteamItemNameId(marketHashName string) (string, error) {
 	return "", fmt.Errorf("item_nameid not found in page")
 }
 
-// UpdateSteamItemNameIds 更新 Steam 表中的 item_nameid（逐条写入）
-func UpdateSteamItemNameIds() {
+// UpdateSteamItemNameIds 更新 Steam 表中的 item_nameid（逐条写入），返回成功数
+// ctx 取消时写完当前饰品后返回
+func UpdateSteamItemNameIds(ctx context.Context) (int, error) {
 	config.Log.Info("Starting to update Steam item_nameid...")
 
 	// 获取所有没有 item_nameid 的商品
 	steams, err := models.GetSteamsWithoutItemNameId()
 	if err != nil {
-		config.Log.Errorf("Failed to get steams without item_nameid: %v", err)
-		return
+		return 0, fmt.Errorf("get steams without item_nameid: %w", err)
 	}
 
 	if len(steams) == 0 {
 		config.Log.Info("All steam items already have item_nameid")
-		return
+		return 0, nil
 	}
 
 	total := len(steams)
@@ -497,17 +483,20 @@ func UpdateSteamItemNameIds() {
 		var itemNameId string
 		var fetchErr error
 		for retry := 0; retry < 3; retry++ {
-			itemNameId, fetchErr = FetchSteamItemNameId(item.MarketHashName)
+			itemNameId, fetchErr = FetchSteamItemNameId(ctx, item.MarketHashName)
 			if fetchErr == nil {
 				consecutive429 = 0 // 成功，重置计数
 				break
 			}
 			// 检查是否是 429 错误
 			if strings.Contains(fetchErr.Error(), "429") {
+				utils.UpstreamRateLimitedTotal.WithLabelValues("steam").Inc()
 				consecutive429++
 				waitTime := time.Duration(30*(retry+1)) * time.Second // 30s, 60s, 90s
 				config.Log.Warnf("[%d/%d] Rate limited (429), waiting %v before retry %d/3...", i+1, total, waitTime, retry+1)
-				time.Sleep(waitTime)
+				if err := sleepContext(ctx, waitTime); err != nil {
+					return successCount, err
+				}
 			} else {
 				break // 非 429 错误不重试
 			}
@@ -519,7 +508,9 @@ func UpdateSteamItemNameIds() {
 			// 如果连续多次 429，暂停更长时间
 			if consecutive429 >= 5 {
 				config.Log.Warnf("Too many rate limits, waiting 5 minutes...")
-				time.Sleep(5 * time.Minute)
+				if err := sleepContext(ctx, 5*time.Minute); err != nil {
+					return successCount, err
+				}
 				consecutive429 = 0
 			}
 		} else {
@@ -533,10 +524,13 @@ func UpdateSteamItemNameIds() {
 			}
 		}
 
-		time.Sleep(7 * time.Second)
+		if err := sleepContext(ctx, 7*time.Second); err != nil {
+			return successCount, err
+		}
 	}
 
 	config.Log.Infof("Update steam item_nameid complete. Success: %d, Failed: %d", successCount, failCount)
+	return successCount, nil
 }
 
 // SteamOrderHistogram Steam 市场订单数据响应结构
@@ -556,21 +550,50 @@ type SteamOrderData struct {
 	BiddingCount int64   // 求购数量
 }
 
+// Steam 市场接口的币种编号（ISO 4217 -> Steam currency id）
+var steamCurrencyCodes = map[string]int{
+	"USD": 1,
+	"GBP": 2,
+	"EUR": 3,
+	"RUB": 5,
+	"JPY": 8,
+	"KRW": 16,
+	"CNY": 23,
+	"HKD": 29,
+}
+
+// steamCurrency Steam 平台当前配置的报价币种
+func steamCurrency() string {
+	if p, ok := models.GetPlatformByCode("STEAM"); ok {
+		return p.Currency
+	}
+	return models.BaseCurrency
+}
+
+// steamCurrencyCode 币种对应的 Steam currency id，不支持的币种使用人民币
+func steamCurrencyCode(currency string) int {
+	if code, ok := steamCurrencyCodes[currency]; ok {
+		return code
+	}
+	return steamCurrencyCodes[models.BaseCurrency]
+}
+
 // FetchSteamOrderData 获取 Steam 市场订单数据（求购价、售价、数量）
-func FetchSteamOrderData(itemNameId string) (*SteamOrderData, error) {
+func FetchSteamOrderData(ctx context.Context, itemNameId string) (*SteamOrderData, error) {
 	if itemNameId == "" {
 		return nil, fmt.Errorf("item_nameid is empty")
 	}
 
-	// currency=23 是人民币
-	url := fmt.Sprintf("market/itemordershistogram?country=CN&language=schinese&currency=23&item_nameid=%s", itemNameId)
+	// 按 Steam 平台配置的币种请求，返回的价格为该币种
+	url := fmt.Sprintf("market/itemordershistogram?country=CN&language=schinese&currency=%d&item_nameid=%s",
+		steamCurrencyCode(steamCurrency()), itemNameId)
 
 	var result SteamOrderHistogram
 	opts := utils.RequestOptions{
 		Result: &result,
 	}
 
-	resp, err := steamCommunityClient.DoRequest("GET", url, opts)
+	resp, err := steamCommunityClient.DoRequest(ctx, "GET", url, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -619,36 +642,47 @@ func FetchSteamOrderData(itemNameId string) (*SteamOrderData, error) {
 	return data, nil
 }
 
-// UpdateSteamPricesFromMarket 从 Steam 市场更新价格数据（需要先有 item_nameid）
-func UpdateSteamPricesFromMarket() {
+// UpdateSteamPricesFromMarket 从 Steam 市场更新价格数据（需要先有 item_nameid），返回成功数
+// ctx 取消时写完当前饰品后返回
+func UpdateSteamPricesFromMarket(ctx context.Context) (int, error) {
 	config.Log.Info("Starting to update Steam prices from market...")
 
 	// 获取有 item_nameid 且 sell_price < 500 的商品
 	var steams []models.Steam
-	config.DB.Where("id != '' AND id IS NOT NULL AND sell_price < 3000 and sell_count > 10").Find(&steams)
+	err := config.DB.Where("id != '' AND id IS NOT NULL AND sell_price < 3000 and sell_count > 10").Find(&steams).Error
+	if err != nil {
+		return 0, fmt.Errorf("get steam items: %w", err)
+	}
 
 	if len(steams) == 0 {
 		config.Log.Info("No steam items with item_nameid found")
-		return
+		return 0, nil
 	}
 
-	total := len(steams)
-	config.Log.Infof("Found %d steam items with item_nameid", total)
+	// 按刷新优先级挑选到期的饰品，每次运行最多请求 steamRefreshPerRun 个
+	byName := make(map[string]models.Steam, len(steams))
+	candidates := make([]refreshCandidate, 0, len(steams))
+	for _, s := range steams {
+		byName[s.MarketHashName] = s
+		candidates = append(candidates, refreshCandidate{name: s.MarketHashName, last: time.Unix(s.UpdateTime, 0)})
+	}
+	names := pickDueRefresh(candidates, steamRefreshIntervals, steamRefreshPerRun, time.Now())
+	total := len(names)
+	config.Log.Infof("Found %d steam items with item_nameid, %d due for refresh", len(steams), total)
+
+	// Steam 报价与其他平台使用同一套异常检测，被标记的报价不记录快照，并可被 exclude_anomaly 过滤
+	others := currentSellPrices(names)
+	detector := models.NewAnomalyDetector()
+	defer detector.Flush()
 
 	successCount := 0
 	failCount := 0
 	consecutive429 := 0
 	processed := 0
 
-	// 每次随机选择一个元素处理，处理后移除
-	for len(steams) > 0 {
+	for _, name := range names {
 		processed++
-		// 随机选择一个索引
-		idx := rand.Intn(len(steams))
-		item := steams[idx]
-		// 移除已选择的元素（与最后一个交换后截断）
-		steams[idx] = steams[len(steams)-1]
-		steams = steams[:len(steams)-1]
+		item := byName[name]
 
 		nowTime := time.Now().Unix()
 
@@ -656,17 +690,20 @@ func UpdateSteamPricesFromMarket() {
 		var orderData *SteamOrderData
 		var fetchErr error
 		for retry := 0; retry < 3; retry++ {
-			orderData, fetchErr = FetchSteamOrderData(item.Id)
+			orderData, fetchErr = FetchSteamOrderData(ctx, item.Id)
 			if fetchErr == nil {
 				consecutive429 = 0
 				break
 			}
 			// 检查是否是 429 错误
 			if strings.Contains(fetchErr.Error(), "429") {
+				utils.UpstreamRateLimitedTotal.WithLabelValues("steam").Inc()
 				consecutive429++
 				waitTime := time.Duration(30*(retry+1)) * time.Second // 30s, 60s, 90s
 				config.Log.Warnf("[%d/%d] Rate limited (429), waiting %v before retry %d/3...", processed, total, waitTime, retry+1)
-				time.Sleep(waitTime)
+				if err := sleepContext(ctx, waitTime); err != nil {
+					return successCount, err
+				}
 			} else {
 				break // 非 429 错误不重试
 			}
@@ -678,12 +715,27 @@ func UpdateSteamPricesFromMarket() {
 			// 如果连续多次 429，暂停更长时间
 			if consecutive429 >= 5 {
 				config.Log.Warnf("Too many rate limits, waiting 5 minutes...")
-				time.Sleep(5 * time.Minute)
+				if err := sleepContext(ctx, 5*time.Minute); err != nil {
+					return successCount, err
+				}
 				consecutive429 = 0
 			}
 		} else {
+			// 非人民币报价按汇率换算后入库
+			currency := steamCurrency()
+			if _, ok := steamCurrencyCodes[currency]; !ok {
+				currency = models.BaseCurrency
+			}
+			rates := models.GetFxRateMap()
+			orderData.SellPrice = models.ToBaseCurrency(orderData.SellPrice, currency, rates)
+			orderData.BiddingPrice = models.ToBaseCurrency(orderData.BiddingPrice, currency, rates)
+			anomaly := detector.Check(item.MarketHashName, "STEAM", orderData.SellPrice, orderData.BiddingPrice,
+				others[item.MarketHashName], item.Anomaly)
+
 			// 更新数据库
 			updates := map[string]interface{}{
+				"anomaly":       anomaly,
+				"currency":      currency,
 				"sell_price":    orderData.SellPrice,
 				"sell_count":    orderData.SellCount,
 				"bidding_price": orderData.BiddingPrice,
@@ -699,6 +751,23 @@ func UpdateSteamPricesFromMarket() {
 				updates["before_count"] = orderData.SellCount
 			}
 
+			// 行情有变化时记录日内快照，供K线和流动性估算使用
+			if anomaly == "" && orderData.SellPrice > 0 && (orderData.SellPrice != item.SellPrice || orderData.SellCount != item.SellCount ||
+				orderData.BiddingPrice != item.BiddingPrice || orderData.BiddingCount != item.BiddingCount) {
+				snapshot := &models.PriceSnapshot{
+					MarketHashName: item.MarketHashName,
+					Platform:       "STEAM",
+					SellPrice:      orderData.SellPrice,
+					SellCount:      orderData.SellCount,
+					BiddingPrice:   orderData.BiddingPrice,
+					BiddingCount:   orderData.BiddingCount,
+					SnapshotTime:   time.Unix(nowTime, 0),
+				}
+				if err := models.BatchCreatePriceSnapshots([]*models.PriceSnapshot{snapshot}); err != nil {
+					config.Log.Warnf("[%d/%d] Failed to record snapshot for %s: %v", processed, total, item.MarketHashName, err)
+				}
+			}
+
 			if err := config.DB.Model(&models.Steam{}).Where("market_hash_name = ?", item.MarketHashName).Updates(updates).Error; err != nil {
 				config.Log.Warnf("[%d/%d] Failed to update %s: %v", processed, total, item.MarketHashName, err)
 				failCount++
@@ -711,8 +780,11 @@ func UpdateSteamPricesFromMarket() {
 			}
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		if err := sleepContext(ctx, 500*time.Millisecond); err != nil {
+			return successCount, err
+		}
 	}
 
 	config.Log.Infof("Update steam prices complete. Success: %d, Failed: %d", successCount, failCount)
+	return successCount, nil
 }
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"strconv"
+	"time"
 	"uu/config"
 	"uu/models"
 	"uu/utils"
@@ -93,7 +95,7 @@ func BuildBodyWithSign(body map[string]interface{}) (map[string]interface{}, err
 	return body, nil
 }
 
-func GetUUItems(pageSize, PageNum int) ([]*models.UItem, int, error) {
+func GetUUItems(ctx context.Context, pageSize, PageNum int) ([]*models.UItem, int, error) {
 	var header = GetHeaders()
 	var uuResp UUResponse
 	var opts = utils.RequestOptions{
@@ -105,7 +107,7 @@ func GetUUItems(pageSize, PageNum int) ([]*models.UItem, int, error) {
 		Headers: header,
 		Result:  &uuResp,
 	}
-	res, err := client.DoRequest("POST", "api/homepage/pc/goods/market/querySaleTemplate", opts)
+	res, err := client.DoRequest(ctx, "POST", "api/homepage/pc/goods/market/querySaleTemplate", opts)
 	if err != nil || res.StatusCode() != 200 {
 		config.Log.Warnf("request uu list api error: %s, code: %d", err, res.StatusCode())
 	}
@@ -126,7 +128,7 @@ func VerifyUUToken() {
 		Headers: header,
 		Result:  &uuResp,
 	}
-	res, err := client.DoRequest("POST", "api/homepage/pc/goods/market/querySaleTemplate", opts)
+	res, err := client.DoRequest(context.Background(), "POST", "api/homepage/pc/goods/market/querySaleTemplate", opts)
 	if err != nil || res.StatusCode() != 200 || uuResp.Code != 0 {
 		config.Log.Warn("uu token expired")
 		uuToken.Expired = "yes"
@@ -139,7 +141,7 @@ func VerifyUUToken() {
 	}
 }
 
-func GetUUInventory() []*models.UItemsInfo {
+func GetUUInventory(ctx context.Context) []*models.UItemsInfo {
 	var header = GetHeaders()
 	var uuInventory UUInventory
 	var opts = utils.RequestOptions{
@@ -150,7 +152,7 @@ func GetUUInventory() []*models.UItemsInfo {
 		Headers: header,
 		Result:  &uuInventory,
 	}
-	res, err := client.DoRequest("POST", "api/youpin/pc/inventory/list", opts)
+	res, err := client.DoRequest(ctx, "POST", "api/youpin/pc/inventory/list", opts)
 	if err != nil || res.StatusCode() != 200 {
 		config.Log.Warnf("request uu inventory list api error: %s, code: %d", err, res.StatusCode())
 	}
@@ -162,7 +164,7 @@ type RequestItem struct {
 	TemplateHashName string `json:"templateHashName"`
 }
 
-func GetUUGoods(hashNames []string) []*models.UBaseInfo {
+func GetUUGoods(ctx context.Context, hashNames []string) []*models.UBaseInfo {
 	var uuResp OpenResponse
 	var requestList []RequestItem
 	var infos []*models.UBaseInfo
@@ -189,7 +191,7 @@ func GetUUGoods(hashNames []string) []*models.UBaseInfo {
 			"Content-Type": "application/json",
 		},
 	}
-	res, err := client.DoRequest("POST", "open/v1/api/batchGetOnSaleCommodityInfo", opts)
+	res, err := client.DoRequest(ctx, "POST", "open/v1/api/batchGetOnSaleCommodityInfo", opts)
 	if err != nil || res.StatusCode() != 200 {
 		config.Log.Errorf("request uu goods api error: %s", err)
 		return infos
@@ -213,3 +215,27 @@ func GetUUGoods(hashNames []string) []*models.UBaseInfo {
 	}
 	return infos
 }
+
+// uuSnapshotProvider 悠悠市场全量扫描（GetUUItems）得到的报价，由 UU 全量更新任务刷新
+var uuSnapshotProvider = newQuoteSnapshot("uu", 30*time.Minute)
+
+// uuItemsToQuotes 将悠悠市场列表转换为报价
+func uuItemsToQuotes(items []*models.UItem) []*PriceQuote {
+	now := time.Now().Unix()
+	quotes := make([]*PriceQuote, 0, len(items))
+	for _, item := range items {
+		price, _ := strconv.ParseFloat(item.Price, 64)
+		if item.HashName == "" || price <= 0 {
+			continue
+		}
+		quotes = append(quotes, &PriceQuote{
+			MarketHashName: item.HashName,
+			Platform:       "YOUPIN",
+			PlatformItemId: strconv.FormatInt(item.Id, 10),
+			SellPrice:      price,
+			SellCount:      item.Count,
+			UpdateTime:     now,
+		})
+	}
+	return quotes
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"html"
 	"math/big"
 )
 
@@ -93,6 +94,35 @@ func (es *EmailService) SendVIPNotification(toEmail string, months int, expiryDa
 	return SUCCESS
 }
 
+// SendPriceAlert 发送价格预警邮件
+func (es *EmailService) SendPriceAlert(toEmail, title, content string) int {
+	m := gomail.NewMessage()
+	m.SetHeader("From", es.FromEmail)
+	m.SetHeader("To", toEmail)
+	m.SetHeader("Subject", "【CS Goods】"+title)
+
+	body := fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+			<h2 style="color: #1890ff;">🔔 %s</h2>
+			<div style="background: #f5f5f5; padding: 20px; border-radius: 8px; margin: 20px 0;">
+				<p style="margin: 10px 0;">%s</p>
+			</div>
+			<p style="color: #666;">您可以在 <a href="https://www.csgoods.com.cn" style="color: #1890ff;">www.csgoods.com.cn</a> 管理您的价格预警。</p>
+			<p style="color: #999; font-size: 12px; margin-top: 30px;">此邮件由系统自动发送，请勿直接回复。</p>
+		</div>
+	`, html.EscapeString(title), html.EscapeString(content))
+
+	m.SetBody("text/html", body)
+
+	d := gomail.NewDialer(es.SMTPHost, es.SMTPPort, es.FromEmail, es.FromPassword)
+
+	err := d.DialAndSend(m)
+	if err != nil {
+		return ErrCodeSendEmailCode
+	}
+	return SUCCESS
+}
+
 // SendErrorAlert 发送错误告警邮件
 func (es *EmailService) SendErrorAlert(recipients []string, subject, body string) error {
 	if len(recipients) == 0 {
@@ -46,6 +46,8 @@ const (
 	ErrCodeCreateDefaultSetting = 2008
 	ErrCodeGetGoodsCategory     = 2009
 	ErrCodeRateLimitExceeded    = 2010
+	ErrCodeGetPlatformFee       = 2011
+	ErrCodeUpdatePlatformFee    = 2012
 )
 
 // 通知模块错误码
@@ -57,6 +59,114 @@ const (
 	ErrCodeDeleteNotification   = 3005
 )
 
+// 价格预警模块错误码
+const (
+	ErrCodeCreateAlert        = 3101
+	ErrCodeGetAlerts          = 3102
+	ErrCodeAlertNotFound      = 3103
+	ErrCodeUpdateAlert        = 3104
+	ErrCodeDeleteAlert        = 3105
+	ErrCodeAlertLimitExceeded = 3106
+	ErrCodeAlertGoodsNotFound = 3107
+)
+
+// 自选模块错误码
+const (
+	ErrCodeGetWatchlist           = 3201
+	ErrCodeUpdateWatchlist        = 3202
+	ErrCodeWatchlistLimitExceeded = 3203
+	ErrCodeWatchlistGoodsNotFound = 3204
+)
+
+// 设置方案模块错误码
+const (
+	ErrCodeCreateSettingProfile   = 3301
+	ErrCodeSettingProfileNotFound = 3302
+	ErrCodeDeleteSettingProfile   = 3303
+	ErrCodeSettingProfileLimit    = 3304
+	ErrCodeDeleteDefaultProfile   = 3305
+)
+
+// 汇率模块错误码
+const (
+	ErrCodeGetFxRates          = 3401
+	ErrCodeUpdateFxRate        = 3402
+	ErrCodeLoadFxRates         = 3403
+	ErrCodeUnsupportedCurrency = 3404
+)
+
+// 持仓模块错误码
+const (
+	ErrCodeGetPortfolio    = 3501
+	ErrCodeCreateHolding   = 3502
+	ErrCodeHoldingNotFound = 3503
+	ErrCodeUpdateHolding   = 3504
+	ErrCodeDeleteHolding   = 3505
+	ErrCodeHoldingLimit    = 3506
+	ErrCodeImportInventory = 3507
+)
+
+// 交易记录模块错误码
+const (
+	ErrCodeGetTrades     = 3601
+	ErrCodeCreateTrade   = 3602
+	ErrCodeTradeNotFound = 3603
+	ErrCodeUpdateTrade   = 3604
+	ErrCodeImportTrades  = 3605
+)
+
+// 回测模块错误码
+const (
+	ErrCodeCreateBacktest   = 3701
+	ErrCodeGetBacktest      = 3702
+	ErrCodeBacktestNotFound = 3703
+	ErrCodeBacktestRunning  = 3704
+)
+
+// 异常报价模块错误码
+const (
+	ErrCodeGetAnomalies = 3801
+)
+
+// 数据新鲜度模块错误码
+const (
+	ErrCodeGetFreshness    = 3901
+	ErrCodeUpdateFreshness = 3902
+)
+
+// 定时任务模块错误码
+const (
+	ErrCodeJobNotFound = 4001
+	ErrCodeJobRunning  = 4002
+	ErrCodeGetJobRuns  = 4003
+	ErrCodeUpdateJob   = 4004
+)
+
+// 健康检查模块错误码
+const (
+	ErrCodeNotReady = 4101
+)
+
+// API key 模块错误码
+const (
+	ErrCodeAPIKeyNotFound = 4201
+	ErrCodeAPIKeyExists   = 4202
+	ErrCodeAddAPIKey      = 4203
+	ErrCodeDeleteAPIKey   = 4204
+	ErrCodeUpdateAPIKey   = 4205
+)
+
+// 行情扫描模块错误码
+const (
+	ErrCodeGetSweepProgress   = 4301
+	ErrCodeGetRefreshPriority = 4302
+)
+
+// 搬砖机会推送模块错误码
+const (
+	ErrCodeCreateStreamTicket = 4401
+)
+
 // 错误码与消息映射
 var errorCodeToMessage = map[int]string{
 	SUCCESS:                  "success",
@@ -71,6 +181,8 @@ var errorCodeToMessage = map[int]string{
 	ErrCodeGetTokenExpired:   "Get token expired error",
 	ErrCodeGetGoodsCategory:  "Get goods category error",
 	ErrCodeRateLimitExceeded: "Too many requests, please try again later",
+	ErrCodeGetPlatformFee:    "Get platform fee error",
+	ErrCodeUpdatePlatformFee: "Update platform fee error",
 	// 用户模块
 	ErrCodeInvalidEmailCode:     "The provided email code is incorrect",
 	ErrCodeUsernameTaken:        "The requested username is already in use",
@@ -104,6 +216,72 @@ var errorCodeToMessage = map[int]string{
 	ErrCodeNotificationNotFound: "Notification not found",
 	ErrCodeMarkNotificationRead: "Mark notification as read error",
 	ErrCodeDeleteNotification:   "Delete notification error",
+	// 价格预警模块
+	ErrCodeCreateAlert:        "Create price alert error",
+	ErrCodeGetAlerts:          "Get price alerts error",
+	ErrCodeAlertNotFound:      "Price alert not found",
+	ErrCodeUpdateAlert:        "Update price alert error",
+	ErrCodeDeleteAlert:        "Delete price alert error",
+	ErrCodeAlertLimitExceeded: "Price alert limit exceeded",
+	ErrCodeAlertGoodsNotFound: "Goods not found",
+	// 自选模块
+	ErrCodeGetWatchlist:           "Get watchlist error",
+	ErrCodeUpdateWatchlist:        "Update watchlist error",
+	ErrCodeWatchlistLimitExceeded: "Watchlist limit exceeded",
+	ErrCodeWatchlistGoodsNotFound: "Goods not found",
+	// 设置方案模块
+	ErrCodeCreateSettingProfile:   "Create setting profile error",
+	ErrCodeSettingProfileNotFound: "Setting profile not found",
+	ErrCodeDeleteSettingProfile:   "Delete setting profile error",
+	ErrCodeSettingProfileLimit:    "Setting profile limit exceeded",
+	ErrCodeDeleteDefaultProfile:   "Default setting profile cannot be deleted",
+	// 汇率模块
+	ErrCodeGetFxRates:          "Get fx rates error",
+	ErrCodeUpdateFxRate:        "Update fx rate error",
+	ErrCodeLoadFxRates:         "Load fx rates file error",
+	ErrCodeUnsupportedCurrency: "Unsupported currency",
+	// 持仓模块
+	ErrCodeGetPortfolio:    "Get portfolio error",
+	ErrCodeCreateHolding:   "Create holding error",
+	ErrCodeHoldingNotFound: "Holding not found",
+	ErrCodeUpdateHolding:   "Update holding error",
+	ErrCodeDeleteHolding:   "Delete holding error",
+	ErrCodeHoldingLimit:    "Holding limit exceeded",
+	ErrCodeImportInventory: "Import inventory error",
+	// 交易记录模块
+	ErrCodeGetTrades:     "Get trades error",
+	ErrCodeCreateTrade:   "Create trade error",
+	ErrCodeTradeNotFound: "Trade not found",
+	ErrCodeUpdateTrade:   "Update trade error",
+	ErrCodeImportTrades:  "Import trades error",
+	// 回测模块
+	ErrCodeCreateBacktest:   "Create backtest error",
+	ErrCodeGetBacktest:      "Get backtest error",
+	ErrCodeBacktestNotFound: "Backtest not found",
+	ErrCodeBacktestRunning:  "A backtest is already running",
+	// 异常报价模块
+	ErrCodeGetAnomalies: "Get anomaly logs error",
+	// 数据新鲜度模块
+	ErrCodeGetFreshness:    "Get data freshness error",
+	ErrCodeUpdateFreshness: "Update data freshness error",
+	// 定时任务模块
+	ErrCodeJobNotFound: "Job not found",
+	ErrCodeJobRunning:  "Job is already running",
+	ErrCodeGetJobRuns:  "Get job runs error",
+	ErrCodeUpdateJob:   "Update job error",
+	// 健康检查模块
+	ErrCodeNotReady: "Service not ready",
+	// API key 模块
+	ErrCodeAPIKeyNotFound: "API key not found",
+	ErrCodeAPIKeyExists:   "API key already exists",
+	ErrCodeAddAPIKey:      "Add API key error",
+	ErrCodeDeleteAPIKey:   "Delete API key error",
+	ErrCodeUpdateAPIKey:   "Update API key error",
+	// 行情扫描模块
+	ErrCodeGetSweepProgress:   "Get price sweep progress error",
+	ErrCodeGetRefreshPriority: "Get refresh priority error",
+	// 搬砖机会推送模块
+	ErrCodeCreateStreamTicket: "Create stream ticket error",
 }
 
 // ErrorMessage 返回指定错误码对应的错误消息
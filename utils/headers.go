@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"testing"
 
 	"gopkg.in/yaml.v3"
 )
@@ -56,6 +57,10 @@ func ReadeHeaders() *Headers {
 	c := &Headers{}
 	const ConfigFile = "headers.yaml"
 	yamlConf, err := os.ReadFile(ConfigFile)
+	// 单元测试在包目录下运行，没有请求头配置
+	if os.IsNotExist(err) && testing.Testing() {
+		return &Headers{UU: &UU{}, Buff: &Buff{}}
+	}
 	if err != nil {
 		panic(fmt.Errorf("get Headers yaml file erro: %s", err))
 		//global.LOG.Panicf("get Yaml Config file erro: %s", err)
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsNamespace 指标名前缀
+const metricsNamespace = "monitor"
+
+// HTTP 接口
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	RateLimitRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limit_rejected_total",
+		Help:      "Requests rejected by the API rate limiter.",
+	}, []string{"limiter"})
+)
+
+// 上游请求
+var (
+	UpstreamRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_requests_total",
+		Help:      "Upstream HTTP calls by host, path and status (error when no response).",
+	}, []string{"host", "path", "status"})
+	UpstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Upstream HTTP call latency by host and path.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"host", "path"})
+	UpstreamRateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_rate_limited_total",
+		Help:      "Rate limit responses from upstream sources (SteamDT 4005, HTTP 429).",
+	}, []string{"source"})
+	ProviderBatchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "provider_batches_total",
+		Help:      "Price provider batches by result (success, error, exhausted).",
+	}, []string{"provider", "result"})
+)
+
+// 定时任务与数据写入
+var (
+	JobRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_runs_total",
+		Help:      "Scheduler job runs by outcome.",
+	}, []string{"job", "status"})
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_duration_seconds",
+		Help:      "Scheduler job run duration.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 10800},
+	}, []string{"job"})
+	JobItems = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_last_items",
+		Help:      "Items processed by the last run of each job.",
+	}, []string{"job"})
+	JobRunning = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_running",
+		Help:      "Whether a job is currently running (1) or idle (0).",
+	}, []string{"job"})
+	PlatformRowsUpsertedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "platform_rows_upserted_total",
+		Help:      "Rows written to platform price tables.",
+	}, []string{"platform"})
+	PriceSweepProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "price_sweep_progress_ratio",
+		Help:      "Share of batches finished in the current full price sweep.",
+	})
+)
+
+// MetricsHandler Prometheus 指标接口，响应压缩交给外层的 gzip 中间件
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{DisableCompression: true})
+}
+
+// hostLabel 基础地址中的主机名，作为上游请求指标的 host 标签
+func hostLabel(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return baseURL
+}
+
+// pathLabel 去掉查询参数和开头的 /，避免同一接口产生多个标签值
+func pathLabel(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimPrefix(path, "/")
+}
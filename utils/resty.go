@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"strconv"
 	"time"
 )
 
@@ -46,11 +48,24 @@ type RequestOptions struct {
 	FormData    map[string]string // 表单数据（application/x-www-form-urlencoded）
 	Result      interface{}
 	Error       interface{}
+	PathLabel   string // 指标中的路径标签，路径中包含饰品名等变量时设置，默认使用 path
 }
 
-// DoRequest 执行通用请求
-func (h *HttpClient) DoRequest(method, path string, opts RequestOptions) (*resty.Response, error) {
-	request := h.client.R()
+// DoRequest 执行通用请求，ctx 取消时中断请求和重试
+func (h *HttpClient) DoRequest(ctx context.Context, method, path string, opts RequestOptions) (*resty.Response, error) {
+	request := h.client.R().SetContext(ctx)
+
+	// 记录上游请求次数和耗时
+	host, label := hostLabel(h.baseURL), opts.PathLabel
+	if label == "" {
+		label = pathLabel(path)
+	}
+	start := time.Now()
+	status := "error"
+	defer func() {
+		UpstreamRequestsTotal.WithLabelValues(host, label, status).Inc()
+		UpstreamRequestDuration.WithLabelValues(host, label).Observe(time.Since(start).Seconds())
+	}()
 
 	// 设置路径参数
 	if opts.PathParams != nil {
@@ -109,6 +124,7 @@ func (h *HttpClient) DoRequest(method, path string, opts RequestOptions) (*resty
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
+	status = strconv.Itoa(resp.StatusCode())
 
 	if resp.IsError() {
 		return resp, fmt.Errorf("HTTP error: %s", resp.Status())